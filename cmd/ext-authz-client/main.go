@@ -0,0 +1,90 @@
+// Command ext-authz-client sends envoy.service.auth.v3 CheckRequests to
+// GateKeeper the way Envoy's ext_authz filter does, to try the integration
+// without running a mesh.
+//
+//	go run ./cmd/ext-authz-client -addr localhost:8082 -method GET \
+//	  -path /api/v1/resource -H "x-api-key: gk_1a2b3c4d_..."
+package main
+
+import (
+	"context"
+	"flag"
+	"fmt"
+	"log"
+	"os"
+	"strings"
+	"time"
+
+	authv3 "github.com/envoyproxy/go-control-plane/envoy/service/auth/v3"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/credentials/insecure"
+	"google.golang.org/protobuf/encoding/protojson"
+)
+
+type headerFlags map[string]string
+
+func (h headerFlags) String() string {
+	return fmt.Sprint(map[string]string(h))
+}
+
+// Set takes "Name: value", names are lower cased like Envoy does.
+func (h headerFlags) Set(value string) error {
+	name, val, ok := strings.Cut(value, ":")
+	if !ok {
+		return fmt.Errorf("header must be 'Name: value', got %q", value)
+	}
+	h[strings.ToLower(strings.TrimSpace(name))] = strings.TrimSpace(val)
+	return nil
+}
+
+func main() {
+
+	headers := headerFlags{}
+	addr := flag.String("addr", "localhost:8082", "GateKeeper gRPC address")
+	method := flag.String("method", "GET", "HTTP method of the checked request")
+	path := flag.String("path", "/", "HTTP path of the checked request, query included")
+	org := flag.String("org", "", "organization_name context extension, used when no credentials are sent")
+	count := flag.Int("n", 1, "number of checks to send")
+	timeout := flag.Duration("timeout", 5*time.Second, "timeout of each check")
+	flag.Var(headers, "H", "request header 'Name: value', repeatable")
+	flag.Parse()
+
+	conn, err := grpc.NewClient(*addr, grpc.WithTransportCredentials(insecure.NewCredentials()))
+	if err != nil {
+		log.Fatalf("couldn't connect to %s: %v", *addr, err)
+	}
+	defer conn.Close()
+	client := authv3.NewAuthorizationClient(conn)
+
+	req := &authv3.CheckRequest{
+		Attributes: &authv3.AttributeContext{
+			Request: &authv3.AttributeContext_Request{
+				Http: &authv3.AttributeContext_HttpRequest{
+					Method:   strings.ToUpper(*method),
+					Path:     *path,
+					Headers:  headers,
+					Protocol: "HTTP/1.1",
+				},
+			},
+		},
+	}
+	if *org != "" {
+		req.Attributes.ContextExtensions = map[string]string{"organization_name": *org}
+	}
+
+	failed := false
+	for i := 0; i < *count; i++ {
+		ctx, cancel := context.WithTimeout(context.Background(), *timeout)
+		resp, err := client.Check(ctx, req)
+		cancel()
+		if err != nil {
+			log.Printf("check %d failed: %v", i+1, err)
+			failed = true
+			continue
+		}
+		fmt.Println(protojson.Format(resp))
+	}
+	if failed {
+		os.Exit(1)
+	}
+}
@@ -0,0 +1,64 @@
+package adminHandler
+
+import (
+	"strconv"
+
+	"github.com/gin-gonic/gin"
+)
+
+func (h *AdminHandler) CreateApiKeyHandler(c *gin.Context) {
+
+	input, err := h.OrganizationService.CreateApiKeyValidation(c)
+	if err != nil {
+		h.ResponseWriter.BadRequest(c, err.Error())
+		return
+	}
+
+	output, err := h.OrganizationService.CreateApiKey(c.Request.Context(), input)
+	if err != nil {
+		h.ResponseWriter.Error(c, err)
+		return
+	}
+
+	h.ResponseWriter.Success(c, output)
+}
+
+func (h *AdminHandler) ListApiKeysByOrgIdHandler(c *gin.Context) {
+
+	id, err := strconv.Atoi(c.Param("organization_id"))
+	if err != nil {
+		h.ResponseWriter.BadRequest(c, "invalid organization_id")
+		return
+	}
+
+	limit, offset, err := ExtractPaginationDetail(c)
+	if err != nil {
+		h.ResponseWriter.BadRequest(c, err.Error())
+		return
+	}
+
+	output, err := h.OrganizationService.ListApiKeysByOrgId(c.Request.Context(), id, limit, offset)
+	if err != nil {
+		h.ResponseWriter.Error(c, err)
+		return
+	}
+
+	h.ResponseWriter.Success(c, output)
+}
+
+func (h *AdminHandler) RevokeApiKeyHandler(c *gin.Context) {
+
+	id64, err := strconv.ParseInt(c.Param("Id"), 10, 32)
+	if err != nil {
+		h.ResponseWriter.BadRequest(c, "invalid id format")
+		return
+	}
+
+	err = h.OrganizationService.RevokeApiKey(c.Request.Context(), int(id64))
+	if err != nil {
+		h.ResponseWriter.Error(c, err)
+		return
+	}
+
+	h.ResponseWriter.Success(c, "api key revoked successfully")
+}
@@ -43,6 +43,29 @@ func (h *AdminHandler) RegisterEndpointInBatchHandler(c *gin.Context) {
 	h.ResponseWriter.Created(c, map[string]int{"affected_rows": output})
 }
 
+func (h *AdminHandler) UpdateEndpointHandler(c *gin.Context) {
+
+	id, err := strconv.Atoi(c.Param("Id"))
+	if err != nil {
+		h.ResponseWriter.BadRequest(c, "invalid id format")
+		return
+	}
+
+	input, err := h.ResourceService.ValidateRegisterInput(c)
+	if err != nil {
+		h.ResponseWriter.BadRequest(c, err.Error())
+		return
+	}
+
+	output, err := h.ResourceService.UpdateApiEndpoint(c.Request.Context(), id, input)
+	if err != nil {
+		h.ResponseWriter.Error(c, err)
+		return
+	}
+
+	h.ResponseWriter.Success(c, output)
+}
+
 func (h *AdminHandler) ListEndpointsHandler(c *gin.Context) {
 
 	var err error
@@ -75,6 +98,23 @@ func (h *AdminHandler) ListEndpointsHandler(c *gin.Context) {
 	h.ResponseWriter.Success(c, output)
 }
 
+func (h *AdminHandler) MatchEndpointHandler(c *gin.Context) {
+
+	req, err := h.ResourceService.MatchEndpointQueryValidation(c)
+	if err != nil {
+		h.ResponseWriter.BadRequest(c, err.Error())
+		return
+	}
+
+	output, err := h.ResourceService.MatchApiEndpoint(c.Request.Context(), req)
+	if err != nil {
+		h.ResponseWriter.Error(c, err)
+		return
+	}
+
+	h.ResponseWriter.Success(c, output)
+}
+
 func (h *AdminHandler) DeleteEndpointsByIdHandler(c *gin.Context) {
 
 	id64, err := strconv.ParseInt(c.Param("id"), 10, 32)
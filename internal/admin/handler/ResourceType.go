@@ -58,6 +58,30 @@ func (h *AdminHandler) ListResourceTypeHandler(c *gin.Context) {
 	h.ResponseWriter.Success(c, resourceTypes)
 }
 
+func (h *AdminHandler) SetResourceTypeUpstreamHandler(c *gin.Context) {
+
+	id, err := strconv.Atoi(c.Param("id"))
+	if err != nil {
+		h.ResponseWriter.BadRequest(c, err.Error())
+		return
+	}
+
+	input, err := h.ResourceService.ValidateResourceTypeUpstreamInput(c)
+	if err != nil {
+		h.ResponseWriter.BadRequest(c, err.Error())
+		return
+	}
+
+	if err := h.ResourceService.SetResourceTypeUpstream(c.Request.Context(), id, input.UpstreamID); err != nil {
+		h.ResponseWriter.Error(c, err)
+		return
+	}
+
+	h.ResponseWriter.Success(c, map[string]string{
+		"message": fmt.Sprintf("upstream of resource type with ID %d updated successfully", id),
+	})
+}
+
 func (h *AdminHandler) DeleteResourceTypeHandler(c *gin.Context) {
 
 	id, err := strconv.Atoi(c.Param("id"))
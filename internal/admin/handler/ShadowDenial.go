@@ -0,0 +1,33 @@
+package adminHandler
+
+import (
+	usage "github.com/bignyap/go-admin/internal/admin/service/Usage"
+	"github.com/gin-gonic/gin"
+)
+
+func (h *AdminHandler) GetShadowDenialSummaryHandler(c *gin.Context) {
+
+	query, err := h.UsageService.ShadowDenialQueryValidation(c)
+	if err != nil {
+		h.ResponseWriter.BadRequest(c, err.Error())
+		return
+	}
+
+	limit, offset, err := ExtractPaginationDetail(c)
+	if err != nil {
+		h.ResponseWriter.BadRequest(c, err.Error())
+		return
+	}
+
+	output, err := h.UsageService.GetShadowDenialSummary(c.Request.Context(), usage.ShadowDenialFilters{
+		ShadowDenialFilterQueryParams: query,
+		Limit:                         int32(limit),
+		Offset:                        int32(offset),
+	})
+	if err != nil {
+		h.ResponseWriter.Error(c, err)
+		return
+	}
+
+	h.ResponseWriter.Success(c, output)
+}
@@ -0,0 +1,94 @@
+package adminHandler
+
+import (
+	"fmt"
+	"strconv"
+
+	"github.com/gin-gonic/gin"
+)
+
+func (h *AdminHandler) CreateUpstreamHandler(c *gin.Context) {
+
+	input, err := h.ResourceService.ValidateUpstreamInput(c)
+	if err != nil {
+		h.ResponseWriter.BadRequest(c, err.Error())
+		return
+	}
+
+	output, err := h.ResourceService.CreateUpstream(c.Request.Context(), input)
+	if err != nil {
+		h.ResponseWriter.Error(c, err)
+		return
+	}
+
+	h.ResponseWriter.Created(c, output)
+}
+
+func (h *AdminHandler) ListUpstreamsHandler(c *gin.Context) {
+
+	upstreams, err := h.ResourceService.ListUpstreams(c.Request.Context())
+	if err != nil {
+		h.ResponseWriter.Error(c, err)
+		return
+	}
+
+	h.ResponseWriter.Success(c, upstreams)
+}
+
+func (h *AdminHandler) GetUpstreamHandler(c *gin.Context) {
+
+	id, err := strconv.Atoi(c.Param("id"))
+	if err != nil {
+		h.ResponseWriter.BadRequest(c, "invalid id format")
+		return
+	}
+
+	upstream, err := h.ResourceService.GetUpstreamById(c.Request.Context(), id)
+	if err != nil {
+		h.ResponseWriter.Error(c, err)
+		return
+	}
+
+	h.ResponseWriter.Success(c, upstream)
+}
+
+func (h *AdminHandler) UpdateUpstreamHandler(c *gin.Context) {
+
+	id, err := strconv.Atoi(c.Param("id"))
+	if err != nil {
+		h.ResponseWriter.BadRequest(c, "invalid id format")
+		return
+	}
+
+	input, err := h.ResourceService.ValidateUpstreamInput(c)
+	if err != nil {
+		h.ResponseWriter.BadRequest(c, err.Error())
+		return
+	}
+
+	output, err := h.ResourceService.UpdateUpstream(c.Request.Context(), id, input)
+	if err != nil {
+		h.ResponseWriter.Error(c, err)
+		return
+	}
+
+	h.ResponseWriter.Success(c, output)
+}
+
+func (h *AdminHandler) DeleteUpstreamHandler(c *gin.Context) {
+
+	id, err := strconv.Atoi(c.Param("id"))
+	if err != nil {
+		h.ResponseWriter.BadRequest(c, "invalid id format")
+		return
+	}
+
+	if err := h.ResourceService.DeleteUpstream(c.Request.Context(), id); err != nil {
+		h.ResponseWriter.Error(c, err)
+		return
+	}
+
+	h.ResponseWriter.Success(c, map[string]string{
+		"message": fmt.Sprintf("upstream with ID %d deleted successfully", id),
+	})
+}
@@ -0,0 +1,114 @@
+package organization
+
+import (
+	"context"
+	"errors"
+	"fmt"
+
+	"github.com/bignyap/go-admin/internal/common"
+	"github.com/bignyap/go-admin/internal/database/sqlcgen"
+	"github.com/bignyap/go-utilities/converter"
+	"github.com/bignyap/go-utilities/server"
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgtype"
+)
+
+func (s *OrganizationService) CreateApiKey(ctx context.Context, input *CreateApiKeyParams) (CreateApiKeyOutput, error) {
+
+	key, prefix, err := common.GenerateApiKey()
+	if err != nil {
+		return CreateApiKeyOutput{}, server.NewError(
+			server.ErrorInternal,
+			"couldn't generate the api key",
+			err,
+		)
+	}
+
+	var expiresAt pgtype.Int4
+	if input.ExpiresAt != nil && !input.ExpiresAt.IsZero() {
+		expiresAt = converter.ToPgInt4FromTimeOrDate(input.ExpiresAt)
+	}
+
+	currentTime := int32(converter.ToUnixTime())
+	insertedID, err := s.DB.CreateApiKey(ctx, sqlcgen.CreateApiKeyParams{
+		ApiKeyName:      input.Name,
+		ApiKeyPrefix:    prefix,
+		ApiKeyHash:      common.HashApiKey(key),
+		ApiKeyCreatedAt: currentTime,
+		ApiKeyExpiresAt: expiresAt,
+		OrganizationID:  int32(input.OrganizationID),
+	})
+	if err != nil {
+		return CreateApiKeyOutput{}, server.NewError(
+			server.ErrorInternal,
+			"couldn't create the api key",
+			err,
+		)
+	}
+
+	return CreateApiKeyOutput{
+		ApiKeyOutput: ApiKeyOutput{
+			ID:             int(insertedID),
+			Name:           input.Name,
+			Prefix:         prefix,
+			OrganizationID: input.OrganizationID,
+			CreatedAt:      converter.FromUnixTime32(currentTime),
+			ExpiresAt:      converter.FromPgInt4TimePtr(expiresAt),
+		},
+		Key: key,
+	}, nil
+}
+
+func (s *OrganizationService) ListApiKeysByOrgId(ctx context.Context, orgId int, limit int, offset int) (ListApiKeyOutputWithCount, error) {
+
+	apiKeys, err := s.DB.ListApiKeysByOrgId(ctx, sqlcgen.ListApiKeysByOrgIdParams{
+		OrganizationID: int32(orgId),
+		Limit:          int32(limit),
+		Offset:         int32(offset),
+	})
+	if err != nil {
+		return ListApiKeyOutputWithCount{}, server.NewError(
+			server.ErrorInternal,
+			"couldn't retrieve the api keys",
+			err,
+		)
+	}
+
+	return ToListApiKeyOutputWithCount(apiKeys), nil
+}
+
+// RevokeApiKey flags the key as revoked and tells every GateKeeper replica to
+// drop it from its cache so the revocation is effective immediately.
+func (s *OrganizationService) RevokeApiKey(ctx context.Context, id int) error {
+
+	revoked, err := s.DB.RevokeApiKey(ctx, int32(id))
+	if errors.Is(err, pgx.ErrNoRows) {
+		return server.NewError(
+			server.ErrorNotFound,
+			"api key not found",
+			fmt.Errorf("no api key with ID %d", id),
+		)
+	}
+	if err != nil {
+		return server.NewError(
+			server.ErrorInternal,
+			"couldn't revoke the api key",
+			err,
+		)
+	}
+
+	err = s.PubSubClient.Publish(ctx, string(common.ApiKeyRevoked), common.ApiKeyRevokedEvent{
+		ID:             int32(id),
+		Hash:           revoked.ApiKeyHash,
+		OrganizationID: revoked.OrganizationID,
+	})
+	if err != nil {
+		return server.NewError(
+			server.ErrorInternal,
+			"couldn't push to the queue",
+			err,
+		)
+	}
+
+	return nil
+}
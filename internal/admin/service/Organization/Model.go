@@ -112,3 +112,58 @@ type UpdateOrganizationParams struct {
 	TypeID         int     `json:"type_id" form:"type_id" validate:"required,min=1"`
 	OrganizationID int     `json:"organization_id" form:"organization_id" validate:"required,min=1"`
 }
+
+type CreateApiKeyParams struct {
+	Name           string                `json:"name" form:"name" validate:"required,max=100"`
+	OrganizationID int                   `json:"organization_id" form:"organization_id" validate:"required,min=1"`
+	ExpiresAt      *converter.TimeOrDate `json:"expires_at" form:"-"`
+	ExpiresAtRaw   string                `form:"expires_at" json:"-"`
+}
+
+type ApiKeyOutput struct {
+	ID             int        `json:"id"`
+	Name           string     `json:"name"`
+	Prefix         string     `json:"prefix"`
+	OrganizationID int        `json:"organization_id"`
+	CreatedAt      time.Time  `json:"created_at"`
+	ExpiresAt      *time.Time `json:"expires_at"`
+	Revoked        bool       `json:"revoked"`
+	LastUsedAt     *time.Time `json:"last_used_at"`
+}
+
+// CreateApiKeyOutput is the only place the plain key is ever returned
+type CreateApiKeyOutput struct {
+	ApiKeyOutput
+	Key string `json:"key"`
+}
+
+type ListApiKeyOutputWithCount struct {
+	TotalItems int            `json:"total_items"`
+	Data       []ApiKeyOutput `json:"data"`
+}
+
+func ToListApiKeyOutputWithCount(inputs []sqlcgen.ListApiKeysByOrgIdRow) ListApiKeyOutputWithCount {
+	data := []ApiKeyOutput{}
+	for _, input := range inputs {
+		data = append(data, ApiKeyOutput{
+			ID:             int(input.ApiKeyID),
+			Name:           input.ApiKeyName,
+			Prefix:         input.ApiKeyPrefix,
+			OrganizationID: int(input.OrganizationID),
+			CreatedAt:      converter.FromUnixTime32(input.ApiKeyCreatedAt),
+			ExpiresAt:      converter.FromPgInt4TimePtr(input.ApiKeyExpiresAt),
+			Revoked:        input.ApiKeyRevoked,
+			LastUsedAt:     converter.FromPgInt4TimePtr(input.ApiKeyLastUsedAt),
+		})
+	}
+
+	totalItems := 0
+	if len(inputs) > 0 {
+		totalItems = int(inputs[0].TotalItems)
+	}
+
+	return ListApiKeyOutputWithCount{
+		Data:       data,
+		TotalItems: totalItems,
+	}
+}
@@ -2,7 +2,9 @@ package organization
 
 import (
 	"fmt"
+	"time"
 
+	"github.com/bignyap/go-admin/internal/common"
 	"github.com/bignyap/go-admin/internal/database/sqlcgen"
 	"github.com/bignyap/go-utilities/converter"
 	"github.com/gin-gonic/gin"
@@ -127,6 +129,9 @@ func (h *OrganizationService) CreateOrgFormValidation(c *gin.Context) (*CreateOr
 	if err := h.Validator.Struct(inputs); err != nil {
 		return nil, fmt.Errorf("validation error: %w", err)
 	}
+	if err := h.validateOrgConfig(inputs.Config); err != nil {
+		return nil, fmt.Errorf("validation error: %w", err)
+	}
 	return &inputs, nil
 }
 
@@ -139,6 +144,9 @@ func (h *OrganizationService) CreateOrgJSONValidation(c *gin.Context) ([]CreateO
 		if err := h.Validator.Struct(input); err != nil {
 			return nil, fmt.Errorf("validation error at index %d: %w", i, err)
 		}
+		if err := h.validateOrgConfig(input.Config); err != nil {
+			return nil, fmt.Errorf("validation error at index %d: %w", i, err)
+		}
 	}
 	return inputs, nil
 }
@@ -152,5 +160,63 @@ func (h *OrganizationService) UpdateOrgFormValidation(c *gin.Context) (*UpdateOr
 	if err := h.Validator.Struct(inputs); err != nil {
 		return nil, fmt.Errorf("validation error: %w", err)
 	}
+	if err := h.validateOrgConfig(inputs.Config); err != nil {
+		return nil, fmt.Errorf("validation error: %w", err)
+	}
 	return &inputs, nil
 }
+
+// validateOrgConfig makes sure the typed sections of the organization config
+// (rate_limit, auth and enforcement) are well formed before they reach GateKeeper.
+func (h *OrganizationService) validateOrgConfig(config *string) error {
+	if config == nil {
+		return nil
+	}
+	cfg, err := common.ParseOrganizationConfig(*config)
+	if err != nil {
+		return err
+	}
+	if cfg.RateLimit != nil {
+		if err := h.Validator.Struct(cfg.RateLimit); err != nil {
+			return fmt.Errorf("invalid rate_limit config: %w", err)
+		}
+	}
+	if cfg.Auth != nil {
+		if err := h.Validator.Struct(cfg.Auth); err != nil {
+			return fmt.Errorf("invalid auth config: %w", err)
+		}
+		if cfg.Auth.JWKSURL != "" && cfg.Auth.JWKSFile != "" {
+			return fmt.Errorf("invalid auth config: only one of jwks_url and jwks_file can be set")
+		}
+	}
+	switch cfg.Enforcement {
+	case "", common.EnforcementEnforce, common.EnforcementShadow:
+	default:
+		return fmt.Errorf("invalid enforcement config: must be %s or %s", common.EnforcementEnforce, common.EnforcementShadow)
+	}
+	return nil
+}
+
+func (h *OrganizationService) CreateApiKeyValidation(c *gin.Context) (*CreateApiKeyParams, error) {
+
+	var input CreateApiKeyParams
+	if err := c.ShouldBind(&input); err != nil {
+		return nil, fmt.Errorf("invalid input: %w", err)
+	}
+	if err := h.Validator.Struct(input); err != nil {
+		return nil, fmt.Errorf("validation failed: %w", err)
+	}
+
+	if input.ExpiresAtRaw != "" {
+		t := &converter.TimeOrDate{}
+		if err := t.UnmarshalText([]byte(input.ExpiresAtRaw)); err != nil {
+			return nil, fmt.Errorf("invalid expires_at: %w", err)
+		}
+		input.ExpiresAt = t
+	}
+	if input.ExpiresAt != nil && !input.ExpiresAt.IsZero() && input.ExpiresAt.Before(time.Now()) {
+		return nil, fmt.Errorf("expires_at must be in the future")
+	}
+
+	return &input, nil
+}
@@ -2,25 +2,23 @@ package resource
 
 import (
 	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"slices"
+	"strings"
 
 	"github.com/jackc/pgx/v5"
 	"github.com/jackc/pgx/v5/pgtype"
 
 	"github.com/bignyap/go-admin/internal/common"
-	"github.com/bignyap/go-admin/internal/database/dbutils"
+	"github.com/bignyap/go-admin/internal/common/route"
 	"github.com/bignyap/go-admin/internal/database/sqlcgen"
+	"github.com/bignyap/go-utilities/converter"
 	"github.com/bignyap/go-utilities/server"
 )
 
-type BulkRegisterEndpointInserter struct {
-	Endpoints       []sqlcgen.RegisterApiEndpointsParams
-	ResourceService *ResourceService
-}
-
-func (input BulkRegisterEndpointInserter) InsertRows(ctx context.Context, tx pgx.Tx) (int64, error) {
-	return input.ResourceService.DB.RegisterApiEndpoints(ctx, input.Endpoints)
-}
-
 func (s *ResourceService) RegisterApiEndpoint(ctx context.Context, input *RegisterEndpointParams) (RegisterEndpointOutputs, error) {
 
 	var desc pgtype.Text
@@ -36,26 +34,43 @@ func (s *ResourceService) RegisterApiEndpoint(ctx context.Context, input *Regist
 		ResourceTypeID:      input.ResourceTypeID,
 		PermissionCode:      input.PermissionCode,
 		AccessType:          input.AccessType,
+		UsageUnitSource:     input.UsageUnitSource,
+		UsageUnitKey:        usageUnitKey(input.UsageUnitKey),
+		UpstreamID:          int4(input.UpstreamID),
+		PathRewrite:         converter.ToPgText(input.PathRewrite),
+		HostPattern:         converter.ToPgText(input.HostPattern),
+		HeaderMatchers:      headerMatchers(input.HeaderMatchers),
+		MatchPriority:       input.MatchPriority,
 	}
 
-	err := s.PubSubClient.Publish(ctx, string(common.EndpointCreated), common.EndpointCreatedEvent{
-		Path:   input.PathTemplate,
-		Method: input.HttpMethod,
-		Code:   input.Name,
+	var insertedID int32
+	err := s.withRouteLock(ctx, func(db *sqlcgen.Queries) error {
+		if err := s.checkRouteConflicts(ctx, db, []RegisterEndpointParams{*input}); err != nil {
+			return err
+		}
+
+		var err error
+		insertedID, err = db.RegisterApiEndpoint(ctx, params)
+		if err != nil {
+			return server.NewError(
+				server.ErrorInternal,
+				"couldn't register the API endpoint",
+				err,
+			)
+		}
+		return nil
 	})
 	if err != nil {
-		return RegisterEndpointOutputs{}, server.NewError(
-			server.ErrorInternal,
-			"couldn't push to the queue",
-			err,
-		)
+		return RegisterEndpointOutputs{}, err
 	}
 
-	insertedID, err := s.DB.RegisterApiEndpoint(ctx, params)
+	// Only once committed, GateKeeper mustn't route an endpoint the catalog
+	// doesn't have
+	err = s.PubSubClient.Publish(ctx, string(common.EndpointCreated), endpointCreatedEvent(input))
 	if err != nil {
 		return RegisterEndpointOutputs{}, server.NewError(
 			server.ErrorInternal,
-			"couldn't register the API endpoint",
+			"couldn't push to the queue",
 			err,
 		)
 	}
@@ -63,13 +78,20 @@ func (s *ResourceService) RegisterApiEndpoint(ctx context.Context, input *Regist
 	output := RegisterEndpointOutputs{
 		ID: int(insertedID),
 		RegisterEndpointParams: RegisterEndpointParams{
-			Name:           input.Name,
-			Description:    input.Description,
-			HttpMethod:     input.HttpMethod,
-			PathTemplate:   input.PathTemplate,
-			ResourceTypeID: input.ResourceTypeID,
-			PermissionCode: input.PermissionCode,
-			AccessType:     input.AccessType,
+			Name:            input.Name,
+			Description:     input.Description,
+			HttpMethod:      input.HttpMethod,
+			PathTemplate:    input.PathTemplate,
+			ResourceTypeID:  input.ResourceTypeID,
+			PermissionCode:  input.PermissionCode,
+			AccessType:      input.AccessType,
+			UsageUnitSource: input.UsageUnitSource,
+			UsageUnitKey:    input.UsageUnitKey,
+			UpstreamID:      input.UpstreamID,
+			PathRewrite:     input.PathRewrite,
+			HostPattern:     input.HostPattern,
+			HeaderMatchers:  input.HeaderMatchers,
+			MatchPriority:   input.MatchPriority,
 		},
 	}
 
@@ -78,28 +100,55 @@ func (s *ResourceService) RegisterApiEndpoint(ctx context.Context, input *Regist
 
 func (s *ResourceService) RegisterApiEndpointInBatch(ctx context.Context, inputs []RegisterEndpointParams) (int, error) {
 
-	var batch []sqlcgen.RegisterApiEndpointsParams
-	for _, in := range inputs {
-		desc := pgtype.Text{Valid: false}
-		if in.Description != nil {
-			desc = pgtype.Text{String: *in.Description, Valid: true}
+	var affectedRows int64
+	err := s.withRouteLock(ctx, func(db *sqlcgen.Queries) error {
+		if err := s.checkRouteConflicts(ctx, db, inputs); err != nil {
+			return err
 		}
 
-		dbIn := sqlcgen.RegisterApiEndpointsParams{
-			EndpointName:        in.Name,
-			EndpointDescription: desc,
-			HttpMethod:          in.HttpMethod,
-			PathTemplate:        in.PathTemplate,
-			ResourceTypeID:      in.ResourceTypeID,
-			PermissionCode:      in.PermissionCode,
-			AccessType:          in.AccessType,
+		var batch []sqlcgen.RegisterApiEndpointsParams
+		for _, in := range inputs {
+			desc := pgtype.Text{Valid: false}
+			if in.Description != nil {
+				desc = pgtype.Text{String: *in.Description, Valid: true}
+			}
+
+			dbIn := sqlcgen.RegisterApiEndpointsParams{
+				EndpointName:        in.Name,
+				EndpointDescription: desc,
+				HttpMethod:          in.HttpMethod,
+				PathTemplate:        in.PathTemplate,
+				ResourceTypeID:      in.ResourceTypeID,
+				PermissionCode:      in.PermissionCode,
+				AccessType:          in.AccessType,
+				UsageUnitSource:     in.UsageUnitSource,
+				UsageUnitKey:        usageUnitKey(in.UsageUnitKey),
+				UpstreamID:          int4(in.UpstreamID),
+				PathRewrite:         converter.ToPgText(in.PathRewrite),
+				HostPattern:         converter.ToPgText(in.HostPattern),
+				HeaderMatchers:      headerMatchers(in.HeaderMatchers),
+				MatchPriority:       in.MatchPriority,
+			}
+			batch = append(batch, dbIn)
 		}
 
-		err := s.PubSubClient.Publish(ctx, string(common.EndpointCreated), common.EndpointCreatedEvent{
-			Path:   in.PathTemplate,
-			Method: in.HttpMethod,
-			Code:   in.Name,
-		})
+		var err error
+		affectedRows, err = db.RegisterApiEndpoints(ctx, batch)
+		if err != nil {
+			return server.NewError(
+				server.ErrorInternal,
+				"couldn't register endpoints",
+				err,
+			)
+		}
+		return nil
+	})
+	if err != nil {
+		return 0, err
+	}
+
+	for _, in := range inputs {
+		err := s.PubSubClient.Publish(ctx, string(common.EndpointCreated), endpointCreatedEvent(&in))
 		if err != nil {
 			return 0, server.NewError(
 				server.ErrorInternal,
@@ -107,25 +156,81 @@ func (s *ResourceService) RegisterApiEndpointInBatch(ctx context.Context, inputs
 				err,
 			)
 		}
+	}
 
-		batch = append(batch, dbIn)
+	return int(affectedRows), nil
+}
+
+// UpdateApiEndpoint changes an endpoint, GateKeeper swaps its route on
+// endpoint:updated.
+func (s *ResourceService) UpdateApiEndpoint(ctx context.Context, id int, input *RegisterEndpointParams) (RegisterEndpointOutputs, error) {
+
+	existing, err := s.DB.GetApiEndpointById(ctx, int32(id))
+	if errors.Is(err, pgx.ErrNoRows) {
+		return RegisterEndpointOutputs{}, server.NewError(
+			server.ErrorNotFound,
+			"endpoint not found",
+			fmt.Errorf("no endpoint with ID %d", id),
+		)
 	}
+	if err != nil {
+		return RegisterEndpointOutputs{}, server.NewError(
+			server.ErrorInternal,
+			"couldn't find the endpoint",
+			err,
+		)
+	}
+
+	err = s.withRouteLock(ctx, func(db *sqlcgen.Queries) error {
+		if err := s.checkRouteConflicts(ctx, db, []RegisterEndpointParams{*input}, existing.EndpointName); err != nil {
+			return err
+		}
 
-	inserter := BulkRegisterEndpointInserter{
-		Endpoints:       batch,
-		ResourceService: s,
+		err := db.UpdateApiEndpointById(ctx, sqlcgen.UpdateApiEndpointByIdParams{
+			ApiEndpointID:       int32(id),
+			EndpointName:        input.Name,
+			EndpointDescription: converter.ToPgText(input.Description),
+			HttpMethod:          input.HttpMethod,
+			PathTemplate:        input.PathTemplate,
+			ResourceTypeID:      input.ResourceTypeID,
+			PermissionCode:      input.PermissionCode,
+			AccessType:          input.AccessType,
+			UsageUnitSource:     input.UsageUnitSource,
+			UsageUnitKey:        usageUnitKey(input.UsageUnitKey),
+			UpstreamID:          int4(input.UpstreamID),
+			PathRewrite:         converter.ToPgText(input.PathRewrite),
+			HostPattern:         converter.ToPgText(input.HostPattern),
+			HeaderMatchers:      headerMatchers(input.HeaderMatchers),
+			MatchPriority:       input.MatchPriority,
+		})
+		if err != nil {
+			return server.NewError(
+				server.ErrorInternal,
+				"couldn't update the endpoint",
+				err,
+			)
+		}
+		return nil
+	})
+	if err != nil {
+		return RegisterEndpointOutputs{}, err
 	}
 
-	affectedRows, err := dbutils.InsertWithTransaction(ctx, s.Conn, inserter)
+	// GateKeeper applies the route in the payload, it doesn't read the
+	// database
+	err = s.PubSubClient.Publish(ctx, string(common.EndpointUpdated), common.EndpointUpdatedEvent{
+		EndpointCreatedEvent: endpointCreatedEvent(input),
+		PreviousCode:         existing.EndpointName,
+	})
 	if err != nil {
-		return 0, server.NewError(
+		return RegisterEndpointOutputs{}, server.NewError(
 			server.ErrorInternal,
-			"couldn't register endpoints",
+			"couldn't push to the queue",
 			err,
 		)
 	}
 
-	return int(affectedRows), nil
+	return RegisterEndpointOutputs{ID: id, RegisterEndpointParams: *input}, nil
 }
 
 func (s *ResourceService) ListApiEndpoints(ctx context.Context, limit int, offset int) ([]ListEndpointOutputs, error) {
@@ -159,13 +264,20 @@ func (s *ResourceService) ListApiEndpoints(ctx context.Context, limit int, offse
 			ID:               int(apiEndpoint.ApiEndpointID),
 			ResourceTypeName: apiEndpoint.ResourceTypeName,
 			RegisterEndpointParams: RegisterEndpointParams{
-				Name:           apiEndpoint.EndpointName,
-				Description:    desc,
-				HttpMethod:     apiEndpoint.HttpMethod,
-				PathTemplate:   apiEndpoint.PathTemplate,
-				ResourceTypeID: apiEndpoint.ResourceTypeID,
-				PermissionCode: apiEndpoint.PermissionCode,
-				AccessType:     apiEndpoint.AccessType,
+				Name:            apiEndpoint.EndpointName,
+				Description:     desc,
+				HttpMethod:      apiEndpoint.HttpMethod,
+				PathTemplate:    apiEndpoint.PathTemplate,
+				ResourceTypeID:  apiEndpoint.ResourceTypeID,
+				PermissionCode:  apiEndpoint.PermissionCode,
+				AccessType:      apiEndpoint.AccessType,
+				UsageUnitSource: apiEndpoint.UsageUnitSource,
+				UsageUnitKey:    textPtr(apiEndpoint.UsageUnitKey),
+				UpstreamID:      int4Ptr(apiEndpoint.UpstreamID),
+				PathRewrite:     textPtr(apiEndpoint.PathRewrite),
+				HostPattern:     textPtr(apiEndpoint.HostPattern),
+				HeaderMatchers:  parseHeaderMatchers(apiEndpoint.HeaderMatchers),
+				MatchPriority:   apiEndpoint.MatchPriority,
 			},
 		})
 	}
@@ -199,13 +311,20 @@ func (s *ResourceService) ListApiEndpointsByResourceType(ctx context.Context, re
 			ID:               int(apiEndpoint.ApiEndpointID),
 			ResourceTypeName: apiEndpoint.ResourceTypeName,
 			RegisterEndpointParams: RegisterEndpointParams{
-				Name:           apiEndpoint.EndpointName,
-				Description:    desc,
-				HttpMethod:     apiEndpoint.HttpMethod,
-				PathTemplate:   apiEndpoint.PathTemplate,
-				ResourceTypeID: apiEndpoint.ResourceTypeID,
-				PermissionCode: apiEndpoint.PermissionCode,
-				AccessType:     apiEndpoint.AccessType,
+				Name:            apiEndpoint.EndpointName,
+				Description:     desc,
+				HttpMethod:      apiEndpoint.HttpMethod,
+				PathTemplate:    apiEndpoint.PathTemplate,
+				ResourceTypeID:  apiEndpoint.ResourceTypeID,
+				PermissionCode:  apiEndpoint.PermissionCode,
+				AccessType:      apiEndpoint.AccessType,
+				UsageUnitSource: apiEndpoint.UsageUnitSource,
+				UsageUnitKey:    textPtr(apiEndpoint.UsageUnitKey),
+				UpstreamID:      int4Ptr(apiEndpoint.UpstreamID),
+				PathRewrite:     textPtr(apiEndpoint.PathRewrite),
+				HostPattern:     textPtr(apiEndpoint.HostPattern),
+				HeaderMatchers:  parseHeaderMatchers(apiEndpoint.HeaderMatchers),
+				MatchPriority:   apiEndpoint.MatchPriority,
 			},
 		})
 	}
@@ -246,3 +365,237 @@ func (s *ResourceService) DeleteApiEndpointsById(ctx context.Context, id int) er
 
 	return nil
 }
+
+// MatchApiEndpoint tells which endpoint GateKeeper matches the request to,
+// with the values of its path parameters.
+func (s *ResourceService) MatchApiEndpoint(ctx context.Context, req *route.RouteRequest) (MatchEndpointOutput, error) {
+
+	matcher, endpoints, err := s.endpointMatcher(ctx)
+	if err != nil {
+		return MatchEndpointOutput{}, err
+	}
+
+	code, params, found := matcher.MatchParams(*req, nil)
+	if !found {
+		return MatchEndpointOutput{}, &server.ApiError{
+			Code:    http.StatusNotFound,
+			Message: fmt.Sprintf("no endpoint matches %s %s", req.Method, req.Path),
+		}
+	}
+
+	endpoint := endpoints[code]
+	output := MatchEndpointOutput{
+		ID:           int(endpoint.ApiEndpointID),
+		Name:         endpoint.EndpointName,
+		HttpMethod:   endpoint.HttpMethod,
+		PathTemplate: endpoint.PathTemplate,
+		Params:       make(map[string]string, len(params)),
+	}
+	for _, param := range params {
+		output.Params[param.Key] = param.Value
+	}
+
+	return output, nil
+}
+
+// withRouteLock runs fn in a transaction holding the lock of the endpoint
+// routes, so two writes can't both pass checkRouteConflicts and add routes
+// conflicting with each other.
+func (s *ResourceService) withRouteLock(ctx context.Context, fn func(db *sqlcgen.Queries) error) error {
+
+	tx, err := s.Conn.Begin(ctx)
+	if err != nil {
+		return server.NewError(server.ErrorInternal, "couldn't start a transaction", err)
+	}
+	defer tx.Rollback(ctx) // safe to call always
+
+	db := s.DB.WithTx(tx)
+	if err := db.LockApiEndpointRoutes(ctx); err != nil {
+		return server.NewError(server.ErrorInternal, "couldn't lock the endpoint routes", err)
+	}
+	if err := fn(db); err != nil {
+		return err
+	}
+
+	if err := tx.Commit(ctx); err != nil {
+		return server.NewError(server.ErrorInternal, "couldn't save the endpoints", err)
+	}
+	return nil
+}
+
+// checkRouteConflicts adds the endpoints, in place of the ones of the same
+// name and of the replaced ones, to a Matcher holding the routes of their
+// methods, so a route GateKeeper would leave out is refused with 409. Run it
+// under withRouteLock.
+func (s *ResourceService) checkRouteConflicts(ctx context.Context, db *sqlcgen.Queries, inputs []RegisterEndpointParams, replaced ...string) error {
+
+	var methods []string
+	for _, input := range inputs {
+		method := strings.ToUpper(input.HttpMethod)
+		if !slices.Contains(methods, method) {
+			methods = append(methods, method)
+		}
+	}
+
+	rows, err := db.ListApiEndpointRoutes(ctx, methods)
+	if err != nil {
+		return server.NewError(
+			server.ErrorInternal,
+			"couldn't retrieve endpoints",
+			err,
+		)
+	}
+
+	routes := make([]route.Endpoint, 0, len(rows))
+	for _, row := range rows {
+		headers, err := common.ParseHeaderMatchers(row.HeaderMatchers.String)
+		if err != nil {
+			continue
+		}
+		routes = append(routes, route.Endpoint{
+			Code:     row.EndpointName,
+			Method:   row.HttpMethod,
+			Path:     row.PathTemplate,
+			Host:     row.HostPattern.String,
+			Headers:  headers,
+			Priority: row.MatchPriority,
+		})
+	}
+
+	matcher := route.NewMatcher()
+	_ = matcher.Load(routes)
+	for _, code := range replaced {
+		matcher.Drop(code)
+	}
+
+	for i := range inputs {
+		endpoint := matcherEndpoint(&inputs[i])
+		matcher.Drop(endpoint.Code)
+
+		err := matcher.Add(endpoint)
+		var conflict *route.RouteConflictError
+		switch {
+		case errors.As(err, &conflict):
+			return &server.ApiError{
+				Code:    http.StatusConflict,
+				Message: conflict.Error(),
+			}
+		case err != nil:
+			return server.NewError(server.ErrorBadRequest, err.Error(), err)
+		}
+	}
+
+	return nil
+}
+
+// endpointMatcher loads every endpoint in a Matcher the way GateKeeper does,
+// with the endpoints by name. The ones already conflicting, or with header
+// matchers it can't read, are left out.
+func (s *ResourceService) endpointMatcher(ctx context.Context) (*route.Matcher, map[string]sqlcgen.ListApiEndpointRow, error) {
+
+	rows, err := common.FetchAll(
+		func(offset, limit int32) ([]sqlcgen.ListApiEndpointRow, error) {
+			return s.DB.ListApiEndpoint(ctx, sqlcgen.ListApiEndpointParams{
+				Limit:  limit,
+				Offset: offset,
+			})
+		}, 10000,
+	)
+	if err != nil {
+		return nil, nil, server.NewError(
+			server.ErrorInternal,
+			"couldn't retrieve endpoints",
+			err,
+		)
+	}
+
+	endpoints := make(map[string]sqlcgen.ListApiEndpointRow, len(rows))
+	matcherEndpoints := make([]route.Endpoint, 0, len(rows))
+	for _, row := range rows {
+		headers, err := common.ParseHeaderMatchers(row.HeaderMatchers.String)
+		if err != nil {
+			continue
+		}
+		endpoints[row.EndpointName] = row
+		matcherEndpoints = append(matcherEndpoints, route.Endpoint{
+			Code:     row.EndpointName,
+			Method:   row.HttpMethod,
+			Path:     row.PathTemplate,
+			Host:     row.HostPattern.String,
+			Headers:  headers,
+			Priority: row.MatchPriority,
+		})
+	}
+
+	matcher := route.NewMatcher()
+	_ = matcher.Load(matcherEndpoints)
+
+	return matcher, endpoints, nil
+}
+
+func matcherEndpoint(input *RegisterEndpointParams) route.Endpoint {
+	return route.Endpoint{
+		Code:     input.Name,
+		Method:   input.HttpMethod,
+		Path:     input.PathTemplate,
+		Host:     converter.ToPgText(input.HostPattern).String,
+		Headers:  input.HeaderMatchers,
+		Priority: input.MatchPriority,
+	}
+}
+
+func endpointCreatedEvent(input *RegisterEndpointParams) common.EndpointCreatedEvent {
+	return common.EndpointCreatedEvent{
+		Path:           input.PathTemplate,
+		Method:         input.HttpMethod,
+		Code:           input.Name,
+		UpstreamID:     int4(input.UpstreamID).Int32,
+		HostPattern:    converter.ToPgText(input.HostPattern).String,
+		HeaderMatchers: input.HeaderMatchers,
+		MatchPriority:  input.MatchPriority,
+	}
+}
+
+// headerMatchers stores the header matchers as a JSON object, NULL when there
+// are none.
+func headerMatchers(matchers map[string]string) pgtype.Text {
+	if len(matchers) == 0 {
+		return pgtype.Text{}
+	}
+	raw, _ := json.Marshal(matchers)
+	return pgtype.Text{String: string(raw), Valid: true}
+}
+
+func usageUnitKey(key *string) pgtype.Text {
+	if key == nil {
+		return pgtype.Text{}
+	}
+	return pgtype.Text{String: *key, Valid: true}
+}
+
+// parseHeaderMatchers reads back what headerMatchers stored, nil if it can't.
+func parseHeaderMatchers(text pgtype.Text) map[string]string {
+	matchers, _ := common.ParseHeaderMatchers(text.String)
+	return matchers
+}
+
+func textPtr(text pgtype.Text) *string {
+	if !text.Valid {
+		return nil
+	}
+	return &text.String
+}
+
+func int4(value *int32) pgtype.Int4 {
+	if value == nil {
+		return pgtype.Int4{}
+	}
+	return pgtype.Int4{Int32: *value, Valid: true}
+}
+
+func int4Ptr(value pgtype.Int4) *int32 {
+	if !value.Valid {
+		return nil
+	}
+	return &value.Int32
+}
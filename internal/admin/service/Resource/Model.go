@@ -8,6 +8,26 @@ type RegisterEndpointParams struct {
 	ResourceTypeID int32   `form:"resource_type_id" json:"resource_type_id" validate:"required"`
 	PermissionCode string  `form:"permission_code" json:"permission_code" validate:"required"`
 	AccessType     string  `form:"access_type" json:"access_type" validate:"required,oneof=free paid private"`
+	// Where GateKeeper reads the usage units multiplying dynamic pricing,
+	// none (default), header, json, messages or bytes
+	UsageUnitSource string `form:"usage_unit_source" json:"usage_unit_source" validate:"omitempty,oneof=none header json messages bytes"`
+	// Header name (default X-Usage-Units) or JSON path into the response body
+	UsageUnitKey *string `form:"usage_unit_key" json:"usage_unit_key"`
+	// Upstream serving the endpoint in proxy mode, the one of the resource
+	// type when empty
+	UpstreamID *int32 `form:"upstream_id" json:"upstream_id"`
+	// Path sent to the upstream in proxy mode, e.g. /v2/accounts/:id for a
+	// path_template of /users/:id. The request path is kept when empty
+	PathRewrite *string `form:"path_rewrite" json:"path_rewrite"`
+	// Host the request must be sent to, e.g. api.example.com or
+	// *.example.com. Any host when empty
+	HostPattern *string `form:"host_pattern" json:"host_pattern"`
+	// Headers the request must send, e.g. {"Accept-Version": "2"}, "*"
+	// accepts any value
+	HeaderMatchers map[string]string `form:"header_matchers" json:"header_matchers"`
+	// Picks between the endpoints with the same path template matching a
+	// request, highest first
+	MatchPriority int32 `form:"match_priority" json:"match_priority"`
 }
 
 type RegisterEndpointOutputs struct {
@@ -19,6 +39,22 @@ type ListEndpointQueryParameters struct {
 	ResourceTypeID *int `form:"resource_type_id" json:"resource_type_id"`
 }
 
+type MatchEndpointQueryParameters struct {
+	Method string `form:"method" binding:"required"`
+	Path   string `form:"path" binding:"required"`
+	Host   string `form:"host"`
+	// Request headers, "Name: value", repeated
+	Header []string `form:"header"`
+}
+
+type MatchEndpointOutput struct {
+	ID           int               `json:"id"`
+	Name         string            `json:"name"`
+	HttpMethod   string            `json:"http_method"`
+	PathTemplate string            `json:"path_template"`
+	Params       map[string]string `json:"params"`
+}
+
 type ListEndpointOutputs struct {
 	ID               int    `json:"id"`
 	ResourceTypeName string `json:"resource_type_name"`
@@ -32,10 +68,16 @@ type CreateResourceTypeParams struct {
 }
 
 type CreateResourceTypeOutput struct {
-	ID int `json:"id"`
+	ID         int    `json:"id"`
+	UpstreamID *int32 `json:"upstream_id"`
 	CreateResourceTypeParams
 }
 
+type SetResourceTypeUpstreamParams struct {
+	// Empty to stop routing the resource type to an upstream
+	UpstreamID *int32 `form:"upstream_id" json:"upstream_id"`
+}
+
 type CreatePermissionTypeParams struct {
 	Name        string  `form:"name" json:"name" validate:"required,min=1"`
 	Code        string  `form:"code" json:"code" validate:"required,min=1"`
@@ -45,3 +87,35 @@ type CreatePermissionTypeParams struct {
 type CreatePermissionTypeOutput struct {
 	CreatePermissionTypeParams
 }
+
+type CreateUpstreamParams struct {
+	Name        string  `form:"name" json:"name" validate:"required,min=1,max=100"`
+	Description *string `form:"description" json:"description"`
+	// Base URLs of the instances serving the upstream
+	Targets       []string `form:"targets" json:"targets" validate:"required,min=1,dive,url"`
+	LoadBalancing string   `form:"load_balancing" json:"load_balancing" validate:"omitempty,oneof=round-robin least-connections"`
+	// Resilience policy, the defaults apply to the fields left out
+	ConnectTimeoutMs  *int32 `form:"connect_timeout_ms" json:"connect_timeout_ms" validate:"omitempty,min=1"`
+	ResponseTimeoutMs *int32 `form:"response_timeout_ms" json:"response_timeout_ms" validate:"omitempty,min=1"`
+	// Longest stall of the response body between two reads, 0 waits forever
+	ReadTimeoutMs *int32 `form:"read_timeout_ms" json:"read_timeout_ms" validate:"omitempty,min=0"`
+	// Retries of a failed idempotent request, on another target if possible
+	MaxRetries *int32 `form:"max_retries" json:"max_retries" validate:"omitempty,min=0,max=10"`
+	// Retries allowed as a share of the requests, so retries can't pile up
+	// on an upstream that is already struggling
+	RetryBudgetPercent *int32 `form:"retry_budget_percent" json:"retry_budget_percent" validate:"omitempty,min=0,max=100"`
+	// Consecutive failures ejecting a target, 0 never ejects
+	EjectAfterFailures *int32 `form:"eject_after_failures" json:"eject_after_failures" validate:"omitempty,min=0"`
+	EjectSeconds       *int32 `form:"eject_seconds" json:"eject_seconds" validate:"omitempty,min=1"`
+	// Request transforms. The caller's credentials are stripped unless
+	// strip_credentials is false, then strip_prefix is cut from the path and
+	// add_prefix put in front of it
+	StripCredentials *bool   `form:"strip_credentials" json:"strip_credentials"`
+	StripPrefix      *string `form:"strip_prefix" json:"strip_prefix"`
+	AddPrefix        *string `form:"add_prefix" json:"add_prefix"`
+}
+
+type UpstreamOutput struct {
+	ID int32 `json:"id"`
+	CreateUpstreamParams
+}
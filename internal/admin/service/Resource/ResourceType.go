@@ -2,6 +2,7 @@ package resource
 
 import (
 	"context"
+	"fmt"
 
 	"github.com/bignyap/go-admin/internal/database/dbutils"
 	"github.com/bignyap/go-admin/internal/database/sqlcgen"
@@ -92,7 +93,8 @@ func (s *ResourceService) ListResourceType(ctx context.Context, limit int, offse
 			description = &resourceType.ResourceTypeDescription.String
 		}
 		output = append(output, CreateResourceTypeOutput{
-			ID: int(resourceType.ResourceTypeID),
+			ID:         int(resourceType.ResourceTypeID),
+			UpstreamID: int4Ptr(resourceType.UpstreamID),
 			CreateResourceTypeParams: CreateResourceTypeParams{
 				Name:        resourceType.ResourceTypeName,
 				Code:        resourceType.ResourceTypeCode,
@@ -104,6 +106,32 @@ func (s *ResourceService) ListResourceType(ctx context.Context, limit int, offse
 	return output, nil
 }
 
+// SetResourceTypeUpstream routes the endpoints of the resource type that
+// don't name their own upstream to upstreamID, nil stops routing them.
+func (s *ResourceService) SetResourceTypeUpstream(ctx context.Context, id int, upstreamID *int32) error {
+
+	affected, err := s.DB.SetResourceTypeUpstream(ctx, sqlcgen.SetResourceTypeUpstreamParams{
+		ResourceTypeID: int32(id),
+		UpstreamID:     int4(upstreamID),
+	})
+	if err != nil {
+		return server.NewError(
+			server.ErrorInternal,
+			"couldn't update the upstream of the resource type",
+			err,
+		)
+	}
+	if affected == 0 {
+		return server.NewError(
+			server.ErrorNotFound,
+			"resource type not found",
+			fmt.Errorf("no resource type with ID %d", id),
+		)
+	}
+
+	return s.publishUpstreamModified(ctx, int4(upstreamID).Int32)
+}
+
 func (s *ResourceService) DeleteResourceType(ctx context.Context, id int) error {
 
 	if err := s.DB.DeleteResourceTypeById(ctx, int32(id)); err != nil {
@@ -0,0 +1,197 @@
+package resource
+
+import (
+	"context"
+	"errors"
+	"fmt"
+
+	"github.com/bignyap/go-admin/internal/common"
+	"github.com/bignyap/go-admin/internal/database/sqlcgen"
+	"github.com/bignyap/go-utilities/converter"
+	"github.com/bignyap/go-utilities/server"
+	"github.com/jackc/pgx/v5"
+)
+
+func (s *ResourceService) CreateUpstream(ctx context.Context, input *CreateUpstreamParams) (UpstreamOutput, error) {
+
+	insertedID, err := s.DB.CreateUpstream(ctx, sqlcgen.CreateUpstreamParams{
+		UpstreamName:          input.Name,
+		UpstreamDescription:   converter.ToPgText(input.Description),
+		UpstreamTargets:       input.Targets,
+		UpstreamLoadBalancing: input.LoadBalancing,
+
+		UpstreamConnectTimeoutMs:   *input.ConnectTimeoutMs,
+		UpstreamResponseTimeoutMs:  *input.ResponseTimeoutMs,
+		UpstreamReadTimeoutMs:      *input.ReadTimeoutMs,
+		UpstreamMaxRetries:         *input.MaxRetries,
+		UpstreamRetryBudgetPercent: *input.RetryBudgetPercent,
+		UpstreamEjectAfterFailures: *input.EjectAfterFailures,
+		UpstreamEjectSeconds:       *input.EjectSeconds,
+
+		UpstreamStripCredentials: *input.StripCredentials,
+		UpstreamStripPrefix:      converter.ToPgText(input.StripPrefix),
+		UpstreamAddPrefix:        converter.ToPgText(input.AddPrefix),
+	})
+	if err != nil {
+		return UpstreamOutput{}, server.NewError(
+			server.ErrorInternal,
+			"couldn't create the upstream",
+			err,
+		)
+	}
+
+	if err := s.publishUpstreamModified(ctx, insertedID); err != nil {
+		return UpstreamOutput{}, err
+	}
+
+	return UpstreamOutput{ID: insertedID, CreateUpstreamParams: *input}, nil
+}
+
+func (s *ResourceService) ListUpstreams(ctx context.Context) ([]UpstreamOutput, error) {
+
+	upstreams, err := s.DB.ListUpstreams(ctx)
+	if err != nil {
+		return []UpstreamOutput{}, server.NewError(
+			server.ErrorInternal,
+			"couldn't retrieve the upstreams",
+			err,
+		)
+	}
+
+	output := make([]UpstreamOutput, 0, len(upstreams))
+	for _, upstream := range upstreams {
+		output = append(output, toUpstreamOutput(upstream))
+	}
+
+	return output, nil
+}
+
+func (s *ResourceService) GetUpstreamById(ctx context.Context, id int) (UpstreamOutput, error) {
+
+	upstream, err := s.DB.GetUpstreamById(ctx, int32(id))
+	if err != nil {
+		if errors.Is(err, pgx.ErrNoRows) {
+			return UpstreamOutput{}, server.NewError(
+				server.ErrorNotFound,
+				"upstream not found",
+				err,
+			)
+		}
+		return UpstreamOutput{}, server.NewError(
+			server.ErrorInternal,
+			"couldn't retrieve the upstream",
+			err,
+		)
+	}
+
+	return toUpstreamOutput(upstream), nil
+}
+
+func (s *ResourceService) UpdateUpstream(ctx context.Context, id int, input *CreateUpstreamParams) (UpstreamOutput, error) {
+
+	affected, err := s.DB.UpdateUpstreamById(ctx, sqlcgen.UpdateUpstreamByIdParams{
+		UpstreamID:            int32(id),
+		UpstreamName:          input.Name,
+		UpstreamDescription:   converter.ToPgText(input.Description),
+		UpstreamTargets:       input.Targets,
+		UpstreamLoadBalancing: input.LoadBalancing,
+
+		UpstreamConnectTimeoutMs:   *input.ConnectTimeoutMs,
+		UpstreamResponseTimeoutMs:  *input.ResponseTimeoutMs,
+		UpstreamReadTimeoutMs:      *input.ReadTimeoutMs,
+		UpstreamMaxRetries:         *input.MaxRetries,
+		UpstreamRetryBudgetPercent: *input.RetryBudgetPercent,
+		UpstreamEjectAfterFailures: *input.EjectAfterFailures,
+		UpstreamEjectSeconds:       *input.EjectSeconds,
+
+		UpstreamStripCredentials: *input.StripCredentials,
+		UpstreamStripPrefix:      converter.ToPgText(input.StripPrefix),
+		UpstreamAddPrefix:        converter.ToPgText(input.AddPrefix),
+	})
+	if err != nil {
+		return UpstreamOutput{}, server.NewError(
+			server.ErrorInternal,
+			"couldn't update the upstream",
+			err,
+		)
+	}
+	if affected == 0 {
+		return UpstreamOutput{}, server.NewError(
+			server.ErrorNotFound,
+			"upstream not found",
+			fmt.Errorf("no upstream with ID %d", id),
+		)
+	}
+
+	if err := s.publishUpstreamModified(ctx, int32(id)); err != nil {
+		return UpstreamOutput{}, err
+	}
+
+	return UpstreamOutput{ID: int32(id), CreateUpstreamParams: *input}, nil
+}
+
+// DeleteUpstream removes an upstream, the endpoints and resource types it
+// served fall back to the default proxy target.
+func (s *ResourceService) DeleteUpstream(ctx context.Context, id int) error {
+
+	affected, err := s.DB.DeleteUpstreamById(ctx, int32(id))
+	if err != nil {
+		return server.NewError(
+			server.ErrorInternal,
+			"couldn't delete the upstream",
+			err,
+		)
+	}
+	if affected == 0 {
+		return server.NewError(
+			server.ErrorNotFound,
+			"upstream not found",
+			fmt.Errorf("no upstream with ID %d", id),
+		)
+	}
+
+	return s.publishUpstreamModified(ctx, int32(id))
+}
+
+// publishUpstreamModified tells the GateKeepers to reload their routes. It
+// runs after the change is stored since they read the routes back from the
+// database.
+func (s *ResourceService) publishUpstreamModified(ctx context.Context, id int32) error {
+
+	err := s.PubSubClient.Publish(ctx, string(common.UpstreamModified), common.UpstreamModifiedEvent{
+		ID: id,
+	})
+	if err != nil {
+		return server.NewError(
+			server.ErrorInternal,
+			"couldn't push to the queue",
+			err,
+		)
+	}
+
+	return nil
+}
+
+func toUpstreamOutput(upstream sqlcgen.Upstream) UpstreamOutput {
+	return UpstreamOutput{
+		ID: upstream.UpstreamID,
+		CreateUpstreamParams: CreateUpstreamParams{
+			Name:          upstream.UpstreamName,
+			Description:   textPtr(upstream.UpstreamDescription),
+			Targets:       upstream.UpstreamTargets,
+			LoadBalancing: upstream.UpstreamLoadBalancing,
+
+			ConnectTimeoutMs:   &upstream.UpstreamConnectTimeoutMs,
+			ResponseTimeoutMs:  &upstream.UpstreamResponseTimeoutMs,
+			ReadTimeoutMs:      &upstream.UpstreamReadTimeoutMs,
+			MaxRetries:         &upstream.UpstreamMaxRetries,
+			RetryBudgetPercent: &upstream.UpstreamRetryBudgetPercent,
+			EjectAfterFailures: &upstream.UpstreamEjectAfterFailures,
+			EjectSeconds:       &upstream.UpstreamEjectSeconds,
+
+			StripCredentials: &upstream.UpstreamStripCredentials,
+			StripPrefix:      textPtr(upstream.UpstreamStripPrefix),
+			AddPrefix:        textPtr(upstream.UpstreamAddPrefix),
+		},
+	}
+}
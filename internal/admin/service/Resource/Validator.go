@@ -2,7 +2,12 @@ package resource
 
 import (
 	"fmt"
+	"net/http"
+	"net/url"
+	"strings"
 
+	"github.com/bignyap/go-admin/internal/common"
+	"github.com/bignyap/go-admin/internal/common/route"
 	"github.com/bignyap/go-admin/internal/database/sqlcgen"
 	"github.com/gin-gonic/gin"
 	"github.com/jackc/pgx/v5/pgtype"
@@ -18,6 +23,15 @@ func (h *ResourceService) ValidateRegisterInput(c *gin.Context) (*RegisterEndpoi
 	if err := h.Validator.Struct(input); err != nil {
 		return nil, fmt.Errorf("validation failed: %w", err)
 	}
+	if err := validateUsageUnits(&input); err != nil {
+		return nil, err
+	}
+	if err := validatePathRewrite(&input); err != nil {
+		return nil, err
+	}
+	if err := validateMatchConditions(&input); err != nil {
+		return nil, err
+	}
 
 	return &input, nil
 }
@@ -29,8 +43,17 @@ func (h *ResourceService) ValidateRegisterBatchInput(c *gin.Context) ([]Register
 		return []RegisterEndpointParams{}, fmt.Errorf("invalid JSON: %w", err)
 	}
 
-	for i, input := range inputs {
-		if err := h.Validator.Struct(input); err != nil {
+	for i := range inputs {
+		if err := h.Validator.Struct(inputs[i]); err != nil {
+			return nil, fmt.Errorf("validation failed at index %d: %w", i, err)
+		}
+		if err := validateUsageUnits(&inputs[i]); err != nil {
+			return nil, fmt.Errorf("validation failed at index %d: %w", i, err)
+		}
+		if err := validatePathRewrite(&inputs[i]); err != nil {
+			return nil, fmt.Errorf("validation failed at index %d: %w", i, err)
+		}
+		if err := validateMatchConditions(&inputs[i]); err != nil {
 			return nil, fmt.Errorf("validation failed at index %d: %w", i, err)
 		}
 	}
@@ -38,6 +61,87 @@ func (h *ResourceService) ValidateRegisterBatchInput(c *gin.Context) ([]Register
 	return inputs, nil
 }
 
+// validateUsageUnits defaults the usage unit source to none and makes sure
+// the json source says where to look.
+func validateUsageUnits(input *RegisterEndpointParams) error {
+	if input.UsageUnitSource == "" {
+		input.UsageUnitSource = common.UsageUnitSourceNone
+	}
+	if input.UsageUnitKey != nil && *input.UsageUnitKey == "" {
+		input.UsageUnitKey = nil
+	}
+	switch input.UsageUnitSource {
+	case common.UsageUnitSourceJSON:
+		if input.UsageUnitKey == nil {
+			return fmt.Errorf("usage_unit_key is required for the json usage unit source")
+		}
+		for _, segment := range strings.Split(*input.UsageUnitKey, ".") {
+			if segment == "" {
+				return fmt.Errorf("invalid usage_unit_key %q, expected a dotted path such as usage.total_tokens", *input.UsageUnitKey)
+			}
+		}
+	case common.UsageUnitSourceNone, common.UsageUnitSourceMessages, common.UsageUnitSourceBytes:
+		input.UsageUnitKey = nil
+	}
+	return nil
+}
+
+// validatePathRewrite makes sure the rewrite template only uses parameters
+// the path template captures.
+func validatePathRewrite(input *RegisterEndpointParams) error {
+	if input.PathRewrite == nil || *input.PathRewrite == "" {
+		input.PathRewrite = nil
+		return nil
+	}
+	if !strings.HasPrefix(*input.PathRewrite, "/") {
+		return fmt.Errorf("invalid path_rewrite %q, expected a path starting with /", *input.PathRewrite)
+	}
+
+	params := make(map[string]bool)
+	for _, segment := range strings.Split(input.PathTemplate, "/") {
+		if strings.HasPrefix(segment, ":") || strings.HasPrefix(segment, "*") {
+			params[segment[1:]] = true
+		}
+	}
+	for _, segment := range strings.Split(*input.PathRewrite, "/") {
+		if (strings.HasPrefix(segment, ":") || strings.HasPrefix(segment, "*")) && !params[segment[1:]] {
+			return fmt.Errorf("path_rewrite uses %s which path_template %s doesn't capture", segment, input.PathTemplate)
+		}
+	}
+	return nil
+}
+
+// validateMatchConditions checks the host pattern, a host name that may start
+// with *. for any subdomain, and the header matchers.
+func validateMatchConditions(input *RegisterEndpointParams) error {
+	if input.HostPattern != nil {
+		host := strings.ToLower(strings.TrimSpace(*input.HostPattern))
+		input.HostPattern = &host
+		if host == "" {
+			input.HostPattern = nil
+		}
+	}
+	if input.HostPattern != nil {
+		host := strings.TrimPrefix(*input.HostPattern, "*.")
+		if host == "" || strings.ContainsAny(host, "*:/ ") || strings.HasSuffix(host, ".") {
+			return fmt.Errorf("invalid host_pattern %q, expected a host such as api.example.com or *.example.com", *input.HostPattern)
+		}
+	}
+
+	for name, value := range input.HeaderMatchers {
+		if strings.TrimSpace(name) == "" || strings.ContainsAny(name, " :") {
+			return fmt.Errorf("invalid header name %q in header_matchers", name)
+		}
+		if value == "" {
+			return fmt.Errorf("header_matchers needs a value for %s, \"*\" accepts any", name)
+		}
+	}
+	if len(input.HeaderMatchers) == 0 {
+		input.HeaderMatchers = nil
+	}
+	return nil
+}
+
 func (h *ResourceService) CreateResourceTypeFormValidator(c *gin.Context) (*sqlcgen.CreateResourceTypeParams, error) {
 
 	var input CreateResourceTypeParams
@@ -146,6 +250,99 @@ func (h *ResourceService) CreatePermissionTypeJSONValidation(c *gin.Context) ([]
 	return outputs, nil
 }
 
+func (h *ResourceService) ValidateUpstreamInput(c *gin.Context) (*CreateUpstreamParams, error) {
+
+	var input CreateUpstreamParams
+	if err := c.ShouldBind(&input); err != nil {
+		return nil, fmt.Errorf("invalid input: %w", err)
+	}
+
+	if err := h.Validator.Struct(input); err != nil {
+		return nil, fmt.Errorf("validation failed: %w", err)
+	}
+
+	for _, target := range input.Targets {
+		u, err := url.Parse(target)
+		if err != nil || (u.Scheme != "http" && u.Scheme != "https") || u.Host == "" {
+			return nil, fmt.Errorf("invalid target %q, expected an http(s) base URL", target)
+		}
+	}
+	if input.LoadBalancing == "" {
+		input.LoadBalancing = common.LoadBalancingRoundRobin
+	}
+	defaultInt32(&input.ConnectTimeoutMs, common.DefaultUpstreamConnectTimeoutMs)
+	defaultInt32(&input.ResponseTimeoutMs, common.DefaultUpstreamResponseTimeoutMs)
+	defaultInt32(&input.ReadTimeoutMs, common.DefaultUpstreamReadTimeoutMs)
+	defaultInt32(&input.MaxRetries, common.DefaultUpstreamMaxRetries)
+	defaultInt32(&input.RetryBudgetPercent, common.DefaultUpstreamRetryBudgetPercent)
+	defaultInt32(&input.EjectAfterFailures, common.DefaultUpstreamEjectAfterFailures)
+	defaultInt32(&input.EjectSeconds, common.DefaultUpstreamEjectSeconds)
+
+	if input.StripCredentials == nil {
+		strip := true
+		input.StripCredentials = &strip
+	}
+	if err := validatePathPrefix("strip_prefix", &input.StripPrefix); err != nil {
+		return nil, err
+	}
+	if err := validatePathPrefix("add_prefix", &input.AddPrefix); err != nil {
+		return nil, err
+	}
+
+	return &input, nil
+}
+
+func validatePathPrefix(name string, prefix **string) error {
+	if *prefix == nil || **prefix == "" {
+		*prefix = nil
+		return nil
+	}
+	if !strings.HasPrefix(**prefix, "/") || strings.HasSuffix(**prefix, "/") {
+		return fmt.Errorf("invalid %s %q, expected a path such as /api", name, **prefix)
+	}
+	return nil
+}
+
+func defaultInt32(value **int32, def int32) {
+	if *value == nil {
+		*value = &def
+	}
+}
+
+func (h *ResourceService) ValidateResourceTypeUpstreamInput(c *gin.Context) (*SetResourceTypeUpstreamParams, error) {
+
+	var input SetResourceTypeUpstreamParams
+	if err := c.ShouldBind(&input); err != nil {
+		return nil, fmt.Errorf("invalid input: %w", err)
+	}
+
+	return &input, nil
+}
+
+func (s *ResourceService) MatchEndpointQueryValidation(c *gin.Context) (*route.RouteRequest, error) {
+
+	var query MatchEndpointQueryParameters
+	if err := c.ShouldBindQuery(&query); err != nil {
+		return nil, err
+	}
+
+	header := make(http.Header, len(query.Header))
+	for _, h := range query.Header {
+		name, value, ok := strings.Cut(h, ":")
+		if !ok || strings.TrimSpace(name) == "" {
+			return nil, fmt.Errorf("invalid header %q, expected \"Name: value\"", h)
+		}
+		header.Add(strings.TrimSpace(name), strings.TrimSpace(value))
+	}
+
+	return &route.RouteRequest{
+		Method: strings.ToUpper(query.Method),
+		Host:   query.Host,
+		Path:   query.Path,
+		Header: header.Get,
+	}, nil
+}
+
 func (s *ResourceService) ListEndpointQueryValidation(c *gin.Context) (ListEndpointQueryParameters, error) {
 
 	var filters ListEndpointQueryParameters
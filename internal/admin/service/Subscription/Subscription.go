@@ -2,27 +2,21 @@ package subscription
 
 import (
 	"context"
+	"fmt"
+	"math"
+	"net/http"
+	"slices"
 	"strings"
 	"time"
 
 	"github.com/bignyap/go-admin/internal/common"
-	"github.com/bignyap/go-admin/internal/database/dbutils"
 	"github.com/bignyap/go-admin/internal/database/sqlcgen"
 	"github.com/bignyap/go-utilities/converter"
 	"github.com/bignyap/go-utilities/server"
-	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgtype"
 	"github.com/jinzhu/copier"
 )
 
-type BulkSubscriptionInserter struct {
-	Subscriptions       []sqlcgen.CreateSubscriptionsParams
-	SubscriptionService *SubscriptionService
-}
-
-func (input BulkSubscriptionInserter) InsertRows(ctx context.Context, tx pgx.Tx) (int64, error) {
-	return input.SubscriptionService.DB.CreateSubscriptions(ctx, input.Subscriptions)
-}
-
 func (s *SubscriptionService) CreateSubscription(ctx context.Context, input *CreateSubscriptionParams) (CreateSubscriptionOutput, error) {
 
 	params := sqlcgen.CreateSubscriptionParams{
@@ -42,13 +36,25 @@ func (s *SubscriptionService) CreateSubscription(ctx context.Context, input *Cre
 		SubscriptionQuotaResetInterval: converter.ToPgText(input.QuotaResetInterval),
 	}
 
-	insertedID, err := s.DB.CreateSubscription(ctx, params)
+	var insertedID int32
+	err := s.withSubscriptionLock(ctx, []int32{params.OrganizationID}, func(db *sqlcgen.Queries) error {
+		if err := s.checkOverlap(ctx, db, subscriptionWindowFromCreate(params), 0); err != nil {
+			return err
+		}
+
+		var err error
+		insertedID, err = db.CreateSubscription(ctx, params)
+		if err != nil {
+			return server.NewError(
+				server.ErrorInternal,
+				"couldn't create subscription",
+				err,
+			)
+		}
+		return nil
+	})
 	if err != nil {
-		return CreateSubscriptionOutput{}, server.NewError(
-			server.ErrorInternal,
-			"couldn't create subscription",
-			err,
-		)
+		return CreateSubscriptionOutput{}, err
 	}
 
 	output := CreateSubscriptionOutput{
@@ -91,18 +97,30 @@ func (s *SubscriptionService) CreateSubscriptionInBatch(ctx context.Context, inp
 		})
 	}
 
-	inserter := BulkSubscriptionInserter{
-		Subscriptions:       params,
-		SubscriptionService: s,
+	orgIDs := make([]int32, len(params))
+	for i, p := range params {
+		orgIDs[i] = p.OrganizationID
 	}
 
-	affectedRows, err := dbutils.InsertWithTransaction(ctx, s.Conn, inserter)
+	var affectedRows int64
+	err := s.withSubscriptionLock(ctx, orgIDs, func(db *sqlcgen.Queries) error {
+		if err := s.checkBatchOverlap(ctx, db, params); err != nil {
+			return err
+		}
+
+		var err error
+		affectedRows, err = db.CreateSubscriptions(ctx, params)
+		if err != nil {
+			return server.NewError(
+				server.ErrorInternal,
+				"insert failed",
+				err,
+			)
+		}
+		return nil
+	})
 	if err != nil {
-		return 0, server.NewError(
-			server.ErrorInternal,
-			"insert failed",
-			err,
-		)
+		return 0, err
 	}
 
 	return int(affectedRows), nil
@@ -230,25 +248,189 @@ func (s *SubscriptionService) UpdateSubscription(ctx context.Context, input *Upd
 		SubscriptionID:                 int32(input.SubscriptionID),
 	}
 
-	_, err := s.DB.UpdateSubscription(ctx, params)
+	window := subscriptionWindow{
+		OrganizationID:     params.OrganizationID,
+		SubscriptionTierID: params.SubscriptionTierID,
+		StartDate:          params.SubscriptionStartDate,
+		ExpiryDate:         params.SubscriptionExpiryDate,
+		Active:             params.SubscriptionStatus.Valid && params.SubscriptionStatus.Bool,
+	}
+	err := s.withSubscriptionLock(ctx, []int32{params.OrganizationID}, func(db *sqlcgen.Queries) error {
+		if err := s.checkOverlap(ctx, db, window, params.SubscriptionID); err != nil {
+			return err
+		}
+
+		if _, err := db.UpdateSubscription(ctx, params); err != nil {
+			return server.NewError(
+				server.ErrorInternal,
+				"couldn't update the organization",
+				err,
+			)
+		}
+		return nil
+	})
+	if err != nil {
+		return err
+	}
+
+	err = s.PubSubClient.Publish(ctx, string(common.SubscriptionModified), common.SubscriptionModifiedEvent{
+		ID: int32(input.OrganizationID),
+	})
 	if err != nil {
 		return server.NewError(
 			server.ErrorInternal,
-			"couldn't update the organization",
+			"couldn't push to the queue",
 			err,
 		)
 	}
 
-	err = s.PubSubClient.Publish(ctx, string(common.SubscriptionModified), common.SubscriptionModifiedEvent{
-		ID: int32(input.OrganizationID),
+	return nil
+}
+
+// withSubscriptionLock runs fn in a transaction holding the subscription lock
+// of the organizations, so two writes can't both pass checkOverlap and save
+// overlapping subscriptions. The locks are taken in order so two batches
+// can't deadlock.
+func (s *SubscriptionService) withSubscriptionLock(ctx context.Context, orgIDs []int32, fn func(db *sqlcgen.Queries) error) error {
+
+	tx, err := s.Conn.Begin(ctx)
+	if err != nil {
+		return server.NewError(server.ErrorInternal, "couldn't start a transaction", err)
+	}
+	defer tx.Rollback(ctx) // safe to call always
+
+	db := s.DB.WithTx(tx)
+	orgIDs = slices.Clone(orgIDs)
+	slices.Sort(orgIDs)
+	for _, orgID := range slices.Compact(orgIDs) {
+		if err := db.LockOrganizationSubscriptions(ctx, orgID); err != nil {
+			return server.NewError(server.ErrorInternal, "couldn't lock the subscriptions of the organization", err)
+		}
+	}
+	if err := fn(db); err != nil {
+		return err
+	}
+
+	if err := tx.Commit(ctx); err != nil {
+		return server.NewError(server.ErrorInternal, "couldn't save the subscriptions", err)
+	}
+	return nil
+}
+
+// subscriptionWindow is the part of a subscription that decides whether it
+// competes with another one for the same endpoints.
+type subscriptionWindow struct {
+	OrganizationID     int32
+	SubscriptionTierID int32
+	StartDate          int32
+	ExpiryDate         pgtype.Int4
+	Active             bool
+}
+
+func subscriptionWindowFromCreate(params sqlcgen.CreateSubscriptionParams) subscriptionWindow {
+	return subscriptionWindow{
+		OrganizationID:     params.OrganizationID,
+		SubscriptionTierID: params.SubscriptionTierID,
+		StartDate:          params.SubscriptionStartDate,
+		ExpiryDate:         params.SubscriptionExpiryDate,
+		Active:             params.SubscriptionStatus.Valid && params.SubscriptionStatus.Bool,
+	}
+}
+
+func (w subscriptionWindow) expiry() int32 {
+	if !w.ExpiryDate.Valid {
+		return math.MaxInt32
+	}
+	return w.ExpiryDate.Int32
+}
+
+func (w subscriptionWindow) intersects(other subscriptionWindow) bool {
+	return w.StartDate < other.expiry() && other.StartDate < w.expiry()
+}
+
+// checkOverlap rejects an active subscription whose validity window intersects
+// another active subscription of the same organization covering at least one
+// common endpoint. GateKeeper would otherwise have to guess which one to bill.
+// Run it under withSubscriptionLock.
+func (s *SubscriptionService) checkOverlap(ctx context.Context, db *sqlcgen.Queries, w subscriptionWindow, excludeID int32) error {
+
+	if !w.Active {
+		return nil
+	}
+
+	overlapping, err := db.GetOverlappingSubscriptions(ctx, sqlcgen.GetOverlappingSubscriptionsParams{
+		OrganizationID:        w.OrganizationID,
+		ExcludeSubscriptionID: excludeID,
+		ExpiryDate:            w.ExpiryDate,
+		StartDate:             w.StartDate,
+		SubscriptionTierID:    w.SubscriptionTierID,
 	})
 	if err != nil {
 		return server.NewError(
 			server.ErrorInternal,
-			"couldn't push to the queue",
+			"couldn't check for overlapping subscriptions",
 			err,
 		)
 	}
 
+	if len(overlapping) > 0 {
+		return &server.ApiError{
+			Code: http.StatusConflict,
+			Message: fmt.Sprintf(
+				"subscription overlaps with active subscription '%s' (id %d) of the organization",
+				overlapping[0].SubscriptionName, overlapping[0].SubscriptionID,
+			),
+		}
+	}
+
+	return nil
+}
+
+// checkBatchOverlap checks every new subscription against the DB and against
+// the other subscriptions of the same batch.
+func (s *SubscriptionService) checkBatchOverlap(ctx context.Context, db *sqlcgen.Queries, params []sqlcgen.CreateSubscriptionsParams) error {
+
+	windows := make([]subscriptionWindow, len(params))
+	for i, p := range params {
+		windows[i] = subscriptionWindowFromCreate(sqlcgen.CreateSubscriptionParams(p))
+		if err := s.checkOverlap(ctx, db, windows[i], 0); err != nil {
+			return err
+		}
+	}
+
+	for i := range windows {
+		for j := 0; j < i; j++ {
+			a, b := windows[i], windows[j]
+			if !a.Active || !b.Active || a.OrganizationID != b.OrganizationID || !a.intersects(b) {
+				continue
+			}
+
+			shared := a.SubscriptionTierID == b.SubscriptionTierID
+			if !shared {
+				var err error
+				shared, err = db.TiersShareEndpoint(ctx, sqlcgen.TiersShareEndpointParams{
+					SubscriptionTierID:   a.SubscriptionTierID,
+					SubscriptionTierID_2: b.SubscriptionTierID,
+				})
+				if err != nil {
+					return server.NewError(
+						server.ErrorInternal,
+						"couldn't check for overlapping subscriptions",
+						err,
+					)
+				}
+			}
+			if shared {
+				return &server.ApiError{
+					Code: http.StatusConflict,
+					Message: fmt.Sprintf(
+						"subscriptions '%s' and '%s' in the batch overlap",
+						params[j].SubscriptionName, params[i].SubscriptionName,
+					),
+				}
+			}
+		}
+	}
+
 	return nil
 }
@@ -22,6 +22,20 @@ type UsageSummaryFilters struct {
 	Offset int32 `form:"offset" default:"1"`
 }
 
+type ShadowDenialFilterQueryParams struct {
+	OrgID      *int    `form:"organization_id"`
+	EndpointID *int    `form:"endpoint_id"`
+	Reason     *string `form:"reason" validate:"omitempty,oneof=permission_denied insufficient_scope subscription_expired quota_exceeded rate_limited"`
+	StartDate  *int    `form:"start_date"`
+	EndDate    *int    `form:"end_date"`
+}
+
+type ShadowDenialFilters struct {
+	ShadowDenialFilterQueryParams
+	Limit  int32
+	Offset int32
+}
+
 type CreateApiUsageSummaryParams struct {
 	StartDate      time.Time `json:"start_date" form:"start_date"`
 	EndDate        time.Time `json:"end_date" form:"end_date"`
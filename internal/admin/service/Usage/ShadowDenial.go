@@ -0,0 +1,31 @@
+package usage
+
+import (
+	"context"
+
+	"github.com/bignyap/go-admin/internal/database/sqlcgen"
+	"github.com/bignyap/go-utilities/converter"
+	"github.com/bignyap/go-utilities/server"
+)
+
+// GetShadowDenialSummary totals the requests GateKeeper would have denied
+// organizations in shadow mode, per organization, endpoint and reason.
+func (s *UsageSummaryService) GetShadowDenialSummary(ctx context.Context, filters ShadowDenialFilters) ([]sqlcgen.GetShadowDenialSummaryRow, error) {
+	output, err := s.DB.GetShadowDenialSummary(ctx, sqlcgen.GetShadowDenialSummaryParams{
+		OrgID:      converter.ToPgInt4(filters.OrgID),
+		EndpointID: converter.ToPgInt4(filters.EndpointID),
+		Reason:     converter.ToPgText(filters.Reason),
+		StartDate:  converter.ToPgInt4(filters.StartDate),
+		EndDate:    converter.ToPgInt4(filters.EndDate),
+		Limit:      filters.Limit,
+		Offset:     filters.Offset,
+	})
+	if err != nil {
+		return nil, server.NewError(
+			server.ErrorInternal,
+			"couldn't fetch the shadow denials",
+			err,
+		)
+	}
+	return output, nil
+}
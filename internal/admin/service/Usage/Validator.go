@@ -48,6 +48,23 @@ func (s *UsageSummaryService) UsageSummaryQueryValidation(c *gin.Context) (Usage
 	return filters, nil
 }
 
+func (s *UsageSummaryService) ShadowDenialQueryValidation(c *gin.Context) (ShadowDenialFilterQueryParams, error) {
+
+	var filters ShadowDenialFilterQueryParams
+	if err := c.ShouldBindQuery(&filters); err != nil {
+		return ShadowDenialFilterQueryParams{}, err
+	}
+	if err := s.Validator.Struct(filters); err != nil {
+		return ShadowDenialFilterQueryParams{}, fmt.Errorf("validation error: %w", err)
+	}
+
+	if filters.StartDate != nil && filters.EndDate != nil && *filters.StartDate > *filters.EndDate {
+		return ShadowDenialFilterQueryParams{}, fmt.Errorf("start_date cannot be greater than end_date")
+	}
+
+	return filters, nil
+}
+
 func GetGroupedUsageSummary[T any, P any](
 	ctx context.Context,
 	queryFunc func(context.Context, P) ([]T, error),
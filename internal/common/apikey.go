@@ -0,0 +1,40 @@
+package common
+
+import (
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/hex"
+	"strings"
+)
+
+// ApiKeyMarker starts every key issued by go-admin, it lets GateKeeper tell an
+// API key apart from other bearer tokens.
+const ApiKeyMarker = "gk_"
+
+// GenerateApiKey returns a new key in the form gk_<prefix>_<secret> along with
+// its public prefix. Only the prefix and the hash of the key are ever stored.
+func GenerateApiKey() (key string, prefix string, err error) {
+
+	prefixBytes := make([]byte, 4)
+	if _, err := rand.Read(prefixBytes); err != nil {
+		return "", "", err
+	}
+	secretBytes := make([]byte, 32)
+	if _, err := rand.Read(secretBytes); err != nil {
+		return "", "", err
+	}
+
+	prefix = ApiKeyMarker + hex.EncodeToString(prefixBytes)
+	key = prefix + "_" + base64.RawURLEncoding.EncodeToString(secretBytes)
+	return key, prefix, nil
+}
+
+func HashApiKey(key string) string {
+	sum := sha256.Sum256([]byte(strings.TrimSpace(key)))
+	return hex.EncodeToString(sum[:])
+}
+
+func IsApiKey(token string) bool {
+	return strings.HasPrefix(token, ApiKeyMarker)
+}
@@ -21,11 +21,53 @@ type PubSubChannel string
 
 const (
 	EndpointCreated       PubSubChannel = "endpoint:created"
+	EndpointUpdated       PubSubChannel = "endpoint:updated"
 	EndpointDeleted       PubSubChannel = "endpoint:deleted"
 	OrganizationModified  PubSubChannel = "organization:modified"
 	SubscriptionModified  PubSubChannel = "subscription:modified"
 	PricingModified       PubSubChannel = "pricing:modified"
 	OrgPermissionModified PubSubChannel = "orgPermission:modified"
+	ApiKeyRevoked         PubSubChannel = "apiKey:revoked"
+	UpstreamModified      PubSubChannel = "upstream:modified"
+)
+
+// Where GateKeeper reads the usage units of a call to an endpoint, see
+// api_endpoint.usage_unit_source. Units multiply the cost of dynamic pricing.
+const (
+	UsageUnitSourceNone   = "none"
+	UsageUnitSourceHeader = "header"
+	UsageUnitSourceJSON   = "json"
+	// Counted while the response is sent, SSE events or WebSocket messages
+	// and the bytes they carry
+	UsageUnitSourceMessages = "messages"
+	UsageUnitSourceBytes    = "bytes"
+)
+
+// How GateKeeper spreads the proxied requests over the targets of an
+// upstream, see upstream.upstream_load_balancing.
+const (
+	LoadBalancingRoundRobin       = "round-robin"
+	LoadBalancingLeastConnections = "least-connections"
+)
+
+// How GateKeeper treats a denied request, see the enforcement key of
+// organization_config and ENFORCEMENT_MODE. In shadow mode the denial is only
+// logged and counted, the request goes through.
+const (
+	EnforcementEnforce = "enforce"
+	EnforcementShadow  = "shadow"
+)
+
+// Resilience policy of an upstream when none is given, the same as the
+// column defaults of the upstream table.
+const (
+	DefaultUpstreamConnectTimeoutMs   = 5000
+	DefaultUpstreamResponseTimeoutMs  = 30000
+	DefaultUpstreamReadTimeoutMs      = 60000
+	DefaultUpstreamMaxRetries         = 1
+	DefaultUpstreamRetryBudgetPercent = 20
+	DefaultUpstreamEjectAfterFailures = 5
+	DefaultUpstreamEjectSeconds       = 30
 )
 
 type RedisPrefix string
@@ -41,6 +83,13 @@ const (
 	EndpointPrefix     RedisPrefix = "endpoint"
 	SubscriptionPrefix RedisPrefix = "subscription"
 	PermissionPrefix   RedisPrefix = "permission"
+	RateLimitPrefix    RedisPrefix = "ratelimit"
+	QuotaPrefix        RedisPrefix = "quota"
+	ApiKeyPrefix       RedisPrefix = "apikey"
+	ReservationPrefix  RedisPrefix = "reservation"
+	CreditHoldPrefix   RedisPrefix = "credithold"
+	IdempotencyPrefix  RedisPrefix = "idempotency"
+	ShadowPrefix       RedisPrefix = "shadow"
 )
 
 var keyTypeTTLs = map[RedisPrefix]time.Duration{
@@ -53,6 +102,8 @@ var keyTypeTTLs = map[RedisPrefix]time.Duration{
 	OrganizationPrefix: 24 * time.Hour,
 	EndpointPrefix:     24 * time.Hour,
 	SubscriptionPrefix: 24 * time.Hour,
+	QuotaPrefix:        5 * time.Minute,
+	ApiKeyPrefix:       24 * time.Hour,
 }
 
 func TTLFor(keyType RedisPrefix) time.Duration {
@@ -87,6 +138,29 @@ func ParseUsageKey(key string) (orgID, subID, endpointID, timestamp int32, err e
 	return int32(org64), int32(sub64), int32(endpoint64), int32(timestamp64), nil
 }
 
+// ParseShadowDenialKey reads the id of a shadow denial counter,
+// <org>:<endpoint>:<reason>:<timestamp>.
+func ParseShadowDenialKey(key string) (orgID, endpointID int32, reason string, timestamp int32, err error) {
+
+	parts := strings.Split(key, ":")
+	if len(parts) != 4 {
+		return 0, 0, "", 0, fmt.Errorf("invalid key format")
+	}
+	org64, err := strconv.ParseInt(parts[0], 10, 32)
+	if err != nil {
+		return 0, 0, "", 0, fmt.Errorf("invalid orgID")
+	}
+	endpoint64, err := strconv.ParseInt(parts[1], 10, 32)
+	if err != nil {
+		return 0, 0, "", 0, fmt.Errorf("invalid endpointID")
+	}
+	timestamp64, err := strconv.ParseInt(parts[3], 10, 32)
+	if err != nil {
+		return 0, 0, "", 0, fmt.Errorf("invalid timestamp")
+	}
+	return int32(org64), int32(endpoint64), parts[2], int32(timestamp64), nil
+}
+
 func RedisKeyFormatter[T ~string | ~int | ~int32 | ~int64](args ...T) string {
 	parts := make([]string, len(args))
 	for i, a := range args {
@@ -111,6 +185,21 @@ type EndpointCreatedEvent struct {
 	Code   string
 	Path   string
 	Method string
+	// Upstream serving the endpoint in proxy mode, 0 to use the one of its
+	// resource type
+	UpstreamID int32
+	// Conditions telling the endpoint apart from the others with the same
+	// path, see ParseHeaderMatchers
+	HostPattern    string
+	HeaderMatchers map[string]string
+	MatchPriority  int32
+}
+
+// EndpointUpdatedEvent carries the endpoint as it is after the update.
+type EndpointUpdatedEvent struct {
+	EndpointCreatedEvent
+	// Code of the endpoint before the update, it can be renamed
+	PreviousCode string
 }
 
 type EndpointDeletedEvent struct {
@@ -135,6 +224,68 @@ type PricingModifiedEvent struct {
 	Type string
 }
 
+// UpstreamModifiedEvent is published when an upstream or the upstream of a
+// resource type changes, GateKeeper reloads its routes.
+type UpstreamModifiedEvent struct {
+	ID int32
+}
+
+type ApiKeyRevokedEvent struct {
+	ID             int32
+	Hash           string
+	OrganizationID int32
+}
+
+// OrganizationConfig is the typed view of organization.organization_config.
+// Unknown keys are ignored so the column can still carry free-form settings.
+type OrganizationConfig struct {
+	RateLimit *OrganizationRateLimit `json:"rate_limit,omitempty" validate:"omitempty"`
+	Auth      *OrganizationAuth      `json:"auth,omitempty" validate:"omitempty"`
+	// enforce or shadow, ENFORCEMENT_MODE applies when empty
+	Enforcement string `json:"enforcement,omitempty"`
+}
+
+// OrganizationRateLimit caps the traffic of the whole organization across all
+// endpoints and subscriptions. Zero means no limit for that window.
+type OrganizationRateLimit struct {
+	RequestsPerSecond int32 `json:"requests_per_second" validate:"min=0"`
+	RequestsPerMinute int32 `json:"requests_per_minute" validate:"min=0"`
+}
+
+// OrganizationAuth tells GateKeeper how to validate the bearer JWTs issued by
+// the organization's identity provider. Empty fields fall back to the
+// GateKeeper wide defaults (JWT_* variables).
+type OrganizationAuth struct {
+	Issuer   string   `json:"issuer" validate:"omitempty,url"`
+	Audience []string `json:"audience"`
+	JWKSURL  string   `json:"jwks_url" validate:"omitempty,url"`
+	JWKSFile string   `json:"jwks_file"`
+}
+
+func ParseOrganizationConfig(raw string) (*OrganizationConfig, error) {
+	var cfg OrganizationConfig
+	if strings.TrimSpace(raw) == "" {
+		return &cfg, nil
+	}
+	if err := json.Unmarshal([]byte(raw), &cfg); err != nil {
+		return nil, fmt.Errorf("organization config must be a JSON object: %w", err)
+	}
+	return &cfg, nil
+}
+
+// ParseHeaderMatchers reads the header_matchers of an endpoint, a JSON object
+// of header names to the value the request must send, "*" for any.
+func ParseHeaderMatchers(raw string) (map[string]string, error) {
+	if strings.TrimSpace(raw) == "" {
+		return nil, nil
+	}
+	var matchers map[string]string
+	if err := json.Unmarshal([]byte(raw), &matchers); err != nil {
+		return nil, fmt.Errorf("header matchers must be a JSON object of strings: %w", err)
+	}
+	return matchers, nil
+}
+
 func FetchAll[T any](fetchFunc func(offset, batchsize int32) ([]T, error), batchsize int32) ([]T, error) {
 
 	var results []T
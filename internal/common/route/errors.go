@@ -0,0 +1,51 @@
+package route
+
+import (
+	"errors"
+	"fmt"
+	"slices"
+	"strings"
+)
+
+// Returned by the Matcher for a template it can't route.
+var (
+	ErrInvalidRoute  = errors.New("invalid route")
+	ErrRouteConflict = errors.New("route conflict")
+)
+
+// ErrStaleSnapshot is returned by Matcher.Reconcile when the routes changed
+// after the endpoints were read.
+var ErrStaleSnapshot = errors.New("routes changed since the endpoints were read")
+
+// RouteConflictError is returned when a route would match the same paths as
+// one the Matcher already has.
+type RouteConflictError struct {
+	Route    Endpoint
+	Existing Endpoint
+}
+
+func (e *RouteConflictError) Error() string {
+	return fmt.Sprintf("%s of endpoint %s conflicts with %s of endpoint %s",
+		describeRoute(e.Route), e.Route.Code, describeRoute(e.Existing), e.Existing.Code)
+}
+
+// describeRoute is "GET /v1/items on api.example.com with Accept-Version: 2".
+func describeRoute(e Endpoint) string {
+	route := e.Method + " " + e.Path
+	if e.Host != "" {
+		route += " on " + e.Host
+	}
+	headers := make([]string, 0, len(e.Headers))
+	for name, value := range e.Headers {
+		headers = append(headers, name+": "+value)
+	}
+	if len(headers) > 0 {
+		slices.Sort(headers)
+		route += " with " + strings.Join(headers, ", ")
+	}
+	return route
+}
+
+func (e *RouteConflictError) Unwrap() error {
+	return ErrRouteConflict
+}
@@ -0,0 +1,145 @@
+//go:build httprouter
+
+// The matcher against the httprouter-backed one it replaced, kept behind a
+// build tag so the default build doesn't compile httprouter in:
+//
+//	go test -tags httprouter -bench . ./internal/common/route
+
+package route
+
+import (
+	"net/http"
+	"net/url"
+	"sync"
+	"testing"
+
+	"github.com/julienschmidt/httprouter"
+)
+
+// legacyMatcher is the former Matcher: a fake request served by an
+// httprouter.Router, behind a RWMutex.
+type legacyMatcher struct {
+	router *httprouter.Router
+	lock   sync.RWMutex
+}
+
+type capture struct {
+	header http.Header
+	code   string
+	found  bool
+}
+
+func (c *capture) Header() http.Header         { return c.header }
+func (c *capture) Write(_ []byte) (int, error) { return 0, nil }
+
+func (c *capture) WriteHeader(_ int) {
+	c.found = true
+	c.code = c.header.Get("x-endpoint-code")
+}
+
+func newLegacyMatcher(endpoints []Endpoint) *legacyMatcher {
+	r := httprouter.New()
+	for _, ep := range endpoints {
+		r.Handle(ep.Method, ep.Path, func(w http.ResponseWriter, r *http.Request, _ httprouter.Params) {
+			w.Header().Set("x-endpoint-code", ep.Code)
+			w.WriteHeader(http.StatusOK)
+		})
+	}
+	return &legacyMatcher{router: r}
+}
+
+func (m *legacyMatcher) Match(method, path string) (string, bool) {
+	m.lock.RLock()
+	defer m.lock.RUnlock()
+
+	rw := &capture{header: http.Header{}}
+	req := &http.Request{
+		Method: method,
+		URL:    &url.URL{Path: path},
+	}
+	m.router.ServeHTTP(rw, req)
+	return rw.code, rw.found
+}
+
+func TestLegacyMatchesLikeMatcher(t *testing.T) {
+	endpoints, requests := benchmarkEndpoints(1000)
+	legacy := newLegacyMatcher(endpoints)
+	m := newTestMatcher(t, endpoints...)
+
+	for i, req := range requests {
+		want := endpoints[i].Code
+		if code, _ := legacy.Match(req.Method, req.Path); code != want {
+			t.Errorf("httprouter matched %s %s to %q, want %q", req.Method, req.Path, code, want)
+		}
+		if code, _ := m.Match(req); code != want {
+			t.Errorf("matcher matched %s %s to %q, want %q", req.Method, req.Path, code, want)
+		}
+	}
+}
+
+func BenchmarkLegacyMatch(b *testing.B) {
+	endpoints, requests := benchmarkEndpoints(1000)
+	legacy := newLegacyMatcher(endpoints)
+
+	b.ReportAllocs()
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		req := requests[i%len(requests)]
+		if _, found := legacy.Match(req.Method, req.Path); !found {
+			b.Fatal("no match")
+		}
+	}
+}
+
+func BenchmarkLegacyMatchParallel(b *testing.B) {
+	endpoints, requests := benchmarkEndpoints(1000)
+	legacy := newLegacyMatcher(endpoints)
+
+	b.ReportAllocs()
+	b.ResetTimer()
+	b.RunParallel(func(pb *testing.PB) {
+		for i := 0; pb.Next(); i++ {
+			req := requests[i%len(requests)]
+			legacy.Match(req.Method, req.Path)
+		}
+	})
+}
+
+func BenchmarkMatchParallel(b *testing.B) {
+	endpoints, requests := benchmarkEndpoints(1000)
+	m := newTestMatcher(b, endpoints...)
+
+	b.ReportAllocs()
+	b.ResetTimer()
+	b.RunParallel(func(pb *testing.PB) {
+		for i := 0; pb.Next(); i++ {
+			m.Match(requests[i%len(requests)])
+		}
+	})
+}
+
+// The former Drop rebuilt the router from every endpoint
+func BenchmarkLegacyDropAdd(b *testing.B) {
+	endpoints, _ := benchmarkEndpoints(1000)
+
+	b.ReportAllocs()
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		newLegacyMatcher(endpoints)
+	}
+}
+
+func BenchmarkDropAdd(b *testing.B) {
+	endpoints, _ := benchmarkEndpoints(1000)
+	m := newTestMatcher(b, endpoints...)
+
+	b.ReportAllocs()
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		ep := endpoints[i%len(endpoints)]
+		m.Drop(ep.Code)
+		if err := m.Add(ep); err != nil {
+			b.Fatal(err)
+		}
+	}
+}
@@ -0,0 +1,747 @@
+// Package route finds the endpoint of a request. GateKeeper routes the
+// requests with it and go-admin checks the endpoints it registers for
+// conflicts with the same rules.
+package route
+
+import (
+	"errors"
+	"fmt"
+	"maps"
+	"net/http"
+	"slices"
+	"strings"
+)
+
+// The routes of a method form a radix tree of path segments. A static node
+// holds one or more segments ("/v1/users"), a run of segments that doesn't
+// branch stays in a single node. A wildcard takes a whole segment: :name
+// matches one non-empty segment, *name the rest of the path and must come
+// last. A ':' or '*' inside a segment is literal. The wildcard names belong
+// to the routes, /users/:id and /users/:name end at the same node.
+//
+// Below a node the static children are tried first, then the :name child,
+// then the *name one, so /users/me beats /users/:id. The routes ending at
+// the same node are tried by priority, then the most specific first: an
+// exact host, then a wildcard one, then any host, and more headers before
+// fewer. Two routes conflict when they have the same template, host and
+// headers.
+//
+// The nodes are never changed once in a snapshot. A change copies the nodes
+// on the way to the route it adds or removes and shares the others.
+
+type nodeKind uint8
+
+const (
+	staticNode nodeKind = iota
+	paramNode
+	catchAllNode
+)
+
+type node struct {
+	kind nodeKind
+	// Segments of a static node, "/v1/users"
+	path string
+	// First byte of the path of each static child, after its '/'
+	indices  string
+	children []*node
+	// Static children by first segment, once there are too many to scan
+	bySegment map[string]*node
+	param     *node
+	catchAll  *node
+	// Routes ending at the node, in the order they are tried
+	routes []*route
+}
+
+// Past this many static children, match looks them up in a map
+const maxScannedChildren = 16
+
+type route struct {
+	endpoint *Endpoint
+	// Names of the wildcards of the template, in order
+	names []string
+	// Lower cased host, ".example.com" for *.example.com
+	host     string
+	wildcard bool
+	// Canonical header names, sorted, with their value
+	headers []headerMatcher
+}
+
+type headerMatcher struct {
+	name  string
+	value string
+}
+
+type routeTable struct {
+	trees  map[string]*node
+	byCode map[string][]Endpoint
+	// Bumped by every change, see Matcher.Version
+	version uint64
+}
+
+type routeToken struct {
+	kind nodeKind
+	path string
+}
+
+// === Constructor ===
+
+func NewMatcher() *Matcher {
+	m := &Matcher{}
+	m.table.Store(newRouteTable())
+	return m
+}
+
+func newRouteTable() *routeTable {
+	return &routeTable{
+		trees:  make(map[string]*node),
+		byCode: make(map[string][]Endpoint),
+	}
+}
+
+// === Helpers ===
+
+func normalizePath(path string) string {
+	if !strings.HasPrefix(path, "/") {
+		return "/" + path
+	}
+	return path
+}
+
+// parseTemplate splits the template into runs of static segments and
+// wildcards, and returns the names of the wildcards.
+func parseTemplate(template string) ([]routeToken, []string, error) {
+	var tokens []routeToken
+	var names []string
+	segments := strings.Split(template, "/")[1:]
+	for i, segment := range segments {
+		if !strings.HasPrefix(segment, ":") && !strings.HasPrefix(segment, "*") {
+			if n := len(tokens); n > 0 && tokens[n-1].kind == staticNode {
+				tokens[n-1].path += "/" + segment
+			} else {
+				tokens = append(tokens, routeToken{kind: staticNode, path: "/" + segment})
+			}
+			continue
+		}
+
+		name := segment[1:]
+		switch {
+		case name == "":
+			return nil, nil, fmt.Errorf("%w: %s: wildcard without a name", ErrInvalidRoute, template)
+		case slices.Contains(names, name):
+			return nil, nil, fmt.Errorf("%w: %s: wildcard %s used twice", ErrInvalidRoute, template, name)
+		}
+		names = append(names, name)
+
+		kind := paramNode
+		if segment[0] == '*' {
+			kind = catchAllNode
+			if i != len(segments)-1 {
+				return nil, nil, fmt.Errorf("%w: %s: catch-all %s must be the last segment", ErrInvalidRoute, template, segment)
+			}
+		}
+		tokens = append(tokens, routeToken{kind: kind})
+	}
+	return tokens, names, nil
+}
+
+// newRoute checks the conditions of the endpoint and readies them for match.
+func newRoute(ep *Endpoint, names []string) (*route, error) {
+
+	r := &route{endpoint: ep, names: names}
+
+	host := strings.ToLower(strings.TrimSpace(ep.Host))
+	if rest, ok := strings.CutPrefix(host, "*."); ok {
+		host, r.wildcard = "."+rest, true
+	}
+	if strings.ContainsAny(host, "*:/ ") || strings.HasSuffix(host, ".") || (r.wildcard && len(host) == 1) {
+		return nil, fmt.Errorf("%w: invalid host %q, expected a name such as api.example.com or *.example.com", ErrInvalidRoute, ep.Host)
+	}
+	r.host = host
+
+	for name, value := range ep.Headers {
+		name = http.CanonicalHeaderKey(strings.TrimSpace(name))
+		if name == "" || value == "" {
+			return nil, fmt.Errorf("%w: header matchers need a name and a value, \"*\" for any", ErrInvalidRoute)
+		}
+		r.headers = append(r.headers, headerMatcher{name: name, value: value})
+	}
+	slices.SortFunc(r.headers, func(a, b headerMatcher) int {
+		return strings.Compare(a.name, b.name)
+	})
+	return r, nil
+}
+
+// sameConditions tells whether r and o accept the same requests.
+func (r *route) sameConditions(o *route) bool {
+	return r.host == o.host && r.wildcard == o.wildcard && slices.Equal(r.headers, o.headers)
+}
+
+// before tells whether r is tried before o.
+func (r *route) before(o *route) bool {
+	if r.endpoint.Priority != o.endpoint.Priority {
+		return r.endpoint.Priority > o.endpoint.Priority
+	}
+	if r.hostRank() != o.hostRank() {
+		return r.hostRank() > o.hostRank()
+	}
+	return len(r.headers) > len(o.headers)
+}
+
+func (r *route) hostRank() int {
+	switch {
+	case r.host == "":
+		return 0
+	case r.wildcard:
+		return 1
+	}
+	return 2
+}
+
+func (r *route) accepts(req *RouteRequest) bool {
+
+	if r.host != "" {
+		host := req.Host
+		if i := strings.LastIndexByte(host, ':'); i >= 0 && !strings.Contains(host[i:], "]") {
+			host = host[:i]
+		}
+		if r.wildcard {
+			if len(host) <= len(r.host) || !strings.EqualFold(host[len(host)-len(r.host):], r.host) {
+				return false
+			}
+		} else if !strings.EqualFold(host, r.host) {
+			return false
+		}
+	}
+
+	for _, h := range r.headers {
+		if req.Header == nil {
+			return false
+		}
+		value := req.Header(h.name)
+		if value == "" || (h.value != "*" && value != h.value) {
+			return false
+		}
+	}
+	return true
+}
+
+// commonSegments is the length of the segments p and q start with.
+func commonSegments(p, q string) int {
+	l := 0
+	for l < len(p) && l < len(q) && p[l] == q[l] {
+		l++
+	}
+	if (l == len(p) || p[l] == '/') && (l == len(q) || q[l] == '/') {
+		return l
+	}
+	return strings.LastIndexByte(p[:l], '/')
+}
+
+func firstSegment(path string) string {
+	if i := strings.IndexByte(path[1:], '/'); i >= 0 {
+		return path[1 : i+1]
+	}
+	return path[1:]
+}
+
+func indexByte(path string) byte {
+	if len(path) > 1 {
+		return path[1]
+	}
+	return '/'
+}
+
+// === Tree ===
+
+func (n *node) setChildren(children []*node) {
+	indices := make([]byte, len(children))
+	for i, child := range children {
+		indices[i] = indexByte(child.path)
+	}
+	n.children = children
+	n.indices = string(indices)
+	n.bySegment = nil
+	if len(children) > maxScannedChildren {
+		n.bySegment = make(map[string]*node, len(children))
+		for _, child := range children {
+			n.bySegment[firstSegment(child.path)] = child
+		}
+	}
+}
+
+// staticChild is the index of the child starting with the first segment of
+// path, -1 if there is none.
+func (n *node) staticChild(path string) int {
+	segment := firstSegment(path)
+	for i, child := range n.children {
+		if firstSegment(child.path) == segment {
+			return i
+		}
+	}
+	return -1
+}
+
+// insert returns a copy of n with the route added.
+func (n *node) insert(tokens []routeToken, r *route) (*node, error) {
+
+	c := *n
+	if len(tokens) == 0 {
+		for _, existing := range n.routes {
+			if existing.sameConditions(r) {
+				return nil, &RouteConflictError{Route: *r.endpoint, Existing: *existing.endpoint}
+			}
+		}
+		i := slices.IndexFunc(n.routes, r.before)
+		if i < 0 {
+			i = len(n.routes)
+		}
+		c.routes = slices.Insert(slices.Clone(n.routes), i, r)
+		return &c, nil
+	}
+
+	token, rest := tokens[0], tokens[1:]
+	if token.kind != staticNode {
+		child := n.param
+		if token.kind == catchAllNode {
+			child = n.catchAll
+		}
+		if child == nil {
+			child = &node{kind: token.kind}
+		}
+		child, err := child.insert(rest, r)
+		if err != nil {
+			return nil, err
+		}
+		if token.kind == paramNode {
+			c.param = child
+		} else {
+			c.catchAll = child
+		}
+		return &c, nil
+	}
+
+	children := slices.Clone(n.children)
+	i := n.staticChild(token.path)
+	if i < 0 {
+		child, err := (&node{path: token.path}).insert(rest, r)
+		if err != nil {
+			return nil, err
+		}
+		children = append(children, child)
+	} else {
+		child := children[i]
+		common := commonSegments(token.path, child.path)
+		if common < len(child.path) {
+			// Split the child where the paths part
+			tail := *child
+			tail.path = child.path[common:]
+			child = &node{path: child.path[:common]}
+			child.setChildren([]*node{&tail})
+		}
+		if common < len(token.path) {
+			rest = append([]routeToken{{kind: staticNode, path: token.path[common:]}}, rest...)
+		}
+		child, err := child.insert(rest, r)
+		if err != nil {
+			return nil, err
+		}
+		children[i] = child
+	}
+	c.setChildren(children)
+	return &c, nil
+}
+
+// remove returns a copy of n without the routes of the endpoint, nil when
+// nothing is left.
+func (n *node) remove(tokens []routeToken, code string) *node {
+
+	c := *n
+	if len(tokens) == 0 {
+		c.routes = slices.DeleteFunc(slices.Clone(n.routes), func(r *route) bool {
+			return r.endpoint.Code == code
+		})
+		return c.compact()
+	}
+
+	token, rest := tokens[0], tokens[1:]
+	switch {
+	case token.kind == paramNode && n.param != nil:
+		c.param = n.param.remove(rest, code)
+	case token.kind == catchAllNode && n.catchAll != nil:
+		c.catchAll = n.catchAll.remove(rest, code)
+	case token.kind == staticNode:
+		i := n.staticChild(token.path)
+		if i < 0 {
+			return n
+		}
+		child := n.children[i]
+		if commonSegments(token.path, child.path) != len(child.path) {
+			return n
+		}
+		if len(child.path) < len(token.path) {
+			rest = append([]routeToken{{kind: staticNode, path: token.path[len(child.path):]}}, rest...)
+		}
+		children := slices.Clone(n.children)
+		if child = child.remove(rest, code); child != nil {
+			children[i] = child
+		} else {
+			children = slices.Delete(children, i, i+1)
+		}
+		c.setChildren(children)
+	default:
+		return n
+	}
+	return c.compact()
+}
+
+// compact drops a node left without routes and merges a static node into
+// its only child.
+func (n *node) compact() *node {
+	if len(n.routes) > 0 || n.param != nil || n.catchAll != nil {
+		return n
+	}
+	switch len(n.children) {
+	case 0:
+		return nil
+	case 1:
+		if n.kind == staticNode && n.path != "" {
+			child := *n.children[0]
+			child.path = n.path + child.path
+			return &child
+		}
+	}
+	return n
+}
+
+// match finds the route of path, what is left of the request path below n.
+// The values of the wildcards it goes through are appended to params unless
+// it is nil.
+func (n *node) match(path string, req *RouteRequest, params *Params) *route {
+
+	if path == "" {
+		return n.accepting(req, params)
+	}
+
+	if child := n.staticMatch(path); child != nil {
+		if r := child.match(path[len(child.path):], req, params); r != nil {
+			return r
+		}
+	}
+
+	if n.param != nil {
+		end := strings.IndexByte(path[1:], '/') + 1
+		if end == 0 {
+			end = len(path)
+		}
+		if value := path[1:end]; value != "" {
+			if params != nil {
+				*params = append(*params, Param{Value: value})
+			}
+			if r := n.param.match(path[end:], req, params); r != nil {
+				return r
+			}
+			if params != nil {
+				*params = (*params)[:len(*params)-1]
+			}
+		}
+	}
+
+	if n.catchAll != nil {
+		if params != nil {
+			*params = append(*params, Param{Value: path[1:]})
+		}
+		if r := n.catchAll.accepting(req, params); r != nil {
+			return r
+		}
+		if params != nil {
+			*params = (*params)[:len(*params)-1]
+		}
+	}
+	return nil
+}
+
+// accepting is the first route of n accepting the request. The wildcard
+// values at the end of params get their names from it.
+func (n *node) accepting(req *RouteRequest, params *Params) *route {
+	for _, r := range n.routes {
+		if !r.accepts(req) {
+			continue
+		}
+		if params != nil {
+			base := len(*params) - len(r.names)
+			for i, name := range r.names {
+				(*params)[base+i].Key = name
+			}
+		}
+		return r
+	}
+	return nil
+}
+
+// staticMatch is the static child path goes through, nil if none.
+func (n *node) staticMatch(path string) *node {
+	if n.bySegment != nil {
+		child := n.bySegment[firstSegment(path)]
+		if child == nil || !strings.HasPrefix(path, child.path) {
+			return nil
+		}
+		if rest := path[len(child.path):]; rest == "" || rest[0] == '/' {
+			return child
+		}
+		return nil
+	}
+
+	b := indexByte(path)
+	for i := 0; i < len(n.indices); i++ {
+		if n.indices[i] != b {
+			continue
+		}
+		child := n.children[i]
+		if !strings.HasPrefix(path, child.path) {
+			continue
+		}
+		if rest := path[len(child.path):]; rest == "" || rest[0] == '/' {
+			return child
+		}
+	}
+	return nil
+}
+
+// === Route table ===
+
+func (t *routeTable) clone() *routeTable {
+	return &routeTable{
+		trees:  maps.Clone(t.trees),
+		byCode: maps.Clone(t.byCode),
+	}
+}
+
+// add replaces the route the endpoint has with the same method and path, if
+// any.
+func (t *routeTable) add(e Endpoint) error {
+
+	e.Method = strings.ToUpper(e.Method)
+	e.Path = normalizePath(e.Path)
+	for _, existing := range t.byCode[e.Code] {
+		if sameEndpoint(existing, e) {
+			return nil // Already registered
+		}
+	}
+
+	tokens, names, err := parseTemplate(e.Path)
+	if err != nil {
+		return err
+	}
+	r, err := newRoute(&e, names)
+	if err != nil {
+		return err
+	}
+
+	kept := slices.DeleteFunc(slices.Clone(t.byCode[e.Code]), func(existing Endpoint) bool {
+		if existing.Method != e.Method || existing.Path != e.Path {
+			return false
+		}
+		t.removeRoute(existing)
+		return true
+	})
+
+	root := t.trees[e.Method]
+	if root == nil {
+		root = &node{}
+	}
+	if root, err = root.insert(tokens, r); err != nil {
+		return err
+	}
+	t.trees[e.Method] = root
+	t.byCode[e.Code] = append(kept, e)
+	return nil
+}
+
+// sameEndpoint compares endpoints normalized by add.
+func sameEndpoint(a, b Endpoint) bool {
+	return a.Code == b.Code && a.Method == b.Method && a.Path == b.Path &&
+		a.Host == b.Host && maps.Equal(a.Headers, b.Headers) && a.Priority == b.Priority
+}
+
+func (t *routeTable) removeRoute(e Endpoint) {
+	tokens, _, _ := parseTemplate(e.Path)
+	if root := t.trees[e.Method].remove(tokens, e.Code); root != nil {
+		t.trees[e.Method] = root
+	} else {
+		delete(t.trees, e.Method)
+	}
+}
+
+func (t *routeTable) drop(code string) {
+	for _, e := range t.byCode[code] {
+		t.removeRoute(e)
+	}
+	delete(t.byCode, code)
+}
+
+// store swaps the table in as the next version, with m.lock held.
+func (m *Matcher) store(table *routeTable) {
+	table.version = m.table.Load().version + 1
+	m.table.Store(table)
+}
+
+// Version changes whenever the routes do. Read it before the endpoints given
+// to Reconcile.
+func (m *Matcher) Version() uint64 {
+	return m.table.Load().version
+}
+
+// === Load (replace all) ===
+
+// Load replaces the routes. The endpoints that can't be added are skipped,
+// the error tells which.
+func (m *Matcher) Load(endpoints []Endpoint) error {
+	m.lock.Lock()
+	defer m.lock.Unlock()
+
+	table := newRouteTable()
+	var errs []error
+	for _, ep := range endpoints {
+		if err := table.add(ep); err != nil {
+			errs = append(errs, err)
+		}
+	}
+	m.store(table)
+	return errors.Join(errs...)
+}
+
+// === Reconcile ===
+
+// Reconcile compares the routes with the ones Load would make of the
+// endpoints and swaps those in if they differ. It returns the codes of the
+// endpoints missing, extra or changed, and the endpoints skipped as Load
+// does. version is the Version the endpoints were read at: when the routes
+// changed since, the endpoints may predate the change and it fails with
+// ErrStaleSnapshot, leaving the routes as they are.
+func (m *Matcher) Reconcile(version uint64, endpoints []Endpoint) ([]string, error) {
+	m.lock.Lock()
+	defer m.lock.Unlock()
+
+	if m.Version() != version {
+		return nil, ErrStaleSnapshot
+	}
+
+	table := newRouteTable()
+	var errs []error
+	for _, ep := range endpoints {
+		if err := table.add(ep); err != nil {
+			errs = append(errs, err)
+		}
+	}
+
+	current := m.table.Load()
+	var drift []string
+	for code, want := range table.byCode {
+		if !sameEndpoints(current.byCode[code], want) {
+			drift = append(drift, code)
+		}
+	}
+	for code := range current.byCode {
+		if _, ok := table.byCode[code]; !ok {
+			drift = append(drift, code)
+		}
+	}
+	if len(drift) > 0 {
+		slices.Sort(drift)
+		m.store(table)
+	}
+	return drift, errors.Join(errs...)
+}
+
+func sameEndpoints(a, b []Endpoint) bool {
+	if len(a) != len(b) {
+		return false
+	}
+	for _, e := range a {
+		if !slices.ContainsFunc(b, func(o Endpoint) bool { return sameEndpoint(e, o) }) {
+			return false
+		}
+	}
+	return true
+}
+
+// === Add (idempotent) ===
+
+// Add registers the endpoint, replacing the route it had with the same
+// method and path. It fails with ErrInvalidRoute or a RouteConflictError,
+// the routes are then left as they were.
+func (m *Matcher) Add(e Endpoint) error {
+	m.lock.Lock()
+	defer m.lock.Unlock()
+
+	table := m.table.Load().clone()
+	if err := table.add(e); err != nil {
+		return err
+	}
+	m.store(table)
+	return nil
+}
+
+// === Replace (update) ===
+
+// Replace swaps the routes of the endpoint, named previous before an update,
+// for the route of e in a single change. On error the routes are left as
+// they were.
+func (m *Matcher) Replace(previous string, e Endpoint) error {
+	m.lock.Lock()
+	defer m.lock.Unlock()
+
+	table := m.table.Load().clone()
+	table.drop(previous)
+	table.drop(e.Code)
+	if err := table.add(e); err != nil {
+		return err
+	}
+	m.store(table)
+	return nil
+}
+
+// === Drop (by code) ===
+
+func (m *Matcher) Drop(code string) {
+	m.lock.Lock()
+	defer m.lock.Unlock()
+
+	current := m.table.Load()
+	if _, ok := current.byCode[code]; !ok {
+		return
+	}
+	table := current.clone()
+	table.drop(code)
+	m.store(table)
+}
+
+// === Match ===
+
+func (m *Matcher) Match(req RouteRequest) (string, bool) {
+	r := m.lookup(&req, nil)
+	if r == nil {
+		return "", false
+	}
+	return r.endpoint.Code, true
+}
+
+// MatchParams is Match also returning the wildcards of the template with
+// what they matched, appended to params. It doesn't allocate when params
+// has room for them.
+func (m *Matcher) MatchParams(req RouteRequest, params Params) (string, Params, bool) {
+	start := len(params)
+	r := m.lookup(&req, &params)
+	if r == nil {
+		return "", params[:start], false
+	}
+	return r.endpoint.Code, params, true
+}
+
+func (m *Matcher) lookup(req *RouteRequest, params *Params) *route {
+	root := m.table.Load().trees[strings.ToUpper(req.Method)]
+	if root == nil {
+		return nil
+	}
+	return root.match(normalizePath(req.Path), req, params)
+}
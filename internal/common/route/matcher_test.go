@@ -0,0 +1,449 @@
+package route
+
+import (
+	"errors"
+	"fmt"
+	"testing"
+)
+
+func newTestMatcher(t testing.TB, endpoints ...Endpoint) *Matcher {
+	t.Helper()
+	m := NewMatcher()
+	if err := m.Load(endpoints); err != nil {
+		t.Fatalf("Load: %v", err)
+	}
+	return m
+}
+
+func header(values map[string]string) func(string) string {
+	return func(name string) string { return values[name] }
+}
+
+func TestMatch(t *testing.T) {
+
+	m := newTestMatcher(t,
+		Endpoint{Code: "list_users", Method: "GET", Path: "/users"},
+		Endpoint{Code: "me", Method: "GET", Path: "/users/me"},
+		Endpoint{Code: "get_user", Method: "GET", Path: "/users/:id"},
+		Endpoint{Code: "user_items", Method: "GET", Path: "/users/:id/items/:item"},
+		Endpoint{Code: "user_settings", Method: "GET", Path: "/users/me/settings"},
+		Endpoint{Code: "create_user", Method: "post", Path: "users"},
+		Endpoint{Code: "files", Method: "GET", Path: "/files/*path"},
+		Endpoint{Code: "file_meta", Method: "GET", Path: "/files/meta"},
+		Endpoint{Code: "literal", Method: "GET", Path: "/a:b/c*d"},
+	)
+
+	tests := []struct {
+		name   string
+		method string
+		path   string
+		code   string
+		params Params
+	}{
+		{"static", "GET", "/users", "list_users", Params{}},
+		{"static over param", "GET", "/users/me", "me", Params{}},
+		{"param", "GET", "/users/42", "get_user", Params{{"id", "42"}}},
+		{"static prefix of a param", "GET", "/users/meow", "get_user", Params{{"id", "meow"}}},
+		{"param falls back from static", "GET", "/users/me/items/7", "user_items", Params{{"id", "me"}, {"item", "7"}}},
+		{"static below static", "GET", "/users/me/settings", "user_settings", Params{}},
+		{"params", "GET", "/users/42/items/7", "user_items", Params{{"id", "42"}, {"item", "7"}}},
+		{"method is case insensitive", "POST", "/users", "create_user", Params{}},
+		{"path without a slash", "GET", "users/42", "get_user", Params{{"id", "42"}}},
+		{"catch-all", "GET", "/files/a/b/c.txt", "files", Params{{"path", "a/b/c.txt"}}},
+		{"static over catch-all", "GET", "/files/meta", "file_meta", Params{}},
+		{"catch-all below static", "GET", "/files/meta/x", "files", Params{{"path", "meta/x"}}},
+		{"literal wildcard characters", "GET", "/a:b/c*d", "literal", Params{}},
+		{"empty param", "GET", "/users//items/7", "", nil},
+		{"unknown method", "DELETE", "/users", "", nil},
+		{"unknown path", "GET", "/orders", "", nil},
+		{"partial segment", "GET", "/user", "", nil},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			req := RouteRequest{Method: tt.method, Path: tt.path}
+
+			code, found := m.Match(req)
+			if code != tt.code || found != (tt.code != "") {
+				t.Fatalf("Match(%s %s) = %q, %v, want %q", tt.method, tt.path, code, found, tt.code)
+			}
+
+			code, params, found := m.MatchParams(req, make(Params, 0, 4))
+			if code != tt.code || found != (tt.code != "") {
+				t.Fatalf("MatchParams(%s %s) = %q, %v, want %q", tt.method, tt.path, code, found, tt.code)
+			}
+			if tt.params != nil && fmt.Sprint(params) != fmt.Sprint(tt.params) {
+				t.Errorf("MatchParams(%s %s) params = %v, want %v", tt.method, tt.path, params, tt.params)
+			}
+			if tt.params == nil && len(params) != 0 {
+				t.Errorf("MatchParams(%s %s) params = %v, want none", tt.method, tt.path, params)
+			}
+		})
+	}
+}
+
+func TestMatchConditions(t *testing.T) {
+
+	m := newTestMatcher(t,
+		Endpoint{Code: "any", Method: "GET", Path: "/items"},
+		Endpoint{Code: "wildcard_host", Method: "GET", Path: "/items", Host: "*.example.com"},
+		Endpoint{Code: "exact_host", Method: "GET", Path: "/items", Host: "API.example.com"},
+		Endpoint{Code: "version", Method: "GET", Path: "/items", Headers: map[string]string{"accept-version": "2"}},
+		Endpoint{Code: "version_tenant", Method: "GET", Path: "/items", Headers: map[string]string{"Accept-Version": "2", "X-Tenant": "*"}},
+		Endpoint{Code: "any_low", Method: "GET", Path: "/items/:id"},
+		Endpoint{Code: "exact_high", Method: "GET", Path: "/items/:id", Host: "api.example.com", Priority: -1},
+		Endpoint{Code: "beta", Method: "GET", Path: "/items/:id", Headers: map[string]string{"X-Beta": "1"}, Priority: 10},
+	)
+
+	tests := []struct {
+		name    string
+		host    string
+		headers map[string]string
+		path    string
+		code    string
+	}{
+		{"no conditions", "other.com", nil, "/items", "any"},
+		{"exact host", "api.example.com", nil, "/items", "exact_host"},
+		{"host is case insensitive", "Api.Example.com", nil, "/items", "exact_host"},
+		{"host port is ignored", "api.example.com:8443", nil, "/items", "exact_host"},
+		{"wildcard host", "eu.example.com", nil, "/items", "wildcard_host"},
+		{"wildcard host needs a subdomain", "example.com", nil, "/items", "any"},
+		{"exact host over header", "api.example.com", map[string]string{"Accept-Version": "2"}, "/items", "exact_host"},
+		{"header", "other.com", map[string]string{"Accept-Version": "2"}, "/items", "version"},
+		{"header value must match", "other.com", map[string]string{"Accept-Version": "3"}, "/items", "any"},
+		{"more headers first", "other.com", map[string]string{"Accept-Version": "2", "X-Tenant": "acme"}, "/items", "version_tenant"},
+		{"priority over host", "api.example.com", nil, "/items/1", "any_low"},
+		{"priority first", "api.example.com", map[string]string{"X-Beta": "1"}, "/items/1", "beta"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			code, _ := m.Match(RouteRequest{Method: "GET", Host: tt.host, Path: tt.path, Header: header(tt.headers)})
+			if code != tt.code {
+				t.Errorf("Match(%s on %s with %v) = %q, want %q", tt.path, tt.host, tt.headers, code, tt.code)
+			}
+		})
+	}
+}
+
+func TestAddConflicts(t *testing.T) {
+
+	tests := []struct {
+		name     string
+		existing Endpoint
+		added    Endpoint
+		conflict bool
+	}{
+		{
+			"wildcard names differ",
+			Endpoint{Code: "by_id", Method: "GET", Path: "/users/:id"},
+			Endpoint{Code: "by_name", Method: "GET", Path: "/users/:name"},
+			true,
+		},
+		{
+			"catch-all names differ",
+			Endpoint{Code: "files", Method: "GET", Path: "/files/*path"},
+			Endpoint{Code: "blobs", Method: "GET", Path: "/files/*rest"},
+			true,
+		},
+		{
+			"same host",
+			Endpoint{Code: "a", Method: "GET", Path: "/items", Host: "api.example.com"},
+			Endpoint{Code: "b", Method: "GET", Path: "/items", Host: "API.example.com"},
+			true,
+		},
+		{
+			"same headers",
+			Endpoint{Code: "a", Method: "GET", Path: "/items", Headers: map[string]string{"X-Beta": "1"}},
+			Endpoint{Code: "b", Method: "GET", Path: "/items", Headers: map[string]string{"x-beta": "1"}},
+			true,
+		},
+		{
+			"other method",
+			Endpoint{Code: "a", Method: "GET", Path: "/users/:id"},
+			Endpoint{Code: "b", Method: "PUT", Path: "/users/:name"},
+			false,
+		},
+		{
+			"other host",
+			Endpoint{Code: "a", Method: "GET", Path: "/users/:id"},
+			Endpoint{Code: "b", Method: "GET", Path: "/users/:name", Host: "api.example.com"},
+			false,
+		},
+		{
+			"other header value",
+			Endpoint{Code: "a", Method: "GET", Path: "/items", Headers: map[string]string{"Accept-Version": "1"}},
+			Endpoint{Code: "b", Method: "GET", Path: "/items", Headers: map[string]string{"Accept-Version": "2"}},
+			false,
+		},
+		{
+			"param and catch-all",
+			Endpoint{Code: "a", Method: "GET", Path: "/files/:name"},
+			Endpoint{Code: "b", Method: "GET", Path: "/files/*path"},
+			false,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			m := newTestMatcher(t, tt.existing)
+
+			err := m.Add(tt.added)
+			if !tt.conflict {
+				if err != nil {
+					t.Fatalf("Add: %v", err)
+				}
+				return
+			}
+
+			var conflict *RouteConflictError
+			if !errors.As(err, &conflict) || !errors.Is(err, ErrRouteConflict) {
+				t.Fatalf("Add: got %v, want a RouteConflictError", err)
+			}
+			if conflict.Existing.Code != tt.existing.Code || conflict.Route.Code != tt.added.Code {
+				t.Errorf("conflict of %s with %s, want %s with %s",
+					conflict.Route.Code, conflict.Existing.Code, tt.added.Code, tt.existing.Code)
+			}
+			if code, _ := m.Match(RouteRequest{Method: tt.existing.Method, Host: tt.existing.Host, Path: tt.existing.Path}); code == tt.added.Code {
+				t.Errorf("the conflicting endpoint was added")
+			}
+		})
+	}
+}
+
+func TestAddInvalid(t *testing.T) {
+
+	tests := []struct {
+		name     string
+		endpoint Endpoint
+	}{
+		{"unnamed param", Endpoint{Code: "a", Method: "GET", Path: "/users/:"}},
+		{"unnamed catch-all", Endpoint{Code: "a", Method: "GET", Path: "/files/*"}},
+		{"catch-all not last", Endpoint{Code: "a", Method: "GET", Path: "/files/*path/meta"}},
+		{"wildcard used twice", Endpoint{Code: "a", Method: "GET", Path: "/users/:id/friends/:id"}},
+		{"host with a port", Endpoint{Code: "a", Method: "GET", Path: "/items", Host: "api.example.com:443"}},
+		{"bare wildcard host", Endpoint{Code: "a", Method: "GET", Path: "/items", Host: "*."}},
+		{"header without a value", Endpoint{Code: "a", Method: "GET", Path: "/items", Headers: map[string]string{"X-Beta": ""}}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if err := NewMatcher().Add(tt.endpoint); !errors.Is(err, ErrInvalidRoute) {
+				t.Errorf("Add(%s %s) = %v, want ErrInvalidRoute", tt.endpoint.Method, tt.endpoint.Path, err)
+			}
+		})
+	}
+}
+
+func TestRemove(t *testing.T) {
+
+	endpoints := []Endpoint{
+		{Code: "list", Method: "GET", Path: "/v1/users/list"},
+		{Code: "banned", Method: "GET", Path: "/v1/users/banned"},
+		{Code: "get", Method: "GET", Path: "/v1/users/:id"},
+		{Code: "files", Method: "GET", Path: "/v1/files/*path"},
+		{Code: "create", Method: "POST", Path: "/v1/users"},
+	}
+
+	tests := []struct {
+		name   string
+		change func(m *Matcher) error
+		// Path of the GET static node below the root, "" to skip the check
+		root    string
+		matches map[string]string
+	}{
+		{
+			"drop merges the static nodes left",
+			func(m *Matcher) error {
+				m.Drop("banned")
+				m.Drop("get")
+				m.Drop("files")
+				return nil
+			},
+			"/v1/users/list",
+			map[string]string{"GET /v1/users/list": "list", "GET /v1/users/banned": "", "GET /v1/users/42": "", "POST /v1/users": "create"},
+		},
+		{
+			"drop of the last route of a method",
+			func(m *Matcher) error {
+				m.Drop("create")
+				return nil
+			},
+			"",
+			map[string]string{"POST /v1/users": "", "GET /v1/users/list": "list"},
+		},
+		{
+			"drop of an unknown code",
+			func(m *Matcher) error {
+				m.Drop("unknown")
+				return nil
+			},
+			"",
+			map[string]string{"GET /v1/users/list": "list", "GET /v1/files/a/b": "files"},
+		},
+		{
+			"replace moves the route",
+			func(m *Matcher) error {
+				return m.Replace("banned", Endpoint{Code: "blocked", Method: "GET", Path: "/v1/users/blocked"})
+			},
+			"",
+			map[string]string{"GET /v1/users/banned": "get", "GET /v1/users/blocked": "blocked"},
+		},
+		{
+			"replace keeping the code",
+			func(m *Matcher) error {
+				return m.Replace("files", Endpoint{Code: "files", Method: "PUT", Path: "/v1/files/:name"})
+			},
+			"",
+			map[string]string{"GET /v1/files/a": "", "PUT /v1/files/a": "files", "GET /v1/users/list": "list"},
+		},
+		{
+			"failed replace leaves the routes",
+			func(m *Matcher) error {
+				if err := m.Replace("banned", Endpoint{Code: "banned", Method: "GET", Path: "/v1/users/list"}); err == nil {
+					return errors.New("Replace onto another route succeeded")
+				}
+				return nil
+			},
+			"",
+			map[string]string{"GET /v1/users/banned": "banned", "GET /v1/users/list": "list"},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			m := newTestMatcher(t, endpoints...)
+			before := m.table.Load()
+
+			if err := tt.change(m); err != nil {
+				t.Fatal(err)
+			}
+			if _, ok := before.byCode["banned"]; !ok || len(before.byCode) != len(endpoints) {
+				t.Fatalf("the change modified the previous snapshot")
+			}
+
+			for request, want := range tt.matches {
+				var method, path string
+				fmt.Sscan(request, &method, &path)
+				if code, _ := m.Match(RouteRequest{Method: method, Path: path}); code != want {
+					t.Errorf("Match(%s) = %q, want %q", request, code, want)
+				}
+			}
+
+			table := m.table.Load()
+			if tt.root != "" {
+				root := table.trees["GET"]
+				if len(root.children) != 1 || root.children[0].path != tt.root || len(root.children[0].children) != 0 {
+					t.Errorf("GET tree wasn't compacted to %s", tt.root)
+				}
+			}
+			for method, root := range table.trees {
+				if root == nil {
+					t.Errorf("%s has an empty tree", method)
+				}
+			}
+			if _, ok := table.trees["POST"]; !ok && tt.matches["POST /v1/users"] != "" {
+				t.Errorf("POST tree dropped")
+			}
+		})
+	}
+}
+
+func TestDropAll(t *testing.T) {
+	m := newTestMatcher(t,
+		Endpoint{Code: "a", Method: "GET", Path: "/a/:id"},
+		Endpoint{Code: "b", Method: "GET", Path: "/a/b/*rest"},
+	)
+	m.Drop("a")
+	m.Drop("b")
+
+	table := m.table.Load()
+	if len(table.trees) != 0 || len(table.byCode) != 0 {
+		t.Errorf("routes left after dropping every endpoint: %v", table.byCode)
+	}
+}
+
+func TestReconcile(t *testing.T) {
+	m := newTestMatcher(t,
+		Endpoint{Code: "a", Method: "GET", Path: "/a"},
+		Endpoint{Code: "b", Method: "GET", Path: "/b"},
+	)
+
+	drift, err := m.Reconcile(m.Version(), []Endpoint{
+		{Code: "a", Method: "get", Path: "a"},
+		{Code: "b", Method: "GET", Path: "/b/:id"},
+		{Code: "c", Method: "GET", Path: "/c"},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if fmt.Sprint(drift) != "[b c]" {
+		t.Errorf("drift = %v, want [b c]", drift)
+	}
+	if code, _ := m.Match(RouteRequest{Method: "GET", Path: "/b/1"}); code != "b" {
+		t.Errorf("the reconciled routes weren't swapped in")
+	}
+
+	if drift, _ := m.Reconcile(m.Version(), []Endpoint{{Code: "a", Method: "GET", Path: "/a"}}); fmt.Sprint(drift) != "[b c]" {
+		t.Errorf("drift = %v, want [b c]", drift)
+	}
+
+	version := m.Version()
+	if err := m.Add(Endpoint{Code: "d", Method: "GET", Path: "/d"}); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := m.Reconcile(version, nil); !errors.Is(err, ErrStaleSnapshot) {
+		t.Errorf("err = %v, want ErrStaleSnapshot", err)
+	}
+	if code, _ := m.Match(RouteRequest{Method: "GET", Path: "/d"}); code != "d" {
+		t.Errorf("a stale reconciliation swapped the routes")
+	}
+}
+
+// benchmarkEndpoints makes count endpoints spread over resources, half of
+// them static and half with two params, and a path for each.
+func benchmarkEndpoints(count int) ([]Endpoint, []RouteRequest) {
+	methods := []string{"GET", "POST", "PUT", "DELETE"}
+	endpoints := make([]Endpoint, 0, count)
+	requests := make([]RouteRequest, 0, count)
+	for i := 0; i < count; i++ {
+		resource := fmt.Sprintf("/api/v%d/resource%d", i%3+1, i/8)
+		ep := Endpoint{Code: fmt.Sprintf("endpoint_%d", i), Method: methods[i%len(methods)], Path: resource}
+		path := resource
+		if i%8 >= 4 {
+			ep.Path += "/:id/items/:item"
+			path += "/42/items/7"
+		}
+		endpoints = append(endpoints, ep)
+		requests = append(requests, RouteRequest{Method: ep.Method, Path: path})
+	}
+	return endpoints, requests
+}
+
+func BenchmarkMatch(b *testing.B) {
+	endpoints, requests := benchmarkEndpoints(1000)
+	m := newTestMatcher(b, endpoints...)
+
+	b.ReportAllocs()
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		if _, found := m.Match(requests[i%len(requests)]); !found {
+			b.Fatal("no match")
+		}
+	}
+}
+
+func BenchmarkMatchParams(b *testing.B) {
+	endpoints, requests := benchmarkEndpoints(1000)
+	m := newTestMatcher(b, endpoints...)
+	params := make(Params, 0, 4)
+
+	b.ReportAllocs()
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		var found bool
+		if _, params, found = m.MatchParams(requests[i%len(requests)], params[:0]); !found {
+			b.Fatal("no match")
+		}
+	}
+}
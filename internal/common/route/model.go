@@ -0,0 +1,56 @@
+package route
+
+import (
+	"sync"
+	"sync/atomic"
+)
+
+type Endpoint struct {
+	Code   string `json:"code" form:"code"`
+	Method string `json:"method" form:"method"`
+	Path   string `json:"path" form:"path"`
+	// Host the request must be sent to, *.example.com for any subdomain of
+	// example.com. Any host when empty
+	Host string `json:"host,omitempty" form:"host"`
+	// Headers the request must send, with the value they must have, "*" for
+	// any
+	Headers map[string]string `json:"headers,omitempty" form:"-"`
+	// Orders the endpoints of the same template, highest first
+	Priority int32 `json:"priority,omitempty" form:"priority"`
+}
+
+// RouteRequest is what the Matcher reads of a request to find its endpoint.
+type RouteRequest struct {
+	Method string
+	Host   string
+	Path   string
+	// Reads a header of the request, nil when there are none
+	Header func(string) string
+}
+
+// Matcher finds the endpoint of a request. Lookups read an immutable
+// snapshot of the routes and take no lock, changes copy the part of the
+// snapshot they touch and swap it in.
+type Matcher struct {
+	lock  sync.Mutex
+	table atomic.Pointer[routeTable]
+}
+
+// Param is a :name or *name segment of the template and the part of the path
+// it matched.
+type Param struct {
+	Key   string
+	Value string
+}
+
+type Params []Param
+
+// ByName is the value of the named parameter, empty if there is none.
+func (ps Params) ByName(name string) string {
+	for _, p := range ps {
+		if p.Key == name {
+			return p.Value
+		}
+	}
+	return ""
+}
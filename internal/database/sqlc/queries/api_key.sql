@@ -0,0 +1,42 @@
+-- name: CreateApiKey :one
+INSERT INTO api_key (
+    api_key_name, api_key_prefix, api_key_hash,
+    api_key_created_at, api_key_expires_at, organization_id
+)
+VALUES ($1, $2, $3, $4, $5, $6)
+RETURNING api_key_id;
+
+-- name: ListApiKeysByOrgId :many
+SELECT
+    api_key_id, api_key_name, api_key_prefix, api_key_created_at,
+    api_key_expires_at, api_key_revoked, api_key_last_used_at, organization_id,
+    COUNT(*) OVER() AS total_items
+FROM api_key
+WHERE organization_id = $1
+ORDER BY api_key_id DESC
+LIMIT $2 OFFSET $3;
+
+-- name: RevokeApiKey :one
+UPDATE api_key
+SET api_key_revoked = TRUE
+WHERE api_key_id = $1
+RETURNING api_key_hash, organization_id;
+
+-- name: GetApiKeyByHash :one
+SELECT
+  api_key.api_key_id AS id,
+  api_key.api_key_prefix AS prefix,
+  api_key.api_key_expires_at AS expires_at,
+  api_key.api_key_revoked AS revoked,
+  organization.organization_id,
+  organization.organization_name,
+  organization.organization_realm
+FROM api_key
+INNER JOIN organization ON api_key.organization_id = organization.organization_id
+WHERE api_key.api_key_hash = $1
+  AND organization.organization_active = TRUE;
+
+-- name: UpdateApiKeyLastUsed :exec
+UPDATE api_key
+SET api_key_last_used_at = $1
+WHERE api_key_id = $2;
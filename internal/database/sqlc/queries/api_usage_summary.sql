@@ -116,3 +116,11 @@ SELECT
 FROM usage_buckets u
 JOIN organization o ON u.organization_id = o.organization_id
 ORDER BY u.bucket_start ASC, u.organization_id;
+
+-- name: GetSubscriptionUsageSince :one
+SELECT
+  COALESCE(SUM(total_cost), 0)::double precision AS costs_used,
+  COALESCE(SUM(total_calls), 0)::INT AS counts_used
+FROM api_usage_summary
+WHERE subscription_id = $1
+  AND usage_start_date >= $2;
@@ -28,6 +28,14 @@ INNER JOIN permission_type ON permission_type.permission_code = api_endpoint.per
 WHERE api_endpoint.resource_type_id = $1
 ORDER BY api_endpoint_id DESC;
 
+-- name: ListApiEndpointRoutes :many
+SELECT endpoint_name, http_method, path_template, host_pattern, header_matchers, match_priority
+FROM api_endpoint
+WHERE upper(http_method) = ANY(sqlc.arg('methods')::text[]);
+
+-- name: LockApiEndpointRoutes :exec
+SELECT pg_advisory_xact_lock(hashtext('api_endpoint_routes'));
+
 -- name: RegisterApiEndpoint :one 
 INSERT INTO api_endpoint (
   endpoint_name,
@@ -36,9 +44,16 @@ INSERT INTO api_endpoint (
   path_template,
   resource_type_id,
   permission_code,
-  access_type
+  access_type,
+  usage_unit_source,
+  usage_unit_key,
+  upstream_id,
+  path_rewrite,
+  host_pattern,
+  header_matchers,
+  match_priority
 )
-VALUES ($1, $2, $3, $4, $5, $6, $7)
+VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9, $10, $11, $12, $13, $14)
 RETURNING api_endpoint_id;
 
 -- name: RegisterApiEndpoints :copyfrom
@@ -49,9 +64,16 @@ INSERT INTO api_endpoint (
   path_template,
   resource_type_id,
   permission_code,
-  access_type
+  access_type,
+  usage_unit_source,
+  usage_unit_key,
+  upstream_id,
+  path_rewrite,
+  host_pattern,
+  header_matchers,
+  match_priority
 )
-VALUES ($1, $2, $3, $4, $5, $6, $7);
+VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9, $10, $11, $12, $13, $14);
 
 -- name: DeleteApiEndpointById :exec
 DELETE FROM api_endpoint
@@ -66,7 +88,14 @@ SET
   path_template = $5,
   resource_type_id = $6,
   permission_code = $7,
-  access_type = $8
+  access_type = $8,
+  usage_unit_source = $9,
+  usage_unit_key = $10,
+  upstream_id = $11,
+  path_rewrite = $12,
+  host_pattern = $13,
+  header_matchers = $14,
+  match_priority = $15
 WHERE api_endpoint_id = $1;
 
 -- name: UpsertApiEndpointByName :one
@@ -77,9 +106,16 @@ INSERT INTO api_endpoint (
   path_template,
   resource_type_id,
   permission_code,
-  access_type
+  access_type,
+  usage_unit_source,
+  usage_unit_key,
+  upstream_id,
+  path_rewrite,
+  host_pattern,
+  header_matchers,
+  match_priority
 )
-VALUES ($1, $2, $3, $4, $5, $6, $7)
+VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9, $10, $11, $12, $13, $14)
 ON CONFLICT (endpoint_name) DO UPDATE
 SET
   endpoint_description = EXCLUDED.endpoint_description,
@@ -87,7 +123,14 @@ SET
   path_template = EXCLUDED.path_template,
   resource_type_id = EXCLUDED.resource_type_id,
   permission_code = EXCLUDED.permission_code,
-  access_type = EXCLUDED.access_type
+  access_type = EXCLUDED.access_type,
+  usage_unit_source = EXCLUDED.usage_unit_source,
+  usage_unit_key = EXCLUDED.usage_unit_key,
+  upstream_id = EXCLUDED.upstream_id,
+  path_rewrite = EXCLUDED.path_rewrite,
+  host_pattern = EXCLUDED.host_pattern,
+  header_matchers = EXCLUDED.header_matchers,
+  match_priority = EXCLUDED.match_priority
 RETURNING api_endpoint_id;
 
 -- name: GetEndpointByName :one
@@ -51,6 +51,7 @@ WHERE organization_id = $1;
 SELECT
   organization_id AS id,
   organization_name AS name,
-  organization_realm AS realm
+  organization_realm AS realm,
+  organization_config AS config
 FROM organization
 WHERE organization_realm = $1 AND organization_active = TRUE;
\ No newline at end of file
@@ -18,4 +18,9 @@ VALUES ($1, $2, $3);
 
 -- name: DeleteResourceTypeById :exec
 DELETE FROM resource_type
+WHERE resource_type_id = $1;
+
+-- name: SetResourceTypeUpstream :execrows
+UPDATE resource_type
+SET upstream_id = $2
 WHERE resource_type_id = $1;
\ No newline at end of file
@@ -0,0 +1,38 @@
+-- name: GetShadowDenialSummary :many
+WITH filtered_denials AS (
+  SELECT organization_id, api_endpoint_id, denial_reason,
+  SUM(total_denials) AS total_denials,
+  MIN(denial_start_date) AS first_denial_date,
+  MAX(denial_end_date) AS last_denial_date
+  FROM shadow_denial_summary
+  WHERE
+    (sqlc.narg('org_id')::int IS NULL OR organization_id = sqlc.narg('org_id')) AND
+    (sqlc.narg('endpoint_id')::int IS NULL OR api_endpoint_id = sqlc.narg('endpoint_id')) AND
+    (sqlc.narg('reason')::text IS NULL OR denial_reason = sqlc.narg('reason')) AND
+    (sqlc.narg('start_date')::int IS NULL OR denial_start_date >= sqlc.narg('start_date')) AND
+    (sqlc.narg('end_date')::int IS NULL OR denial_end_date <= sqlc.narg('end_date'))
+  GROUP BY organization_id, api_endpoint_id, denial_reason
+  ORDER BY organization_id, api_endpoint_id, denial_reason
+  LIMIT sqlc.arg('limit') OFFSET sqlc.arg('offset')
+)
+SELECT
+  f.organization_id,
+  org.organization_name,
+  f.api_endpoint_id,
+  ae.endpoint_name,
+  f.denial_reason,
+  f.total_denials,
+  f.first_denial_date::int AS first_denial_date,
+  f.last_denial_date::int AS last_denial_date
+FROM filtered_denials f
+JOIN api_endpoint ae ON f.api_endpoint_id = ae.api_endpoint_id
+JOIN organization org ON f.organization_id = org.organization_id
+ORDER BY f.total_denials DESC;
+
+-- name: CreateShadowDenialSummary :one
+INSERT INTO shadow_denial_summary (
+    denial_start_date, denial_end_date, denial_reason,
+    total_denials, api_endpoint_id, organization_id
+)
+VALUES ($1, $2, $3, $4, $5, $6)
+RETURNING shadow_denial_id;
@@ -75,12 +75,52 @@ SELECT
   organization_id,
   subscription_api_limit AS api_limit,
   subscription_expiry_date AS expiry_timestamp,
-  subscription_status AS active
+  subscription_status AS active,
+  subscription_start_date AS start_timestamp,
+  subscription_quota_reset_interval AS quota_reset_interval
 FROM subscription
 WHERE organization_id = $1
-  AND subscription_status = TRUE;
-  -- AND EXISTS (
-  --   SELECT 1 FROM tier_base_pricing tbp
-  --   WHERE tbp.subscription_tier_id = subscription.subscription_tier_id
-  --     AND tbp.api_endpoint_id = $2
-  -- );
\ No newline at end of file
+  AND subscription_status = TRUE
+  AND subscription_start_date <= EXTRACT(EPOCH FROM NOW())::INTEGER
+  AND EXISTS (
+    SELECT 1 FROM tier_base_pricing tbp
+    WHERE tbp.subscription_tier_id = subscription.subscription_tier_id
+      AND tbp.api_endpoint_id = $2
+  )
+ORDER BY
+  (subscription_expiry_date IS NULL OR subscription_expiry_date > EXTRACT(EPOCH FROM NOW())::INTEGER) DESC,
+  subscription_start_date DESC,
+  subscription_id DESC
+LIMIT 1;
+
+-- name: GetOverlappingSubscriptions :many
+SELECT
+  subscription_id,
+  subscription_name
+FROM subscription
+WHERE organization_id = sqlc.arg('organization_id')
+  AND subscription_status = TRUE
+  AND subscription_id <> sqlc.arg('exclude_subscription_id')
+  AND subscription_start_date < COALESCE(sqlc.narg('expiry_date')::INTEGER, 2147483647)
+  AND COALESCE(subscription_expiry_date, 2147483647) > sqlc.arg('start_date')::INTEGER
+  AND (
+    subscription_tier_id = sqlc.arg('subscription_tier_id')
+    OR EXISTS (
+      SELECT 1 FROM tier_base_pricing a
+      JOIN tier_base_pricing b ON a.api_endpoint_id = b.api_endpoint_id
+      WHERE a.subscription_tier_id = subscription.subscription_tier_id
+        AND b.subscription_tier_id = sqlc.arg('subscription_tier_id')
+    )
+  )
+ORDER BY subscription_id;
+
+-- name: LockOrganizationSubscriptions :exec
+SELECT pg_advisory_xact_lock(hashtext('subscription'), sqlc.arg('organization_id')::INTEGER);
+
+-- name: TiersShareEndpoint :one
+SELECT EXISTS (
+  SELECT 1 FROM tier_base_pricing a
+  JOIN tier_base_pricing b ON a.api_endpoint_id = b.api_endpoint_id
+  WHERE a.subscription_tier_id = $1
+    AND b.subscription_tier_id = $2
+) AS shared;
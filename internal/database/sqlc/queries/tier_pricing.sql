@@ -47,7 +47,8 @@ RETURNING subscription_tier_id;
 -- name: GetPricing :one
 SELECT
   COALESCE(cep.custom_cost_per_call, tbp.base_cost_per_call, 0)::double precision AS cost_per_call,
-  COALESCE(cep.cost_mode, tbp.cost_mode, 'fixed') AS cost_mode
+  COALESCE(cep.cost_mode, tbp.cost_mode, 'fixed') AS cost_mode,
+  COALESCE(NULLIF(cep.custom_rate_limit, 0), tbp.base_rate_limit, 0)::integer AS rate_limit
 FROM subscription
 JOIN tier_base_pricing tbp
   ON subscription.subscription_tier_id = tbp.subscription_tier_id
@@ -0,0 +1,51 @@
+-- name: CreateUpstream :one
+INSERT INTO upstream (
+    upstream_name, upstream_description, upstream_targets, upstream_load_balancing,
+    upstream_connect_timeout_ms, upstream_response_timeout_ms, upstream_max_retries,
+    upstream_retry_budget_percent, upstream_eject_after_failures, upstream_eject_seconds,
+    upstream_strip_credentials, upstream_strip_prefix, upstream_add_prefix,
+    upstream_read_timeout_ms
+)
+VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9, $10, $11, $12, $13, $14)
+RETURNING upstream_id;
+
+-- name: ListUpstreams :many
+SELECT * FROM upstream
+ORDER BY upstream_id;
+
+-- name: GetUpstreamById :one
+SELECT * FROM upstream
+WHERE upstream_id = $1;
+
+-- name: UpdateUpstreamById :execrows
+UPDATE upstream
+SET
+  upstream_name = $2,
+  upstream_description = $3,
+  upstream_targets = $4,
+  upstream_load_balancing = $5,
+  upstream_connect_timeout_ms = $6,
+  upstream_response_timeout_ms = $7,
+  upstream_max_retries = $8,
+  upstream_retry_budget_percent = $9,
+  upstream_eject_after_failures = $10,
+  upstream_eject_seconds = $11,
+  upstream_strip_credentials = $12,
+  upstream_strip_prefix = $13,
+  upstream_add_prefix = $14,
+  upstream_read_timeout_ms = $15
+WHERE upstream_id = $1;
+
+-- name: DeleteUpstreamById :execrows
+DELETE FROM upstream
+WHERE upstream_id = $1;
+
+-- name: ListEndpointUpstreams :many
+SELECT endpoint_name, upstream_id::INTEGER AS upstream_id
+FROM api_endpoint
+WHERE upstream_id IS NOT NULL;
+
+-- name: ListResourceTypeUpstreams :many
+SELECT resource_type_id, upstream_id::INTEGER AS upstream_id
+FROM resource_type
+WHERE upstream_id IS NOT NULL;
\ No newline at end of file
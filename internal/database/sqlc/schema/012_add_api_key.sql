@@ -0,0 +1,18 @@
+-- +goose Up
+CREATE TABLE api_key (
+  api_key_id SERIAL PRIMARY KEY,
+  api_key_name VARCHAR(100) NOT NULL,
+  api_key_prefix VARCHAR(16) UNIQUE NOT NULL,
+  api_key_hash VARCHAR(64) UNIQUE NOT NULL,
+  api_key_created_at INTEGER NOT NULL,
+  api_key_expires_at INTEGER,
+  api_key_revoked BOOLEAN DEFAULT false NOT NULL,
+  api_key_last_used_at INTEGER,
+  organization_id INTEGER NOT NULL REFERENCES organization(organization_id) ON DELETE CASCADE
+);
+
+CREATE INDEX idx_api_key_organization_id ON api_key(organization_id);
+
+-- +goose Down
+DROP INDEX IF EXISTS idx_api_key_organization_id;
+DROP TABLE IF EXISTS api_key;
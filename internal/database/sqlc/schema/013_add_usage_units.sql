@@ -0,0 +1,7 @@
+-- +goose Up
+ALTER TABLE api_endpoint ADD COLUMN usage_unit_source VARCHAR(10) NOT NULL DEFAULT 'none' CHECK (usage_unit_source IN ('none', 'header', 'json'));
+ALTER TABLE api_endpoint ADD COLUMN usage_unit_key TEXT;
+
+-- +goose Down
+ALTER TABLE api_endpoint DROP COLUMN IF EXISTS usage_unit_key;
+ALTER TABLE api_endpoint DROP COLUMN IF EXISTS usage_unit_source;
\ No newline at end of file
@@ -0,0 +1,17 @@
+-- +goose Up
+CREATE TABLE upstream (
+  upstream_id SERIAL PRIMARY KEY,
+  upstream_name VARCHAR(100) UNIQUE NOT NULL,
+  upstream_description TEXT,
+  upstream_targets TEXT[] NOT NULL,
+  upstream_load_balancing VARCHAR(20) NOT NULL DEFAULT 'round-robin' CHECK (upstream_load_balancing IN ('round-robin', 'least-connections'))
+);
+
+-- The endpoint upstream wins over the one of its resource type
+ALTER TABLE resource_type ADD COLUMN upstream_id INTEGER REFERENCES upstream(upstream_id) ON DELETE SET NULL;
+ALTER TABLE api_endpoint ADD COLUMN upstream_id INTEGER REFERENCES upstream(upstream_id) ON DELETE SET NULL;
+
+-- +goose Down
+ALTER TABLE api_endpoint DROP COLUMN IF EXISTS upstream_id;
+ALTER TABLE resource_type DROP COLUMN IF EXISTS upstream_id;
+DROP TABLE IF EXISTS upstream;
\ No newline at end of file
@@ -0,0 +1,20 @@
+-- +goose Up
+-- Resilience policy of the proxy towards the upstream, a target failing
+-- upstream_eject_after_failures times in a row is ejected for
+-- upstream_eject_seconds (0 never ejects)
+ALTER TABLE upstream
+  ADD COLUMN upstream_connect_timeout_ms INTEGER NOT NULL DEFAULT 5000 CHECK (upstream_connect_timeout_ms > 0),
+  ADD COLUMN upstream_response_timeout_ms INTEGER NOT NULL DEFAULT 30000 CHECK (upstream_response_timeout_ms > 0),
+  ADD COLUMN upstream_max_retries INTEGER NOT NULL DEFAULT 1 CHECK (upstream_max_retries >= 0),
+  ADD COLUMN upstream_retry_budget_percent INTEGER NOT NULL DEFAULT 20 CHECK (upstream_retry_budget_percent BETWEEN 0 AND 100),
+  ADD COLUMN upstream_eject_after_failures INTEGER NOT NULL DEFAULT 5 CHECK (upstream_eject_after_failures >= 0),
+  ADD COLUMN upstream_eject_seconds INTEGER NOT NULL DEFAULT 30 CHECK (upstream_eject_seconds > 0);
+
+-- +goose Down
+ALTER TABLE upstream
+  DROP COLUMN IF EXISTS upstream_connect_timeout_ms,
+  DROP COLUMN IF EXISTS upstream_response_timeout_ms,
+  DROP COLUMN IF EXISTS upstream_max_retries,
+  DROP COLUMN IF EXISTS upstream_retry_budget_percent,
+  DROP COLUMN IF EXISTS upstream_eject_after_failures,
+  DROP COLUMN IF EXISTS upstream_eject_seconds;
\ No newline at end of file
@@ -0,0 +1,10 @@
+-- +goose Up
+-- Streamed responses can be metered by the messages (SSE events, WebSocket
+-- messages) or the bytes they carry
+ALTER TABLE api_endpoint DROP CONSTRAINT IF EXISTS api_endpoint_usage_unit_source_check;
+ALTER TABLE api_endpoint ADD CONSTRAINT api_endpoint_usage_unit_source_check CHECK (usage_unit_source IN ('none', 'header', 'json', 'messages', 'bytes'));
+
+-- +goose Down
+UPDATE api_endpoint SET usage_unit_source = 'none' WHERE usage_unit_source IN ('messages', 'bytes');
+ALTER TABLE api_endpoint DROP CONSTRAINT IF EXISTS api_endpoint_usage_unit_source_check;
+ALTER TABLE api_endpoint ADD CONSTRAINT api_endpoint_usage_unit_source_check CHECK (usage_unit_source IN ('none', 'header', 'json'));
\ No newline at end of file
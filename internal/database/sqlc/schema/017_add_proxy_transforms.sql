@@ -0,0 +1,21 @@
+-- +goose Up
+-- Request transforms of the proxy. An upstream strips the caller's
+-- credentials unless told otherwise and can strip or add a path prefix, an
+-- endpoint can rewrite its path with a template reusing the parameters of
+-- its path_template
+ALTER TABLE upstream
+  ADD COLUMN upstream_strip_credentials BOOLEAN NOT NULL DEFAULT TRUE,
+  ADD COLUMN upstream_strip_prefix TEXT,
+  ADD COLUMN upstream_add_prefix TEXT;
+
+ALTER TABLE api_endpoint
+  ADD COLUMN path_rewrite TEXT;
+
+-- +goose Down
+ALTER TABLE api_endpoint
+  DROP COLUMN IF EXISTS path_rewrite;
+
+ALTER TABLE upstream
+  DROP COLUMN IF EXISTS upstream_strip_credentials,
+  DROP COLUMN IF EXISTS upstream_strip_prefix,
+  DROP COLUMN IF EXISTS upstream_add_prefix;
\ No newline at end of file
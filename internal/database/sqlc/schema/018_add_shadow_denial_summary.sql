@@ -0,0 +1,18 @@
+-- +goose Up
+-- Requests an organization in shadow mode would have been denied, counted by
+-- reason per flush interval. They are only there to be reviewed before the
+-- organization is switched to enforcement
+CREATE TABLE shadow_denial_summary (
+  shadow_denial_id SERIAL PRIMARY KEY,
+  denial_start_date INTEGER NOT NULL,
+  denial_end_date INTEGER NOT NULL,
+  denial_reason VARCHAR(50) NOT NULL,
+  total_denials INTEGER NOT NULL,
+  api_endpoint_id INTEGER NOT NULL REFERENCES api_endpoint(api_endpoint_id) ON DELETE CASCADE,
+  organization_id INTEGER NOT NULL REFERENCES organization(organization_id) ON DELETE CASCADE
+);
+
+CREATE INDEX idx_shadow_denial_summary_org_start ON shadow_denial_summary (organization_id, denial_start_date);
+
+-- +goose Down
+DROP TABLE IF EXISTS shadow_denial_summary;
\ No newline at end of file
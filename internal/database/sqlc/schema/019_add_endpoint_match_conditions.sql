@@ -0,0 +1,16 @@
+-- +goose Up
+-- Conditions an endpoint can add to its method and path, so endpoints with
+-- the same template can be told apart by host or by headers such as
+-- Accept-Version. header_matchers is a JSON object of header names to the
+-- value they must have, "*" for any. The priority orders the endpoints of
+-- the same template, highest first
+ALTER TABLE api_endpoint
+  ADD COLUMN host_pattern TEXT,
+  ADD COLUMN header_matchers TEXT,
+  ADD COLUMN match_priority INTEGER NOT NULL DEFAULT 0;
+
+-- +goose Down
+ALTER TABLE api_endpoint
+  DROP COLUMN IF EXISTS host_pattern,
+  DROP COLUMN IF EXISTS header_matchers,
+  DROP COLUMN IF EXISTS match_priority;
\ No newline at end of file
@@ -0,0 +1,9 @@
+-- +goose Up
+-- How long a response body may stall between two reads before the proxy
+-- gives up on the upstream, 0 waits forever (e.g. long-polled streams)
+ALTER TABLE upstream
+  ADD COLUMN upstream_read_timeout_ms INTEGER NOT NULL DEFAULT 60000 CHECK (upstream_read_timeout_ms >= 0);
+
+-- +goose Down
+ALTER TABLE upstream
+  DROP COLUMN IF EXISTS upstream_read_timeout_ms;
@@ -56,6 +56,32 @@ func (q *Queries) CreateApiUsageSummary(ctx context.Context, arg CreateApiUsageS
 	return usage_summary_id, err
 }
 
+const getSubscriptionUsageSince = `-- name: GetSubscriptionUsageSince :one
+SELECT
+  COALESCE(SUM(total_cost), 0)::double precision AS costs_used,
+  COALESCE(SUM(total_calls), 0)::INT AS counts_used
+FROM api_usage_summary
+WHERE subscription_id = $1
+  AND usage_start_date >= $2
+`
+
+type GetSubscriptionUsageSinceParams struct {
+	SubscriptionID int32 `json:"subscription_id"`
+	UsageStartDate int32 `json:"usage_start_date"`
+}
+
+type GetSubscriptionUsageSinceRow struct {
+	CostsUsed  float64 `json:"costs_used"`
+	CountsUsed int32   `json:"counts_used"`
+}
+
+func (q *Queries) GetSubscriptionUsageSince(ctx context.Context, arg GetSubscriptionUsageSinceParams) (GetSubscriptionUsageSinceRow, error) {
+	row := q.db.QueryRow(ctx, getSubscriptionUsageSince, arg.SubscriptionID, arg.UsageStartDate)
+	var i GetSubscriptionUsageSinceRow
+	err := row.Scan(&i.CostsUsed, &i.CountsUsed)
+	return i, err
+}
+
 const getTotalCallsGroupedByOrgAndTimeBucket = `-- name: GetTotalCallsGroupedByOrgAndTimeBucket :many
 WITH usage_buckets AS (
   SELECT
@@ -440,6 +440,13 @@ func (r iteratorForRegisterApiEndpoints) Values() ([]interface{}, error) {
 		r.rows[0].ResourceTypeID,
 		r.rows[0].PermissionCode,
 		r.rows[0].AccessType,
+		r.rows[0].UsageUnitSource,
+		r.rows[0].UsageUnitKey,
+		r.rows[0].UpstreamID,
+		r.rows[0].PathRewrite,
+		r.rows[0].HostPattern,
+		r.rows[0].HeaderMatchers,
+		r.rows[0].MatchPriority,
 	}, nil
 }
 
@@ -448,5 +455,5 @@ func (r iteratorForRegisterApiEndpoints) Err() error {
 }
 
 func (q *Queries) RegisterApiEndpoints(ctx context.Context, arg []RegisterApiEndpointsParams) (int64, error) {
-	return q.db.CopyFrom(ctx, []string{"api_endpoint"}, []string{"endpoint_name", "endpoint_description", "http_method", "path_template", "resource_type_id", "permission_code", "access_type"}, &iteratorForRegisterApiEndpoints{rows: arg})
+	return q.db.CopyFrom(ctx, []string{"api_endpoint"}, []string{"endpoint_name", "endpoint_description", "http_method", "path_template", "resource_type_id", "permission_code", "access_type", "usage_unit_source", "usage_unit_key", "upstream_id", "path_rewrite", "host_pattern", "header_matchers", "match_priority"}, &iteratorForRegisterApiEndpoints{rows: arg})
 }
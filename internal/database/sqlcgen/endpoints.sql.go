@@ -22,7 +22,7 @@ func (q *Queries) DeleteApiEndpointById(ctx context.Context, apiEndpointID int32
 }
 
 const getApiEndpointById = `-- name: GetApiEndpointById :one
-SELECT api_endpoint.api_endpoint_id, api_endpoint.endpoint_name, api_endpoint.endpoint_description, api_endpoint.http_method, api_endpoint.path_template, api_endpoint.resource_type_id, api_endpoint.permission_code, api_endpoint.access_type, resource_type.resource_type_name, permission_type.permission_code, permission_type.permission_name
+SELECT api_endpoint.api_endpoint_id, api_endpoint.endpoint_name, api_endpoint.endpoint_description, api_endpoint.http_method, api_endpoint.path_template, api_endpoint.resource_type_id, api_endpoint.permission_code, api_endpoint.access_type, api_endpoint.usage_unit_source, api_endpoint.usage_unit_key, api_endpoint.upstream_id, api_endpoint.path_rewrite, api_endpoint.host_pattern, api_endpoint.header_matchers, api_endpoint.match_priority, resource_type.resource_type_name, permission_type.permission_code, permission_type.permission_name
 FROM api_endpoint
 INNER JOIN resource_type ON resource_type.resource_type_id = api_endpoint.resource_type_id
 INNER JOIN permission_type ON permission_type.permission_code = api_endpoint.permission_code
@@ -38,6 +38,13 @@ type GetApiEndpointByIdRow struct {
 	ResourceTypeID      int32       `json:"resource_type_id"`
 	PermissionCode      string      `json:"permission_code"`
 	AccessType          string      `json:"access_type"`
+	UsageUnitSource     string      `json:"usage_unit_source"`
+	UsageUnitKey        pgtype.Text `json:"usage_unit_key"`
+	UpstreamID          pgtype.Int4 `json:"upstream_id"`
+	PathRewrite         pgtype.Text `json:"path_rewrite"`
+	HostPattern         pgtype.Text `json:"host_pattern"`
+	HeaderMatchers      pgtype.Text `json:"header_matchers"`
+	MatchPriority       int32       `json:"match_priority"`
 	ResourceTypeName    string      `json:"resource_type_name"`
 	PermissionCode_2    string      `json:"permission_code_2"`
 	PermissionName      string      `json:"permission_name"`
@@ -55,6 +62,13 @@ func (q *Queries) GetApiEndpointById(ctx context.Context, apiEndpointID int32) (
 		&i.ResourceTypeID,
 		&i.PermissionCode,
 		&i.AccessType,
+		&i.UsageUnitSource,
+		&i.UsageUnitKey,
+		&i.UpstreamID,
+		&i.PathRewrite,
+		&i.HostPattern,
+		&i.HeaderMatchers,
+		&i.MatchPriority,
 		&i.ResourceTypeName,
 		&i.PermissionCode_2,
 		&i.PermissionName,
@@ -63,7 +77,7 @@ func (q *Queries) GetApiEndpointById(ctx context.Context, apiEndpointID int32) (
 }
 
 const getApiEndpointByName = `-- name: GetApiEndpointByName :one
-SELECT api_endpoint.api_endpoint_id, api_endpoint.endpoint_name, api_endpoint.endpoint_description, api_endpoint.http_method, api_endpoint.path_template, api_endpoint.resource_type_id, api_endpoint.permission_code, api_endpoint.access_type, resource_type.resource_type_name, permission_type.permission_code, permission_type.permission_name
+SELECT api_endpoint.api_endpoint_id, api_endpoint.endpoint_name, api_endpoint.endpoint_description, api_endpoint.http_method, api_endpoint.path_template, api_endpoint.resource_type_id, api_endpoint.permission_code, api_endpoint.access_type, api_endpoint.usage_unit_source, api_endpoint.usage_unit_key, api_endpoint.upstream_id, api_endpoint.path_rewrite, api_endpoint.host_pattern, api_endpoint.header_matchers, api_endpoint.match_priority, resource_type.resource_type_name, permission_type.permission_code, permission_type.permission_name
 FROM api_endpoint
 INNER JOIN resource_type ON resource_type.resource_type_id = api_endpoint.resource_type_id
 INNER JOIN permission_type ON permission_type.permission_code = api_endpoint.permission_code
@@ -79,6 +93,13 @@ type GetApiEndpointByNameRow struct {
 	ResourceTypeID      int32       `json:"resource_type_id"`
 	PermissionCode      string      `json:"permission_code"`
 	AccessType          string      `json:"access_type"`
+	UsageUnitSource     string      `json:"usage_unit_source"`
+	UsageUnitKey        pgtype.Text `json:"usage_unit_key"`
+	UpstreamID          pgtype.Int4 `json:"upstream_id"`
+	PathRewrite         pgtype.Text `json:"path_rewrite"`
+	HostPattern         pgtype.Text `json:"host_pattern"`
+	HeaderMatchers      pgtype.Text `json:"header_matchers"`
+	MatchPriority       int32       `json:"match_priority"`
 	ResourceTypeName    string      `json:"resource_type_name"`
 	PermissionCode_2    string      `json:"permission_code_2"`
 	PermissionName      string      `json:"permission_name"`
@@ -96,6 +117,13 @@ func (q *Queries) GetApiEndpointByName(ctx context.Context, endpointName string)
 		&i.ResourceTypeID,
 		&i.PermissionCode,
 		&i.AccessType,
+		&i.UsageUnitSource,
+		&i.UsageUnitKey,
+		&i.UpstreamID,
+		&i.PathRewrite,
+		&i.HostPattern,
+		&i.HeaderMatchers,
+		&i.MatchPriority,
 		&i.ResourceTypeName,
 		&i.PermissionCode_2,
 		&i.PermissionName,
@@ -124,7 +152,7 @@ func (q *Queries) GetEndpointByName(ctx context.Context, endpointName string) (G
 }
 
 const listApiEndpoint = `-- name: ListApiEndpoint :many
-SELECT api_endpoint.api_endpoint_id, api_endpoint.endpoint_name, api_endpoint.endpoint_description, api_endpoint.http_method, api_endpoint.path_template, api_endpoint.resource_type_id, api_endpoint.permission_code, api_endpoint.access_type, resource_type.resource_type_name, permission_type.permission_code, permission_type.permission_name
+SELECT api_endpoint.api_endpoint_id, api_endpoint.endpoint_name, api_endpoint.endpoint_description, api_endpoint.http_method, api_endpoint.path_template, api_endpoint.resource_type_id, api_endpoint.permission_code, api_endpoint.access_type, api_endpoint.usage_unit_source, api_endpoint.usage_unit_key, api_endpoint.upstream_id, api_endpoint.path_rewrite, api_endpoint.host_pattern, api_endpoint.header_matchers, api_endpoint.match_priority, resource_type.resource_type_name, permission_type.permission_code, permission_type.permission_name
 FROM api_endpoint
 INNER JOIN resource_type ON resource_type.resource_type_id = api_endpoint.resource_type_id
 INNER JOIN permission_type ON permission_type.permission_code = api_endpoint.permission_code
@@ -146,6 +174,13 @@ type ListApiEndpointRow struct {
 	ResourceTypeID      int32       `json:"resource_type_id"`
 	PermissionCode      string      `json:"permission_code"`
 	AccessType          string      `json:"access_type"`
+	UsageUnitSource     string      `json:"usage_unit_source"`
+	UsageUnitKey        pgtype.Text `json:"usage_unit_key"`
+	UpstreamID          pgtype.Int4 `json:"upstream_id"`
+	PathRewrite         pgtype.Text `json:"path_rewrite"`
+	HostPattern         pgtype.Text `json:"host_pattern"`
+	HeaderMatchers      pgtype.Text `json:"header_matchers"`
+	MatchPriority       int32       `json:"match_priority"`
 	ResourceTypeName    string      `json:"resource_type_name"`
 	PermissionCode_2    string      `json:"permission_code_2"`
 	PermissionName      string      `json:"permission_name"`
@@ -169,6 +204,13 @@ func (q *Queries) ListApiEndpoint(ctx context.Context, arg ListApiEndpointParams
 			&i.ResourceTypeID,
 			&i.PermissionCode,
 			&i.AccessType,
+			&i.UsageUnitSource,
+			&i.UsageUnitKey,
+			&i.UpstreamID,
+			&i.PathRewrite,
+			&i.HostPattern,
+			&i.HeaderMatchers,
+			&i.MatchPriority,
 			&i.ResourceTypeName,
 			&i.PermissionCode_2,
 			&i.PermissionName,
@@ -183,8 +225,50 @@ func (q *Queries) ListApiEndpoint(ctx context.Context, arg ListApiEndpointParams
 	return items, nil
 }
 
+const listApiEndpointRoutes = `-- name: ListApiEndpointRoutes :many
+SELECT endpoint_name, http_method, path_template, host_pattern, header_matchers, match_priority
+FROM api_endpoint
+WHERE upper(http_method) = ANY($1::text[])
+`
+
+type ListApiEndpointRoutesRow struct {
+	EndpointName   string      `json:"endpoint_name"`
+	HttpMethod     string      `json:"http_method"`
+	PathTemplate   string      `json:"path_template"`
+	HostPattern    pgtype.Text `json:"host_pattern"`
+	HeaderMatchers pgtype.Text `json:"header_matchers"`
+	MatchPriority  int32       `json:"match_priority"`
+}
+
+func (q *Queries) ListApiEndpointRoutes(ctx context.Context, methods []string) ([]ListApiEndpointRoutesRow, error) {
+	rows, err := q.db.Query(ctx, listApiEndpointRoutes, methods)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	items := []ListApiEndpointRoutesRow{}
+	for rows.Next() {
+		var i ListApiEndpointRoutesRow
+		if err := rows.Scan(
+			&i.EndpointName,
+			&i.HttpMethod,
+			&i.PathTemplate,
+			&i.HostPattern,
+			&i.HeaderMatchers,
+			&i.MatchPriority,
+		); err != nil {
+			return nil, err
+		}
+		items = append(items, i)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	return items, nil
+}
+
 const listApiEndpointsByResourceType = `-- name: ListApiEndpointsByResourceType :many
-SELECT api_endpoint.api_endpoint_id, api_endpoint.endpoint_name, api_endpoint.endpoint_description, api_endpoint.http_method, api_endpoint.path_template, api_endpoint.resource_type_id, api_endpoint.permission_code, api_endpoint.access_type, resource_type.resource_type_name, permission_type.permission_code, permission_type.permission_name
+SELECT api_endpoint.api_endpoint_id, api_endpoint.endpoint_name, api_endpoint.endpoint_description, api_endpoint.http_method, api_endpoint.path_template, api_endpoint.resource_type_id, api_endpoint.permission_code, api_endpoint.access_type, api_endpoint.usage_unit_source, api_endpoint.usage_unit_key, api_endpoint.upstream_id, api_endpoint.path_rewrite, api_endpoint.host_pattern, api_endpoint.header_matchers, api_endpoint.match_priority, resource_type.resource_type_name, permission_type.permission_code, permission_type.permission_name
 FROM api_endpoint
 INNER JOIN resource_type ON resource_type.resource_type_id = api_endpoint.resource_type_id
 INNER JOIN permission_type ON permission_type.permission_code = api_endpoint.permission_code
@@ -201,6 +285,13 @@ type ListApiEndpointsByResourceTypeRow struct {
 	ResourceTypeID      int32       `json:"resource_type_id"`
 	PermissionCode      string      `json:"permission_code"`
 	AccessType          string      `json:"access_type"`
+	UsageUnitSource     string      `json:"usage_unit_source"`
+	UsageUnitKey        pgtype.Text `json:"usage_unit_key"`
+	UpstreamID          pgtype.Int4 `json:"upstream_id"`
+	PathRewrite         pgtype.Text `json:"path_rewrite"`
+	HostPattern         pgtype.Text `json:"host_pattern"`
+	HeaderMatchers      pgtype.Text `json:"header_matchers"`
+	MatchPriority       int32       `json:"match_priority"`
 	ResourceTypeName    string      `json:"resource_type_name"`
 	PermissionCode_2    string      `json:"permission_code_2"`
 	PermissionName      string      `json:"permission_name"`
@@ -224,6 +315,13 @@ func (q *Queries) ListApiEndpointsByResourceType(ctx context.Context, resourceTy
 			&i.ResourceTypeID,
 			&i.PermissionCode,
 			&i.AccessType,
+			&i.UsageUnitSource,
+			&i.UsageUnitKey,
+			&i.UpstreamID,
+			&i.PathRewrite,
+			&i.HostPattern,
+			&i.HeaderMatchers,
+			&i.MatchPriority,
 			&i.ResourceTypeName,
 			&i.PermissionCode_2,
 			&i.PermissionName,
@@ -238,6 +336,15 @@ func (q *Queries) ListApiEndpointsByResourceType(ctx context.Context, resourceTy
 	return items, nil
 }
 
+const lockApiEndpointRoutes = `-- name: LockApiEndpointRoutes :exec
+SELECT pg_advisory_xact_lock(hashtext('api_endpoint_routes'))
+`
+
+func (q *Queries) LockApiEndpointRoutes(ctx context.Context) error {
+	_, err := q.db.Exec(ctx, lockApiEndpointRoutes)
+	return err
+}
+
 const registerApiEndpoint = `-- name: RegisterApiEndpoint :one
 INSERT INTO api_endpoint (
   endpoint_name,
@@ -246,9 +353,16 @@ INSERT INTO api_endpoint (
   path_template,
   resource_type_id,
   permission_code,
-  access_type
+  access_type,
+  usage_unit_source,
+  usage_unit_key,
+  upstream_id,
+  path_rewrite,
+  host_pattern,
+  header_matchers,
+  match_priority
 )
-VALUES ($1, $2, $3, $4, $5, $6, $7)
+VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9, $10, $11, $12, $13, $14)
 RETURNING api_endpoint_id
 `
 
@@ -260,6 +374,13 @@ type RegisterApiEndpointParams struct {
 	ResourceTypeID      int32       `json:"resource_type_id"`
 	PermissionCode      string      `json:"permission_code"`
 	AccessType          string      `json:"access_type"`
+	UsageUnitSource     string      `json:"usage_unit_source"`
+	UsageUnitKey        pgtype.Text `json:"usage_unit_key"`
+	UpstreamID          pgtype.Int4 `json:"upstream_id"`
+	PathRewrite         pgtype.Text `json:"path_rewrite"`
+	HostPattern         pgtype.Text `json:"host_pattern"`
+	HeaderMatchers      pgtype.Text `json:"header_matchers"`
+	MatchPriority       int32       `json:"match_priority"`
 }
 
 func (q *Queries) RegisterApiEndpoint(ctx context.Context, arg RegisterApiEndpointParams) (int32, error) {
@@ -271,6 +392,13 @@ func (q *Queries) RegisterApiEndpoint(ctx context.Context, arg RegisterApiEndpoi
 		arg.ResourceTypeID,
 		arg.PermissionCode,
 		arg.AccessType,
+		arg.UsageUnitSource,
+		arg.UsageUnitKey,
+		arg.UpstreamID,
+		arg.PathRewrite,
+		arg.HostPattern,
+		arg.HeaderMatchers,
+		arg.MatchPriority,
 	)
 	var api_endpoint_id int32
 	err := row.Scan(&api_endpoint_id)
@@ -285,6 +413,13 @@ type RegisterApiEndpointsParams struct {
 	ResourceTypeID      int32       `json:"resource_type_id"`
 	PermissionCode      string      `json:"permission_code"`
 	AccessType          string      `json:"access_type"`
+	UsageUnitSource     string      `json:"usage_unit_source"`
+	UsageUnitKey        pgtype.Text `json:"usage_unit_key"`
+	UpstreamID          pgtype.Int4 `json:"upstream_id"`
+	PathRewrite         pgtype.Text `json:"path_rewrite"`
+	HostPattern         pgtype.Text `json:"host_pattern"`
+	HeaderMatchers      pgtype.Text `json:"header_matchers"`
+	MatchPriority       int32       `json:"match_priority"`
 }
 
 const updateApiEndpointById = `-- name: UpdateApiEndpointById :exec
@@ -296,7 +431,14 @@ SET
   path_template = $5,
   resource_type_id = $6,
   permission_code = $7,
-  access_type = $8
+  access_type = $8,
+  usage_unit_source = $9,
+  usage_unit_key = $10,
+  upstream_id = $11,
+  path_rewrite = $12,
+  host_pattern = $13,
+  header_matchers = $14,
+  match_priority = $15
 WHERE api_endpoint_id = $1
 `
 
@@ -309,6 +451,13 @@ type UpdateApiEndpointByIdParams struct {
 	ResourceTypeID      int32       `json:"resource_type_id"`
 	PermissionCode      string      `json:"permission_code"`
 	AccessType          string      `json:"access_type"`
+	UsageUnitSource     string      `json:"usage_unit_source"`
+	UsageUnitKey        pgtype.Text `json:"usage_unit_key"`
+	UpstreamID          pgtype.Int4 `json:"upstream_id"`
+	PathRewrite         pgtype.Text `json:"path_rewrite"`
+	HostPattern         pgtype.Text `json:"host_pattern"`
+	HeaderMatchers      pgtype.Text `json:"header_matchers"`
+	MatchPriority       int32       `json:"match_priority"`
 }
 
 func (q *Queries) UpdateApiEndpointById(ctx context.Context, arg UpdateApiEndpointByIdParams) error {
@@ -321,6 +470,13 @@ func (q *Queries) UpdateApiEndpointById(ctx context.Context, arg UpdateApiEndpoi
 		arg.ResourceTypeID,
 		arg.PermissionCode,
 		arg.AccessType,
+		arg.UsageUnitSource,
+		arg.UsageUnitKey,
+		arg.UpstreamID,
+		arg.PathRewrite,
+		arg.HostPattern,
+		arg.HeaderMatchers,
+		arg.MatchPriority,
 	)
 	return err
 }
@@ -333,9 +489,16 @@ INSERT INTO api_endpoint (
   path_template,
   resource_type_id,
   permission_code,
-  access_type
+  access_type,
+  usage_unit_source,
+  usage_unit_key,
+  upstream_id,
+  path_rewrite,
+  host_pattern,
+  header_matchers,
+  match_priority
 )
-VALUES ($1, $2, $3, $4, $5, $6, $7)
+VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9, $10, $11, $12, $13, $14)
 ON CONFLICT (endpoint_name) DO UPDATE
 SET
   endpoint_description = EXCLUDED.endpoint_description,
@@ -343,7 +506,14 @@ SET
   path_template = EXCLUDED.path_template,
   resource_type_id = EXCLUDED.resource_type_id,
   permission_code = EXCLUDED.permission_code,
-  access_type = EXCLUDED.access_type
+  access_type = EXCLUDED.access_type,
+  usage_unit_source = EXCLUDED.usage_unit_source,
+  usage_unit_key = EXCLUDED.usage_unit_key,
+  upstream_id = EXCLUDED.upstream_id,
+  path_rewrite = EXCLUDED.path_rewrite,
+  host_pattern = EXCLUDED.host_pattern,
+  header_matchers = EXCLUDED.header_matchers,
+  match_priority = EXCLUDED.match_priority
 RETURNING api_endpoint_id
 `
 
@@ -355,6 +525,13 @@ type UpsertApiEndpointByNameParams struct {
 	ResourceTypeID      int32       `json:"resource_type_id"`
 	PermissionCode      string      `json:"permission_code"`
 	AccessType          string      `json:"access_type"`
+	UsageUnitSource     string      `json:"usage_unit_source"`
+	UsageUnitKey        pgtype.Text `json:"usage_unit_key"`
+	UpstreamID          pgtype.Int4 `json:"upstream_id"`
+	PathRewrite         pgtype.Text `json:"path_rewrite"`
+	HostPattern         pgtype.Text `json:"host_pattern"`
+	HeaderMatchers      pgtype.Text `json:"header_matchers"`
+	MatchPriority       int32       `json:"match_priority"`
 }
 
 func (q *Queries) UpsertApiEndpointByName(ctx context.Context, arg UpsertApiEndpointByNameParams) (int32, error) {
@@ -366,6 +543,13 @@ func (q *Queries) UpsertApiEndpointByName(ctx context.Context, arg UpsertApiEndp
 		arg.ResourceTypeID,
 		arg.PermissionCode,
 		arg.AccessType,
+		arg.UsageUnitSource,
+		arg.UsageUnitKey,
+		arg.UpstreamID,
+		arg.PathRewrite,
+		arg.HostPattern,
+		arg.HeaderMatchers,
+		arg.MatchPriority,
 	)
 	var api_endpoint_id int32
 	err := row.Scan(&api_endpoint_id)
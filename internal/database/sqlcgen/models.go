@@ -17,6 +17,25 @@ type ApiEndpoint struct {
 	ResourceTypeID      int32       `json:"resource_type_id"`
 	PermissionCode      string      `json:"permission_code"`
 	AccessType          string      `json:"access_type"`
+	UsageUnitSource     string      `json:"usage_unit_source"`
+	UsageUnitKey        pgtype.Text `json:"usage_unit_key"`
+	UpstreamID          pgtype.Int4 `json:"upstream_id"`
+	PathRewrite         pgtype.Text `json:"path_rewrite"`
+	HostPattern         pgtype.Text `json:"host_pattern"`
+	HeaderMatchers      pgtype.Text `json:"header_matchers"`
+	MatchPriority       int32       `json:"match_priority"`
+}
+
+type ApiKey struct {
+	ApiKeyID         int32       `json:"api_key_id"`
+	ApiKeyName       string      `json:"api_key_name"`
+	ApiKeyPrefix     string      `json:"api_key_prefix"`
+	ApiKeyHash       string      `json:"api_key_hash"`
+	ApiKeyCreatedAt  int32       `json:"api_key_created_at"`
+	ApiKeyExpiresAt  pgtype.Int4 `json:"api_key_expires_at"`
+	ApiKeyRevoked    bool        `json:"api_key_revoked"`
+	ApiKeyLastUsedAt pgtype.Int4 `json:"api_key_last_used_at"`
+	OrganizationID   int32       `json:"organization_id"`
 }
 
 type ApiUsageSummary struct {
@@ -103,6 +122,17 @@ type ResourceType struct {
 	ResourceTypeCode        string      `json:"resource_type_code"`
 	ResourceTypeName        string      `json:"resource_type_name"`
 	ResourceTypeDescription pgtype.Text `json:"resource_type_description"`
+	UpstreamID              pgtype.Int4 `json:"upstream_id"`
+}
+
+type ShadowDenialSummary struct {
+	ShadowDenialID  int32  `json:"shadow_denial_id"`
+	DenialStartDate int32  `json:"denial_start_date"`
+	DenialEndDate   int32  `json:"denial_end_date"`
+	DenialReason    string `json:"denial_reason"`
+	TotalDenials    int32  `json:"total_denials"`
+	ApiEndpointID   int32  `json:"api_endpoint_id"`
+	OrganizationID  int32  `json:"organization_id"`
 }
 
 type Subscription struct {
@@ -141,6 +171,24 @@ type TierBasePricing struct {
 	CostMode           string      `json:"cost_mode"`
 }
 
+type Upstream struct {
+	UpstreamID                 int32       `json:"upstream_id"`
+	UpstreamName               string      `json:"upstream_name"`
+	UpstreamDescription        pgtype.Text `json:"upstream_description"`
+	UpstreamTargets            []string    `json:"upstream_targets"`
+	UpstreamLoadBalancing      string      `json:"upstream_load_balancing"`
+	UpstreamConnectTimeoutMs   int32       `json:"upstream_connect_timeout_ms"`
+	UpstreamResponseTimeoutMs  int32       `json:"upstream_response_timeout_ms"`
+	UpstreamMaxRetries         int32       `json:"upstream_max_retries"`
+	UpstreamRetryBudgetPercent int32       `json:"upstream_retry_budget_percent"`
+	UpstreamEjectAfterFailures int32       `json:"upstream_eject_after_failures"`
+	UpstreamEjectSeconds       int32       `json:"upstream_eject_seconds"`
+	UpstreamStripCredentials   bool        `json:"upstream_strip_credentials"`
+	UpstreamStripPrefix        pgtype.Text `json:"upstream_strip_prefix"`
+	UpstreamAddPrefix          pgtype.Text `json:"upstream_add_prefix"`
+	UpstreamReadTimeoutMs      int32       `json:"upstream_read_timeout_ms"`
+}
+
 type VSubscriptionQuotaUsage struct {
 	SubscriptionID                 int32       `json:"subscription_id"`
 	SubscriptionName               string      `json:"subscription_name"`
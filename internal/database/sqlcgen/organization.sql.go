@@ -81,21 +81,28 @@ const getOrganizationByName = `-- name: GetOrganizationByName :one
 SELECT
   organization_id AS id,
   organization_name AS name,
-  organization_realm AS realm
+  organization_realm AS realm,
+  organization_config AS config
 FROM organization
 WHERE organization_realm = $1 AND organization_active = TRUE
 `
 
 type GetOrganizationByNameRow struct {
-	ID    int32  `json:"id"`
-	Name  string `json:"name"`
-	Realm string `json:"realm"`
+	ID     int32       `json:"id"`
+	Name   string      `json:"name"`
+	Realm  string      `json:"realm"`
+	Config pgtype.Text `json:"config"`
 }
 
 func (q *Queries) GetOrganizationByName(ctx context.Context, organizationRealm string) (GetOrganizationByNameRow, error) {
 	row := q.db.QueryRow(ctx, getOrganizationByName, organizationRealm)
 	var i GetOrganizationByNameRow
-	err := row.Scan(&i.ID, &i.Name, &i.Realm)
+	err := row.Scan(
+		&i.ID,
+		&i.Name,
+		&i.Realm,
+		&i.Config,
+	)
 	return i, err
 }
 
@@ -49,7 +49,7 @@ func (q *Queries) DeleteResourceTypeById(ctx context.Context, resourceTypeID int
 }
 
 const listResourceType = `-- name: ListResourceType :many
-SELECT resource_type_id, resource_type_code, resource_type_name, resource_type_description FROM resource_type
+SELECT resource_type_id, resource_type_code, resource_type_name, resource_type_description, upstream_id FROM resource_type
 ORDER BY resource_type_name
 LIMIT $1 OFFSET $2
 `
@@ -73,6 +73,7 @@ func (q *Queries) ListResourceType(ctx context.Context, arg ListResourceTypePara
 			&i.ResourceTypeCode,
 			&i.ResourceTypeName,
 			&i.ResourceTypeDescription,
+			&i.UpstreamID,
 		); err != nil {
 			return nil, err
 		}
@@ -83,3 +84,22 @@ func (q *Queries) ListResourceType(ctx context.Context, arg ListResourceTypePara
 	}
 	return items, nil
 }
+
+const setResourceTypeUpstream = `-- name: SetResourceTypeUpstream :execrows
+UPDATE resource_type
+SET upstream_id = $2
+WHERE resource_type_id = $1
+`
+
+type SetResourceTypeUpstreamParams struct {
+	ResourceTypeID int32       `json:"resource_type_id"`
+	UpstreamID     pgtype.Int4 `json:"upstream_id"`
+}
+
+func (q *Queries) SetResourceTypeUpstream(ctx context.Context, arg SetResourceTypeUpstreamParams) (int64, error) {
+	result, err := q.db.Exec(ctx, setResourceTypeUpstream, arg.ResourceTypeID, arg.UpstreamID)
+	if err != nil {
+		return 0, err
+	}
+	return result.RowsAffected(), nil
+}
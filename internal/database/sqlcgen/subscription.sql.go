@@ -109,22 +109,42 @@ SELECT
   organization_id,
   subscription_api_limit AS api_limit,
   subscription_expiry_date AS expiry_timestamp,
-  subscription_status AS active
+  subscription_status AS active,
+  subscription_start_date AS start_timestamp,
+  subscription_quota_reset_interval AS quota_reset_interval
 FROM subscription
 WHERE organization_id = $1
   AND subscription_status = TRUE
+  AND subscription_start_date <= EXTRACT(EPOCH FROM NOW())::INTEGER
+  AND EXISTS (
+    SELECT 1 FROM tier_base_pricing tbp
+    WHERE tbp.subscription_tier_id = subscription.subscription_tier_id
+      AND tbp.api_endpoint_id = $2
+  )
+ORDER BY
+  (subscription_expiry_date IS NULL OR subscription_expiry_date > EXTRACT(EPOCH FROM NOW())::INTEGER) DESC,
+  subscription_start_date DESC,
+  subscription_id DESC
+LIMIT 1
 `
 
+type GetActiveSubscriptionParams struct {
+	OrganizationID int32 `json:"organization_id"`
+	ApiEndpointID  int32 `json:"api_endpoint_id"`
+}
+
 type GetActiveSubscriptionRow struct {
-	ID              int32       `json:"id"`
-	OrganizationID  int32       `json:"organization_id"`
-	ApiLimit        pgtype.Int4 `json:"api_limit"`
-	ExpiryTimestamp pgtype.Int4 `json:"expiry_timestamp"`
-	Active          pgtype.Bool `json:"active"`
+	ID                 int32       `json:"id"`
+	OrganizationID     int32       `json:"organization_id"`
+	ApiLimit           pgtype.Int4 `json:"api_limit"`
+	ExpiryTimestamp    pgtype.Int4 `json:"expiry_timestamp"`
+	Active             pgtype.Bool `json:"active"`
+	StartTimestamp     int32       `json:"start_timestamp"`
+	QuotaResetInterval pgtype.Text `json:"quota_reset_interval"`
 }
 
-func (q *Queries) GetActiveSubscription(ctx context.Context, organizationID int32) (GetActiveSubscriptionRow, error) {
-	row := q.db.QueryRow(ctx, getActiveSubscription, organizationID)
+func (q *Queries) GetActiveSubscription(ctx context.Context, arg GetActiveSubscriptionParams) (GetActiveSubscriptionRow, error) {
+	row := q.db.QueryRow(ctx, getActiveSubscription, arg.OrganizationID, arg.ApiEndpointID)
 	var i GetActiveSubscriptionRow
 	err := row.Scan(
 		&i.ID,
@@ -132,10 +152,73 @@ func (q *Queries) GetActiveSubscription(ctx context.Context, organizationID int3
 		&i.ApiLimit,
 		&i.ExpiryTimestamp,
 		&i.Active,
+		&i.StartTimestamp,
+		&i.QuotaResetInterval,
 	)
 	return i, err
 }
 
+const getOverlappingSubscriptions = `-- name: GetOverlappingSubscriptions :many
+SELECT
+  subscription_id,
+  subscription_name
+FROM subscription
+WHERE organization_id = $1
+  AND subscription_status = TRUE
+  AND subscription_id <> $2
+  AND subscription_start_date < COALESCE($3::INTEGER, 2147483647)
+  AND COALESCE(subscription_expiry_date, 2147483647) > $4::INTEGER
+  AND (
+    subscription_tier_id = $5
+    OR EXISTS (
+      SELECT 1 FROM tier_base_pricing a
+      JOIN tier_base_pricing b ON a.api_endpoint_id = b.api_endpoint_id
+      WHERE a.subscription_tier_id = subscription.subscription_tier_id
+        AND b.subscription_tier_id = $5
+    )
+  )
+ORDER BY subscription_id
+`
+
+type GetOverlappingSubscriptionsParams struct {
+	OrganizationID        int32       `json:"organization_id"`
+	ExcludeSubscriptionID int32       `json:"exclude_subscription_id"`
+	ExpiryDate            pgtype.Int4 `json:"expiry_date"`
+	StartDate             int32       `json:"start_date"`
+	SubscriptionTierID    int32       `json:"subscription_tier_id"`
+}
+
+type GetOverlappingSubscriptionsRow struct {
+	SubscriptionID   int32  `json:"subscription_id"`
+	SubscriptionName string `json:"subscription_name"`
+}
+
+func (q *Queries) GetOverlappingSubscriptions(ctx context.Context, arg GetOverlappingSubscriptionsParams) ([]GetOverlappingSubscriptionsRow, error) {
+	rows, err := q.db.Query(ctx, getOverlappingSubscriptions,
+		arg.OrganizationID,
+		arg.ExcludeSubscriptionID,
+		arg.ExpiryDate,
+		arg.StartDate,
+		arg.SubscriptionTierID,
+	)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	items := []GetOverlappingSubscriptionsRow{}
+	for rows.Next() {
+		var i GetOverlappingSubscriptionsRow
+		if err := rows.Scan(&i.SubscriptionID, &i.SubscriptionName); err != nil {
+			return nil, err
+		}
+		items = append(items, i)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	return items, nil
+}
+
 const getSubscriptionById = `-- name: GetSubscriptionById :one
 SELECT 
     subscription.subscription_id, subscription.subscription_name, subscription.subscription_type, subscription.subscription_created_date, subscription.subscription_updated_date, subscription.subscription_start_date, subscription.subscription_api_limit, subscription.subscription_expiry_date, subscription.subscription_description, subscription.subscription_status, subscription.organization_id, subscription.subscription_tier_id, subscription.subscription_quota_reset_interval, subscription.subscription_billing_model, subscription.subscription_billing_interval, subscription_tier.tier_name  
@@ -334,6 +417,36 @@ func (q *Queries) ListSubscription(ctx context.Context, arg ListSubscriptionPara
 	return items, nil
 }
 
+const lockOrganizationSubscriptions = `-- name: LockOrganizationSubscriptions :exec
+SELECT pg_advisory_xact_lock(hashtext('subscription'), $1::INTEGER)
+`
+
+func (q *Queries) LockOrganizationSubscriptions(ctx context.Context, organizationID int32) error {
+	_, err := q.db.Exec(ctx, lockOrganizationSubscriptions, organizationID)
+	return err
+}
+
+const tiersShareEndpoint = `-- name: TiersShareEndpoint :one
+SELECT EXISTS (
+  SELECT 1 FROM tier_base_pricing a
+  JOIN tier_base_pricing b ON a.api_endpoint_id = b.api_endpoint_id
+  WHERE a.subscription_tier_id = $1
+    AND b.subscription_tier_id = $2
+) AS shared
+`
+
+type TiersShareEndpointParams struct {
+	SubscriptionTierID   int32 `json:"subscription_tier_id"`
+	SubscriptionTierID_2 int32 `json:"subscription_tier_id_2"`
+}
+
+func (q *Queries) TiersShareEndpoint(ctx context.Context, arg TiersShareEndpointParams) (bool, error) {
+	row := q.db.QueryRow(ctx, tiersShareEndpoint, arg.SubscriptionTierID, arg.SubscriptionTierID_2)
+	var shared bool
+	err := row.Scan(&shared)
+	return shared, err
+}
+
 const updateSubscription = `-- name: UpdateSubscription :execresult
 UPDATE subscription
 SET 
@@ -76,7 +76,8 @@ func (q *Queries) DeleteTierPricingByTierId(ctx context.Context, subscriptionTie
 const getPricing = `-- name: GetPricing :one
 SELECT
   COALESCE(cep.custom_cost_per_call, tbp.base_cost_per_call, 0)::double precision AS cost_per_call,
-  COALESCE(cep.cost_mode, tbp.cost_mode, 'fixed') AS cost_mode
+  COALESCE(cep.cost_mode, tbp.cost_mode, 'fixed') AS cost_mode,
+  COALESCE(NULLIF(cep.custom_rate_limit, 0), tbp.base_rate_limit, 0)::integer AS rate_limit
 FROM subscription
 JOIN tier_base_pricing tbp
   ON subscription.subscription_tier_id = tbp.subscription_tier_id
@@ -95,12 +96,13 @@ type GetPricingParams struct {
 type GetPricingRow struct {
 	CostPerCall float64 `json:"cost_per_call"`
 	CostMode    string  `json:"cost_mode"`
+	RateLimit   int32   `json:"rate_limit"`
 }
 
 func (q *Queries) GetPricing(ctx context.Context, arg GetPricingParams) (GetPricingRow, error) {
 	row := q.db.QueryRow(ctx, getPricing, arg.SubscriptionID, arg.ApiEndpointID)
 	var i GetPricingRow
-	err := row.Scan(&i.CostPerCall, &i.CostMode)
+	err := row.Scan(&i.CostPerCall, &i.CostMode, &i.RateLimit)
 	return i, err
 }
 
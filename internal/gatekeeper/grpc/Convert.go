@@ -0,0 +1,134 @@
+package grpc
+
+import (
+	"time"
+
+	gatekeeping "github.com/bignyap/go-admin/internal/gatekeeper/service/GateKeeping"
+	pb "github.com/bignyap/go-admin/pkg/gatekeeper/proto"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/protobuf/types/known/durationpb"
+	"google.golang.org/protobuf/types/known/timestamppb"
+)
+
+func toDecision(output *gatekeeping.ValidationRequestOutput, now time.Time) *pb.Decision {
+
+	org := output.Organization
+	endpoint := output.Endpoint
+	sub := output.Subscription
+
+	decision := &pb.Decision{
+		Allowed: true,
+		HoldId:  output.HoldID,
+		Organization: &pb.Organization{
+			Id:    org.ID,
+			Name:  org.Name,
+			Realm: org.Realm,
+		},
+		Endpoint: &pb.Endpoint{
+			Id:               endpoint.ApiEndpointID,
+			Code:             endpoint.EndpointName,
+			Method:           endpoint.HttpMethod,
+			PathTemplate:     endpoint.PathTemplate,
+			ResourceTypeId:   endpoint.ResourceTypeID,
+			ResourceTypeName: endpoint.ResourceTypeName,
+			PermissionCode:   endpoint.PermissionCode,
+			AccessType:       endpoint.AccessType,
+		},
+		Subscription: &pb.Subscription{
+			Id:                 sub.ID,
+			OrganizationId:     sub.OrganizationID,
+			ApiLimit:           sub.ApiLimit.Int32,
+			StartsAt:           timestamppb.New(time.Unix(int64(sub.StartTimestamp), 0)),
+			QuotaResetInterval: sub.QuotaResetInterval.String,
+			Active:             sub.Active.Bool,
+		},
+		Quota: &pb.Quota{},
+	}
+
+	if sub.ExpiryTimestamp.Valid && sub.ExpiryTimestamp.Int32 > 0 {
+		decision.Subscription.ExpiresAt = timestamppb.New(time.Unix(int64(sub.ExpiryTimestamp.Int32), 0))
+	}
+
+	if sub.ApiLimit.Int32 > 0 {
+		decision.Quota.Limited = true
+		decision.Quota.Limit = int64(sub.ApiLimit.Int32)
+		decision.Quota.Remaining = int64(output.Remaining)
+		if resetsAt := gatekeeping.QuotaPeriodEnd(sub.QuotaResetInterval.String, now); resetsAt > 0 {
+			decision.Quota.ResetsAt = timestamppb.New(time.Unix(resetsAt, 0))
+		}
+	}
+
+	if rl := output.RateLimit; rl != nil {
+		decision.RateLimit = &pb.RateLimit{
+			Scope:     rl.Scope,
+			Limit:     rl.Limit,
+			Remaining: rl.Remaining,
+			ResetIn:   durationpb.New(rl.ResetIn),
+		}
+	}
+
+	return decision
+}
+
+var denialReasons = map[gatekeeping.DenialCode]pb.DenialReason{
+	gatekeeping.DenialUnknownEndpoint:     pb.DenialReason_DENIAL_REASON_UNKNOWN_ENDPOINT,
+	gatekeeping.DenialOrgNotFound:         pb.DenialReason_DENIAL_REASON_ORG_NOT_FOUND,
+	gatekeeping.DenialInvalidCredentials:  pb.DenialReason_DENIAL_REASON_INVALID_CREDENTIALS,
+	gatekeeping.DenialPermissionDenied:    pb.DenialReason_DENIAL_REASON_PERMISSION_DENIED,
+	gatekeeping.DenialInsufficientScope:   pb.DenialReason_DENIAL_REASON_INSUFFICIENT_SCOPE,
+	gatekeeping.DenialNoSubscription:      pb.DenialReason_DENIAL_REASON_NO_SUBSCRIPTION,
+	gatekeeping.DenialSubscriptionExpired: pb.DenialReason_DENIAL_REASON_SUBSCRIPTION_EXPIRED,
+	gatekeeping.DenialQuotaExceeded:       pb.DenialReason_DENIAL_REASON_QUOTA_EXCEEDED,
+	gatekeeping.DenialRateLimited:         pb.DenialReason_DENIAL_REASON_RATE_LIMITED,
+}
+
+// toDenial turns an access decision error into a Decision. ok is false for
+// errors that are not access decisions (bad input, internal failures), those
+// are still reported as gRPC errors.
+func toDenial(err error) (*pb.Decision, bool) {
+
+	denial, ok := gatekeeping.DeniedDecision(err, time.Now())
+	if !ok {
+		return nil, false
+	}
+
+	decision := &pb.Decision{
+		Reason:  denialReasons[denial.Code],
+		Message: denial.Message,
+	}
+	if rl := denial.RateLimit; rl != nil {
+		decision.RateLimit = &pb.RateLimit{
+			Scope:     rl.Scope,
+			Limit:     rl.Limit,
+			Remaining: rl.Remaining,
+			ResetIn:   durationpb.New(rl.ResetIn),
+		}
+	}
+	if q := denial.Quota; q != nil {
+		decision.Quota = &pb.Quota{
+			Limited:   true,
+			Limit:     int64(q.Limit),
+			Remaining: int64(q.Remaining),
+		}
+		if q.ResetsAt > 0 {
+			decision.Quota.ResetsAt = timestamppb.New(time.Unix(q.ResetsAt, 0))
+		}
+	}
+	if denial.RetryAfter > 0 {
+		decision.RetryAfter = durationpb.New(denial.RetryAfter)
+	}
+	return decision, true
+}
+
+// denialStatusCode is the gRPC counterpart of DenialCode.HTTPStatus.
+func denialStatusCode(code gatekeeping.DenialCode) codes.Code {
+	switch code {
+	case gatekeeping.DenialUnknownEndpoint:
+		return codes.NotFound
+	case gatekeeping.DenialOrgNotFound, gatekeeping.DenialInvalidCredentials:
+		return codes.Unauthenticated
+	case gatekeeping.DenialQuotaExceeded, gatekeeping.DenialRateLimited:
+		return codes.ResourceExhausted
+	}
+	return codes.PermissionDenied
+}
@@ -0,0 +1,91 @@
+package grpc
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"strings"
+	"time"
+
+	gatekeeping "github.com/bignyap/go-admin/internal/gatekeeper/service/GateKeeping"
+	"github.com/bignyap/go-utilities/server"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/metadata"
+	"google.golang.org/grpc/status"
+)
+
+// DenialCodeMetadata carries the DenialCode of a denied call in the response
+// header, next to the rate limit and quota headers of the HTTP modes.
+const DenialCodeMetadata = "x-denial-code"
+
+// toStatusError converts service errors into gRPC status errors.
+// Denials carry their code, the rate limit and quota and the retry hint in
+// the response header.
+func toStatusError(ctx context.Context, err error) error {
+	if err == nil {
+		return nil
+	}
+	if _, ok := status.FromError(err); ok {
+		return err
+	}
+
+	if denial, ok := gatekeeping.DeniedDecision(err, time.Now()); ok {
+		pairs := []string{DenialCodeMetadata, string(denial.Code)}
+		for key, values := range denial.Headers() {
+			for _, value := range values {
+				pairs = append(pairs, strings.ToLower(key), value)
+			}
+		}
+		_ = grpc.SetHeader(ctx, metadata.Pairs(pairs...))
+		return status.Error(denialStatusCode(denial.Code), denial.Message)
+	}
+
+	var internalErr *server.InternalError
+	if errors.As(err, &internalErr) {
+		return status.Error(internalErrorCode(internalErr.Type), internalErr.Message)
+	}
+
+	var apiErr *server.ApiError
+	if errors.As(err, &apiErr) {
+		return status.Error(httpStatusCode(apiErr.Code), apiErr.Message)
+	}
+
+	return status.Error(codes.Internal, "internal server error")
+}
+
+// Access decisions are caught before, what is left is a failure of the
+// service or of the input.
+func internalErrorCode(errType server.ErrorType) codes.Code {
+	switch errType {
+	case server.ErrorBadRequest:
+		return codes.InvalidArgument
+	case server.ErrorUnauthorized:
+		return codes.PermissionDenied
+	case server.ErrorNotFound:
+		return codes.NotFound
+	case server.ErrorLargePayload:
+		return codes.ResourceExhausted
+	default:
+		return codes.Internal
+	}
+}
+
+func httpStatusCode(code int) codes.Code {
+	switch code {
+	case http.StatusBadRequest:
+		return codes.InvalidArgument
+	case http.StatusUnauthorized:
+		return codes.Unauthenticated
+	case http.StatusForbidden:
+		return codes.PermissionDenied
+	case http.StatusNotFound:
+		return codes.NotFound
+	case http.StatusConflict:
+		return codes.AlreadyExists
+	case http.StatusTooManyRequests:
+		return codes.ResourceExhausted
+	default:
+		return codes.Internal
+	}
+}
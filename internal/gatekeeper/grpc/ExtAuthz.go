@@ -0,0 +1,150 @@
+package grpc
+
+import (
+	"context"
+	"crypto/x509"
+	"encoding/json"
+	"errors"
+	"maps"
+	"slices"
+	"strings"
+	"time"
+
+	gatekeeping "github.com/bignyap/go-admin/internal/gatekeeper/service/GateKeeping"
+	corev3 "github.com/envoyproxy/go-control-plane/envoy/config/core/v3"
+	authv3 "github.com/envoyproxy/go-control-plane/envoy/service/auth/v3"
+	typev3 "github.com/envoyproxy/go-control-plane/envoy/type/v3"
+	rpcstatus "google.golang.org/genproto/googleapis/rpc/status"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+)
+
+// ExtAuthzOrganizationKey is the context extension read when neither the
+// credentials nor the organization extractors identify the organization,
+// set it per route with check_settings in Envoy.
+const ExtAuthzOrganizationKey = "organization_name"
+
+// ExtAuthzHandler implements envoy.service.auth.v3.Authorization so Envoy and
+// Istio can use GateKeeper as an ext_authz filter. Envoy never reports back
+// after the upstream answered, so usage is recorded as soon as a request is
+// allowed.
+type ExtAuthzHandler struct {
+	authv3.UnimplementedAuthorizationServer
+	Service *gatekeeping.GateKeepingService
+}
+
+func NewExtAuthzHandler(service *gatekeeping.GateKeepingService) *ExtAuthzHandler {
+	return &ExtAuthzHandler{Service: service}
+}
+
+// Check answers denials with an HTTP response for Envoy to send back, only
+// unexpected failures are gRPC errors so failure_mode_allow applies to them.
+func (h *ExtAuthzHandler) Check(ctx context.Context, req *authv3.CheckRequest) (*authv3.CheckResponse, error) {
+
+	httpReq := req.GetAttributes().GetRequest().GetHttp()
+	if httpReq == nil {
+		return nil, status.Error(codes.InvalidArgument, "attributes.request.http is required")
+	}
+	path, _, _ := strings.Cut(httpReq.GetPath(), "?")
+	if path == "" {
+		return nil, status.Error(codes.InvalidArgument, "path is required")
+	}
+
+	// Envoy sends the header names lower cased
+	headers := httpReq.GetHeaders()
+	get := func(name string) string { return strings.TrimSpace(headers[strings.ToLower(name)]) }
+	key, token := credentialsFrom(get)
+
+	identityReq := &gatekeeping.IdentityRequest{
+		Host:   httpReq.GetHost(),
+		Path:   path,
+		Header: get,
+	}
+	// URL encoded PEM of the downstream client certificate, when Envoy
+	// terminated mTLS and include_peer_certificate is set
+	if cert := gatekeeping.ParseForwardedCertificate(req.GetAttributes().GetSource().GetCertificate()); cert != nil {
+		identityReq.PeerCertificates = []*x509.Certificate{cert}
+	}
+
+	who, err := h.Service.ResolveCaller(ctx, key, token, identityReq)
+	if err != nil {
+		return h.deny(ctx, err)
+	}
+	input := &gatekeeping.ValidateRequestInput{
+		Method:           httpReq.GetMethod(),
+		Path:             identityReq.Path,
+		Host:             httpReq.GetHost(),
+		Header:           get,
+		OrganizationName: req.GetAttributes().GetContextExtensions()[ExtAuthzOrganizationKey],
+	}
+	if who != nil {
+		input.OrganizationName = who.Realm
+		input.Scopes = who.Scopes
+	}
+	if input.OrganizationName == "" {
+		return h.deny(ctx, gatekeeping.ErrInvalidApiKey)
+	}
+
+	output, err := h.Service.ValidateRequest(ctx, input)
+	if err != nil {
+		return h.deny(ctx, err)
+	}
+
+	if _, err := h.Service.RecordValidatedUsage(ctx, output); err != nil {
+		h.Service.Logger.Error("couldn't record ext_authz usage", err)
+	}
+
+	return &authv3.CheckResponse{
+		Status: &rpcstatus.Status{Code: int32(codes.OK)},
+		HttpResponse: &authv3.CheckResponse_OkResponse{
+			OkResponse: &authv3.OkHttpResponse{
+				ResponseHeadersToAdd: decisionHeaders(gatekeeping.AllowedDecision(output, time.Now())),
+			},
+		},
+	}, nil
+}
+
+func (h *ExtAuthzHandler) deny(ctx context.Context, err error) (*authv3.CheckResponse, error) {
+
+	denial, ok := gatekeeping.DeniedDecision(err, time.Now())
+	if !ok {
+		return nil, toStatusError(ctx, err)
+	}
+
+	headers := decisionHeaders(denial)
+	if errors.Is(err, gatekeeping.ErrInvalidToken) {
+		headers = append(headers, header("WWW-Authenticate", `Bearer error="invalid_token"`))
+	}
+	body, _ := json.Marshal(map[string]string{"error": denial.Message, "code": string(denial.Code)})
+	headers = append(headers, header("Content-Type", "application/json"))
+
+	return &authv3.CheckResponse{
+		Status: &rpcstatus.Status{Code: int32(denialStatusCode(denial.Code)), Message: denial.Message},
+		HttpResponse: &authv3.CheckResponse_DeniedResponse{
+			DeniedResponse: &authv3.DeniedHttpResponse{
+				Status:  &typev3.HttpStatus{Code: typev3.StatusCode(denial.HTTPStatus())},
+				Headers: headers,
+				Body:    string(body),
+			},
+		},
+	}, nil
+}
+
+// decisionHeaders are the rate limit and quota headers of the decision, in
+// sorted order so Envoy gets them the same way every time.
+func decisionHeaders(decision *gatekeeping.Decision) []*corev3.HeaderValueOption {
+	decisionHeader := decision.Headers()
+	keys := slices.Sorted(maps.Keys(decisionHeader))
+	headers := make([]*corev3.HeaderValueOption, 0, len(keys))
+	for _, key := range keys {
+		headers = append(headers, header(key, decisionHeader.Get(key)))
+	}
+	return headers
+}
+
+func header(key, value string) *corev3.HeaderValueOption {
+	return &corev3.HeaderValueOption{
+		Header:       &corev3.HeaderValue{Key: key, Value: value},
+		AppendAction: corev3.HeaderValueOption_OVERWRITE_IF_EXISTS_OR_ADD,
+	}
+}
@@ -2,10 +2,19 @@ package grpc
 
 import (
 	"context"
+	"crypto/x509"
+	"strings"
+	"time"
 
 	"github.com/bignyap/go-admin/internal/common"
-	pb "github.com/bignyap/go-admin/internal/gatekeeper/proto"
 	gatekeeping "github.com/bignyap/go-admin/internal/gatekeeper/service/GateKeeping"
+	pb "github.com/bignyap/go-admin/pkg/gatekeeper/proto"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/credentials"
+	"google.golang.org/grpc/metadata"
+	"google.golang.org/grpc/peer"
+	"google.golang.org/grpc/status"
+	"google.golang.org/protobuf/types/known/timestamppb"
 )
 
 type GatekeeperGRPCHandler struct {
@@ -18,45 +27,83 @@ func NewGatekeeperGRPCHandler(service *gatekeeping.GateKeepingService) *Gatekeep
 }
 
 func (g *GatekeeperGRPCHandler) RecordUsage(ctx context.Context, req *pb.RecordUsageRequest) (*pb.RecordUsageResponse, error) {
+	who, path, err := g.resolveCaller(ctx, req.OrganizationName, req.Host, req.Path)
+	if err != nil {
+		return nil, toStatusError(ctx, err)
+	}
+	if who.Realm == "" || path == "" {
+		return nil, status.Error(codes.InvalidArgument, "organization_name and path are required")
+	}
+
 	input := &gatekeeping.RecordUsageInput{
 		Method:           req.Method,
-		Path:             req.Path,
-		OrganizationName: req.OrganizationName,
+		Path:             path,
+		OrganizationName: who.Realm,
+		Host:             req.Host,
+		Header:           metadataHeader(ctx),
+		HoldID:           req.HoldId,
+		IdempotencyKey:   req.IdempotencyKey,
+		UsageUnits:       req.UsageUnits,
+	}
+	if input.UsageUnits != nil {
+		if err := gatekeeping.CheckUsageUnits(*input.UsageUnits); err != nil {
+			return nil, status.Error(codes.InvalidArgument, err.Error())
+		}
+	}
+	if len(input.IdempotencyKey) > gatekeeping.MaxIdempotencyKeyLength {
+		return nil, status.Errorf(
+			codes.InvalidArgument, "idempotency key longer than %d characters", gatekeeping.MaxIdempotencyKeyLength,
+		)
 	}
 
 	cost, err := g.Service.RecordUsage(ctx, input)
 	if err != nil {
-		return nil, err
+		return nil, toStatusError(ctx, err)
 	}
 
 	return &pb.RecordUsageResponse{Cost: cost}, nil
 }
 
+// ValidateRequest is kept for existing clients during the deprecation window.
+//
+// Deprecated: use Authorize.
 func (g *GatekeeperGRPCHandler) ValidateRequest(ctx context.Context, req *pb.ValidateRequestRequest) (*pb.ValidateRequestResponse, error) {
+	who, path, err := g.resolveCaller(ctx, req.OrganizationName, "", req.Path)
+	if err != nil {
+		return nil, toStatusError(ctx, err)
+	}
+	if who.Realm == "" || path == "" {
+		return nil, status.Error(codes.InvalidArgument, "organization_name and path are required")
+	}
+
 	input := &gatekeeping.ValidateRequestInput{
-		OrganizationName: req.OrganizationName,
+		OrganizationName: who.Realm,
 		Method:           req.Method,
-		Path:             req.Path,
+		Path:             path,
+		Header:           metadataHeader(ctx),
+		Scopes:           who.Scopes,
+		// The response has no hold_id to settle a hold with
+		SkipHold: true,
 	}
 
 	output, err := g.Service.ValidateRequest(ctx, input)
 	if err != nil {
-		return nil, err
+		return nil, toStatusError(ctx, err)
 	}
 
 	orgStruct, err := common.ConvertProtoStruct(output.Organization)
 	if err != nil {
-		return nil, err
+		return nil, status.Error(codes.Internal, err.Error())
 	}
 
 	endpointStruct, err := common.ConvertProtoStruct(output.Endpoint)
 	if err != nil {
-		return nil, err
+		return nil, status.Error(codes.Internal, err.Error())
 	}
 
 	subStruct, err := common.ConvertProtoStruct(output.Subscription)
 	if err != nil {
-		return nil, err
+		return nil, status.Error(codes.Internal, err.Error())
 	}
 
 	return &pb.ValidateRequestResponse{
@@ -66,3 +113,183 @@ func (g *GatekeeperGRPCHandler) ValidateRequest(ctx context.Context, req *pb.Val
 		Remaining:    int64(output.Remaining),
 	}, nil
 }
+
+// Authorize is the typed replacement of ValidateRequest. Denials come back as
+// a Decision with the reason rather than as a gRPC error.
+func (g *GatekeeperGRPCHandler) Authorize(ctx context.Context, req *pb.AuthorizeRequest) (*pb.Decision, error) {
+	input, denial, err := g.authorizeInput(ctx, req)
+	if err != nil || denial != nil {
+		return denial, err
+	}
+
+	output, err := g.Service.ValidateRequest(ctx, input)
+	if err != nil {
+		if decision, ok := toDenial(err); ok {
+			return decision, nil
+		}
+		return nil, toStatusError(ctx, err)
+	}
+
+	return toDecision(output, time.Now()), nil
+}
+
+// ValidateAndRecord is Authorize plus a reservation of the request cost, the
+// token is only set when the request was allowed.
+func (g *GatekeeperGRPCHandler) ValidateAndRecord(ctx context.Context, req *pb.AuthorizeRequest) (*pb.ValidateAndRecordResponse, error) {
+	input, denial, err := g.authorizeInput(ctx, req)
+	if err != nil {
+		return nil, err
+	}
+	if denial != nil {
+		return &pb.ValidateAndRecordResponse{Decision: denial}, nil
+	}
+
+	output, err := g.Service.ValidateAndRecord(ctx, input)
+	if err != nil {
+		if decision, ok := toDenial(err); ok {
+			return &pb.ValidateAndRecordResponse{Decision: decision}, nil
+		}
+		return nil, toStatusError(ctx, err)
+	}
+
+	return &pb.ValidateAndRecordResponse{
+		Decision:         toDecision(&output.ValidationRequestOutput, time.Now()),
+		ReservationToken: output.ReservationToken,
+		Cost:             output.Cost,
+		ExpiresAt:        timestamppb.New(time.Unix(output.ExpiresAt, 0)),
+	}, nil
+}
+
+func (g *GatekeeperGRPCHandler) CommitReservation(ctx context.Context, req *pb.ReservationRequest) (*pb.RecordUsageResponse, error) {
+	if req.ReservationToken == "" {
+		return nil, status.Error(codes.InvalidArgument, "reservation_token is required")
+	}
+
+	if req.UsageUnits != nil {
+		if err := gatekeeping.CheckUsageUnits(*req.UsageUnits); err != nil {
+			return nil, status.Error(codes.InvalidArgument, err.Error())
+		}
+	}
+
+	cost, err := g.Service.CommitReservation(ctx, &gatekeeping.ReservationInput{
+		ReservationToken: req.ReservationToken,
+		UsageUnits:       req.UsageUnits,
+	})
+	if err != nil {
+		return nil, toStatusError(ctx, err)
+	}
+
+	return &pb.RecordUsageResponse{Cost: cost}, nil
+}
+
+func (g *GatekeeperGRPCHandler) CancelReservation(ctx context.Context, req *pb.ReservationRequest) (*pb.CancelReservationResponse, error) {
+	if req.ReservationToken == "" {
+		return nil, status.Error(codes.InvalidArgument, "reservation_token is required")
+	}
+
+	err := g.Service.CancelReservation(ctx, &gatekeeping.ReservationInput{
+		ReservationToken: req.ReservationToken,
+	})
+	if err != nil {
+		return nil, toStatusError(ctx, err)
+	}
+
+	return &pb.CancelReservationResponse{}, nil
+}
+
+// authorizeInput resolves the caller of an AuthorizeRequest. Credential
+// failures are returned as a denial Decision, bad input as a status error.
+func (g *GatekeeperGRPCHandler) authorizeInput(ctx context.Context, req *pb.AuthorizeRequest) (*gatekeeping.ValidateRequestInput, *pb.Decision, error) {
+	if req.Path == "" {
+		return nil, nil, status.Error(codes.InvalidArgument, "path is required")
+	}
+
+	who, path, err := g.resolveCaller(ctx, req.OrganizationName, req.Host, req.Path)
+	if err != nil {
+		if decision, ok := toDenial(err); ok {
+			return nil, decision, nil
+		}
+		return nil, nil, toStatusError(ctx, err)
+	}
+	if who.Realm == "" {
+		return nil, nil, status.Error(codes.InvalidArgument, "organization_name or credentials are required")
+	}
+
+	return &gatekeeping.ValidateRequestInput{
+		OrganizationName: who.Realm,
+		Method:           req.Method,
+		Path:             path,
+		Host:             req.Host,
+		Header:           metadataHeader(ctx),
+		Scopes:           who.Scopes,
+	}, nil, nil
+}
+
+// resolveCaller mirrors the HTTP auth-middleware mode: credentials sent in
+// the x-api-key or authorization metadata, then the organization extractors,
+// win over organization_name. The returned path has the tenant segment
+// stripped, the caller is never nil.
+func (g *GatekeeperGRPCHandler) resolveCaller(ctx context.Context, orgName, host, path string) (*gatekeeping.Caller, string, error) {
+
+	get := metadataHeader(ctx)
+	key, token := credentialsFrom(get)
+
+	req := &gatekeeping.IdentityRequest{
+		Host:             host,
+		Path:             path,
+		Header:           get,
+		PeerCertificates: peerCertificates(ctx),
+	}
+	who, err := g.Service.ResolveCaller(ctx, key, token, req)
+	if err != nil {
+		return nil, "", err
+	}
+	if who == nil {
+		who = &gatekeeping.Caller{Realm: orgName}
+	}
+	return who, req.Path, nil
+}
+
+// peerCertificates returns the verified client certificates when the gRPC
+// server terminates TLS itself.
+func peerCertificates(ctx context.Context) []*x509.Certificate {
+	p, ok := peer.FromContext(ctx)
+	if !ok {
+		return nil
+	}
+	if tlsInfo, ok := p.AuthInfo.(credentials.TLSInfo); ok {
+		return tlsInfo.State.PeerCertificates
+	}
+	return nil
+}
+
+// credentialsFrom picks the API key or bearer token out of the x-api-key and
+// authorization values, get returns "" for a missing one.
+func credentialsFrom(get func(name string) string) (key string, token string) {
+
+	key = get("x-api-key")
+	scheme, value, _ := strings.Cut(get("authorization"), " ")
+	value = strings.TrimSpace(value)
+	switch {
+	case key != "":
+	case strings.EqualFold(scheme, "ApiKey"), strings.EqualFold(scheme, "Bearer") && common.IsApiKey(value):
+		key = value
+	case strings.EqualFold(scheme, "Bearer"):
+		token = value
+	}
+	return key, token
+}
+
+// metadataHeader reads the incoming metadata the way headers are read, the
+// header matchers of the endpoints look there.
+func metadataHeader(ctx context.Context) func(string) string {
+	md, _ := metadata.FromIncomingContext(ctx)
+	return func(name string) string { return firstMetadata(md, name) }
+}
+
+func firstMetadata(md metadata.MD, key string) string {
+	if values := md.Get(key); len(values) > 0 {
+		return strings.TrimSpace(values[0])
+	}
+	return ""
+}
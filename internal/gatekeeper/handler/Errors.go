@@ -0,0 +1,98 @@
+package gateKeeperHandler
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"net"
+	"net/http"
+	"strconv"
+	"time"
+
+	gatekeeping "github.com/bignyap/go-admin/internal/gatekeeper/service/GateKeeping"
+	"github.com/bignyap/go-utilities/logger/api"
+	"github.com/gin-gonic/gin"
+)
+
+// WriteError answers a denied request with the status of its denial code,
+// everything else goes through the regular response writer.
+func (h *GateKeeperHandler) WriteError(c *gin.Context, err error) {
+	if decision, ok := gatekeeping.DeniedDecision(err, time.Now()); ok {
+		writeDenial(c, decision, err)
+		c.JSON(decision.HTTPStatus(), denialBody(decision))
+		return
+	}
+	h.ResponseWriter.Error(c, err)
+}
+
+// AbortRequest is used by the middleware and proxy modes to reject a request
+// before it reaches the upstream.
+func (h *GateKeeperHandler) AbortRequest(c *gin.Context, err error) {
+	if decision, ok := gatekeeping.DeniedDecision(err, time.Now()); ok {
+		writeDenial(c, decision, err)
+		c.AbortWithStatusJSON(decision.HTTPStatus(), denialBody(decision))
+		return
+	}
+	h.ResponseWriter.Error(c, err)
+	c.Abort()
+}
+
+// DenialCodeHeader carries the denial code, for the proxies that drop the
+// body of the answer.
+const DenialCodeHeader = "X-Denial-Code"
+
+// writeDenial sets the headers of a denied request.
+func writeDenial(c *gin.Context, decision *gatekeeping.Decision, err error) {
+	setHeaders(c, decision.Headers())
+	c.Header(DenialCodeHeader, string(decision.Code))
+	if errors.Is(err, gatekeeping.ErrInvalidToken) {
+		c.Header("WWW-Authenticate", `Bearer error="invalid_token"`)
+	}
+}
+
+func denialBody(decision *gatekeeping.Decision) gin.H {
+	return gin.H{"error": decision.Message, "code": decision.Code}
+}
+
+func setHeaders(c *gin.Context, header http.Header) {
+	for key, values := range header {
+		for _, value := range values {
+			c.Header(key, value)
+		}
+	}
+}
+
+// UpstreamErrorHeader tells the client that GateKeeper, not the upstream,
+// answered a proxied request: circuit-open, timeout or unreachable.
+const UpstreamErrorHeader = "X-Gatekeeper-Upstream-Error"
+
+// ProxyErrorHandler answers proxied requests the upstream couldn't serve.
+// An open circuit gets 503 with Retry-After, a timeout 504 and any other
+// failure 502, all marked with UpstreamErrorHeader so they can't be taken for
+// a denial.
+func (h *GateKeeperHandler) ProxyErrorHandler(w http.ResponseWriter, r *http.Request, err error) {
+
+	status, reason := http.StatusBadGateway, "unreachable"
+	message := "upstream unreachable"
+
+	var circuitErr *gatekeeping.CircuitOpenError
+	var netErr net.Error
+	switch {
+	case errors.As(err, &circuitErr):
+		status, reason, message = http.StatusServiceUnavailable, "circuit-open", circuitErr.Error()
+		w.Header().Set("Retry-After", strconv.Itoa(max(circuitErr.RetryAfterSeconds(), 1)))
+	case errors.Is(err, context.DeadlineExceeded) || (errors.As(err, &netErr) && netErr.Timeout()):
+		status, reason, message = http.StatusGatewayTimeout, "timeout", "upstream timed out"
+	}
+
+	h.Logger.Warn("proxy request failed",
+		api.Field{Key: "path", Value: r.URL.Path},
+		api.Field{Key: "reason", Value: reason},
+		api.Field{Key: "error", Value: err.Error()},
+	)
+
+	w.Header().Set(UpstreamErrorHeader, reason)
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(status)
+	_ = json.NewEncoder(w).Encode(gin.H{"error": message})
+}
@@ -0,0 +1,64 @@
+package gateKeeperHandler
+
+import (
+	"errors"
+	"net/http"
+	"time"
+
+	gatekeeping "github.com/bignyap/go-admin/internal/gatekeeper/service/GateKeeping"
+	"github.com/bignyap/go-utilities/server"
+	"github.com/gin-gonic/gin"
+)
+
+// ForwardAuthHandler answers NGINX auth_request and Traefik ForwardAuth
+// subrequests. Only the status code and headers matter to the proxy, the
+// rate limit and quota headers can be copied onto the client response.
+// The proxy doesn't call back once the upstream answered, so usage is
+// recorded as soon as the request is allowed.
+func (h *GateKeeperHandler) ForwardAuthHandler(c *gin.Context) {
+
+	input, err := h.GateKeepingService.ForwardAuthValidator(c)
+	if err != nil {
+		h.abortForwardAuth(c, err)
+		return
+	}
+
+	output, err := h.GateKeepingService.ValidateRequest(c.Request.Context(), input)
+	if err != nil {
+		h.abortForwardAuth(c, err)
+		return
+	}
+
+	if _, err := h.GateKeepingService.RecordValidatedUsage(c.Request.Context(), output); err != nil {
+		h.Logger.Error("couldn't record forward-auth usage", err)
+	}
+
+	setDecisionHeaders(c, output)
+	c.Status(http.StatusOK)
+}
+
+func (h *GateKeeperHandler) abortForwardAuth(c *gin.Context, err error) {
+	var internalErr *server.InternalError
+	if errors.As(err, &internalErr) && internalErr.Type == server.ErrorBadRequest {
+		c.AbortWithStatusJSON(http.StatusBadRequest, gin.H{"error": internalErr.Message})
+		return
+	}
+	// NGINX auth_request turns anything but 2xx, 401 and 403 into a 500, its
+	// clients get the code from DenialCodeHeader instead
+	if decision, ok := gatekeeping.DeniedDecision(err, time.Now()); ok && c.GetHeader(gatekeeping.OriginalURIHeader) != "" {
+		status := decision.HTTPStatus()
+		if status != http.StatusUnauthorized {
+			status = http.StatusForbidden
+		}
+		writeDenial(c, decision, err)
+		c.AbortWithStatusJSON(status, denialBody(decision))
+		return
+	}
+	h.AbortRequest(c, err)
+}
+
+// setDecisionHeaders exposes the tightest rate limit and the subscription
+// quota of an allowed request.
+func setDecisionHeaders(c *gin.Context, output *gatekeeping.ValidationRequestOutput) {
+	setHeaders(c, gatekeeping.AllowedDecision(output, time.Now()).Headers())
+}
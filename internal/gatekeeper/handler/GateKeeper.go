@@ -2,6 +2,7 @@ package gateKeeperHandler
 
 import (
 	"github.com/bignyap/go-admin/internal/caching"
+	"github.com/bignyap/go-admin/internal/common/route"
 	"github.com/bignyap/go-admin/internal/database/sqlcgen"
 	gatekeeping "github.com/bignyap/go-admin/internal/gatekeeper/service/GateKeeping"
 	conuter "github.com/bignyap/go-utilities/counter"
@@ -17,7 +18,7 @@ type GateKeeperHandler struct {
 	Logger             api.Logger
 	Validator          *validator.Validate
 	Cache              *caching.CacheController
-	Matcher            *gatekeeping.Matcher
+	Matcher            *route.Matcher
 	CounterWorker      *conuter.CounterWorker
 }
 
@@ -28,8 +29,14 @@ func NewGateKeeperHandler(
 	conn *pgxpool.Pool,
 	validator *validator.Validate,
 	cacheContoller *caching.CacheController,
-	matcher *gatekeeping.Matcher,
+	matcher *route.Matcher,
 	conuter *conuter.CounterWorker,
+	rateLimiter *gatekeeping.RateLimiter,
+	reservations *gatekeeping.ReservationStore,
+	idempotency *gatekeeping.IdempotencyStore,
+	jwtValidator *gatekeeping.JWTValidator,
+	identity *gatekeeping.OrgIdentity,
+	enforcement string,
 	flushInterval int64,
 ) *GateKeeperHandler {
 
@@ -49,6 +56,12 @@ func NewGateKeeperHandler(
 			Cache:         cacheContoller,
 			Match:         matcher,
 			CounterWorker: conuter,
+			RateLimiter:   rateLimiter,
+			Reservations:  reservations,
+			Idempotency:   idempotency,
+			JWT:           jwtValidator,
+			Identity:      identity,
+			Enforcement:   enforcement,
 			FlushInterval: flushInterval,
 		},
 	}
@@ -0,0 +1,47 @@
+package gateKeeperHandler
+
+import (
+	"github.com/gin-gonic/gin"
+)
+
+func (h *GateKeeperHandler) ValidateAndRecordHandler(c *gin.Context) {
+	input, err := h.GateKeepingService.ValidateRequestHeader(c)
+	if err != nil {
+		h.WriteError(c, err)
+		return
+	}
+	output, err := h.GateKeepingService.ValidateAndRecord(c.Request.Context(), input)
+	if err != nil {
+		h.WriteError(c, err)
+		return
+	}
+	setDecisionHeaders(c, &output.ValidationRequestOutput)
+	h.ResponseWriter.Success(c, output)
+}
+
+func (h *GateKeeperHandler) CommitReservationHandler(c *gin.Context) {
+	input, err := h.GateKeepingService.ReservationValidator(c)
+	if err != nil {
+		h.ResponseWriter.BadRequest(c, err.Error())
+		return
+	}
+	cost, err := h.GateKeepingService.CommitReservation(c.Request.Context(), input)
+	if err != nil {
+		h.ResponseWriter.Error(c, err)
+		return
+	}
+	h.ResponseWriter.Success(c, cost)
+}
+
+func (h *GateKeeperHandler) CancelReservationHandler(c *gin.Context) {
+	input, err := h.GateKeepingService.ReservationValidator(c)
+	if err != nil {
+		h.ResponseWriter.BadRequest(c, err.Error())
+		return
+	}
+	if err := h.GateKeepingService.CancelReservation(c.Request.Context(), input); err != nil {
+		h.ResponseWriter.Error(c, err)
+		return
+	}
+	h.ResponseWriter.NoContent(c)
+}
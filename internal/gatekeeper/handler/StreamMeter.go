@@ -0,0 +1,189 @@
+package gateKeeperHandler
+
+import (
+	"bytes"
+	"net"
+	"sync/atomic"
+
+	gatekeeping "github.com/bignyap/go-admin/internal/gatekeeper/service/GateKeeping"
+)
+
+// sseScanner follows a text/event-stream response as it is written. It counts
+// the events carrying data and, when given a JSON path, keeps the units of the
+// last event holding it, e.g. the usage chunk closing a streamed completion.
+type sseScanner struct {
+	path   string
+	filter []byte
+
+	line     []byte
+	data     []byte
+	hasData  bool
+	overflow bool
+
+	events int64
+	units  float64
+	found  bool
+}
+
+func newSSEScanner(path string) *sseScanner {
+	s := &sseScanner{path: path}
+	if path != "" {
+		// Skip parsing the events that can't hold the path
+		first, _, _ := bytes.Cut([]byte(path), []byte("."))
+		s.filter = append(append([]byte(`"`), first...), '"')
+	}
+	return s
+}
+
+func (s *sseScanner) feed(b []byte) {
+	for len(b) > 0 {
+		i := bytes.IndexByte(b, '\n')
+		if i < 0 {
+			s.appendLine(b)
+			return
+		}
+		s.appendLine(b[:i])
+		s.endLine()
+		b = b[i+1:]
+	}
+}
+
+func (s *sseScanner) appendLine(b []byte) {
+	if len(s.line)+len(b) > maxUsageBodyBytes {
+		s.overflow = true
+		return
+	}
+	s.line = append(s.line, b...)
+}
+
+func (s *sseScanner) endLine() {
+	line := bytes.TrimSuffix(s.line, []byte("\r"))
+	overflow := s.overflow
+	s.line, s.overflow = s.line[:0], false
+
+	if len(line) == 0 && !overflow {
+		s.endEvent()
+		return
+	}
+	value, ok := bytes.CutPrefix(line, []byte("data:"))
+	if !ok {
+		return
+	}
+	s.hasData = true
+	if overflow || s.path == "" {
+		return
+	}
+	value = bytes.TrimPrefix(value, []byte(" "))
+	if len(s.data) > 0 {
+		s.data = append(s.data, '\n')
+	}
+	s.data = append(s.data, value...)
+}
+
+func (s *sseScanner) endEvent() {
+	if !s.hasData {
+		return
+	}
+	s.events++
+	if len(s.data) > 0 && bytes.Contains(s.data, s.filter) {
+		if units, found, err := gatekeeping.UsageUnitsFromJSON(s.data, s.path); err == nil && found {
+			s.units, s.found = units, true
+		}
+	}
+	s.data, s.hasData = s.data[:0], false
+}
+
+// meteredConn counts the WebSocket traffic of an upgraded connection, in both
+// directions.
+type meteredConn struct {
+	net.Conn
+	bytes    atomic.Int64
+	messages atomic.Int64
+	// One parser per direction, each only used by its copying goroutine
+	fromClient wsFrameParser
+	toClient   wsFrameParser
+}
+
+func (c *meteredConn) Read(b []byte) (int, error) {
+	n, err := c.Conn.Read(b)
+	c.bytes.Add(int64(n))
+	c.messages.Add(c.fromClient.feed(b[:n]))
+	return n, err
+}
+
+func (c *meteredConn) Write(b []byte) (int, error) {
+	n, err := c.Conn.Write(b)
+	c.bytes.Add(int64(n))
+	c.messages.Add(c.toClient.feed(b[:n]))
+	return n, err
+}
+
+// CloseWrite passes the half-close of the upstream on to the client.
+func (c *meteredConn) CloseWrite() error {
+	if conn, ok := c.Conn.(interface{ CloseWrite() error }); ok {
+		return conn.CloseWrite()
+	}
+	return nil
+}
+
+// wsFrameParser walks WebSocket frames (RFC 6455) without keeping them, to
+// count the data messages. A message ends with a FIN frame that isn't a
+// control frame.
+type wsFrameParser struct {
+	header    [14]byte
+	headerLen int
+	remaining uint64
+}
+
+func (p *wsFrameParser) feed(b []byte) int64 {
+	var messages int64
+	for len(b) > 0 {
+		if p.remaining > 0 {
+			n := min(uint64(len(b)), p.remaining)
+			p.remaining -= n
+			b = b[n:]
+			continue
+		}
+
+		p.header[p.headerLen] = b[0]
+		p.headerLen++
+		b = b[1:]
+		if p.headerLen < 2 {
+			continue
+		}
+
+		size := 2
+		switch p.header[1] & 0x7f {
+		case 126:
+			size += 2
+		case 127:
+			size += 8
+		}
+		if p.header[1]&0x80 != 0 {
+			size += 4 // masking key, set on the client frames
+		}
+		if p.headerLen < size {
+			continue
+		}
+
+		var length uint64
+		switch p.header[1] & 0x7f {
+		case 126:
+			length = uint64(p.header[2])<<8 | uint64(p.header[3])
+		case 127:
+			for _, v := range p.header[2:10] {
+				length = length<<8 | uint64(v)
+			}
+		default:
+			length = uint64(p.header[1] & 0x7f)
+		}
+
+		fin, opcode := p.header[0]&0x80 != 0, p.header[0]&0x0f
+		if fin && opcode < 0x8 {
+			messages++
+		}
+		p.remaining = length
+		p.headerLen = 0
+	}
+	return messages
+}
@@ -0,0 +1,160 @@
+package gateKeeperHandler
+
+import (
+	"bytes"
+	"encoding/binary"
+	"testing"
+)
+
+func TestSSEScanner(t *testing.T) {
+	tests := []struct {
+		name   string
+		path   string
+		stream string
+		events int64
+		units  float64
+		found  bool
+	}{
+		{"events counted", "", "data: a\n\ndata: b\n\n", 2, 0, false},
+		{"CRLF lines", "", "data: a\r\n\r\ndata: b\r\n\r\n", 2, 0, false},
+		{"events without data skipped", "", ": keep-alive\n\nevent: ping\n\ndata: a\n\n", 1, 0, false},
+		{"unfinished event not counted", "", "data: a\n\ndata: b\n", 1, 0, false},
+		{"last usage kept", "usage.total_tokens",
+			"data: {\"usage\":{\"total_tokens\":3}}\n\ndata: {\"usage\":{\"total_tokens\":7}}\n\ndata: [DONE]\n\n", 3, 7, true},
+		{"multi-line data joined", "usage.total_tokens",
+			"data: {\"usage\":\ndata: {\"total_tokens\":5}}\n\n", 1, 5, true},
+		{"no space after the colon", "usage", "data:{\"usage\":2}\n\n", 1, 2, true},
+		{"path missing", "usage.total_tokens", "data: {\"choices\":[]}\n\n", 1, 0, false},
+		{"invalid units ignored", "usage", "data: {\"usage\":-1}\n\n", 1, 0, false},
+		{"not JSON", "usage", "data: usage\n\n", 1, 0, false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			whole := newSSEScanner(tt.path)
+			whole.feed([]byte(tt.stream))
+			// Chunks split anywhere, in the middle of lines and of CRLF
+			split := newSSEScanner(tt.path)
+			for i := range tt.stream {
+				split.feed([]byte(tt.stream[i : i+1]))
+			}
+
+			for _, s := range []*sseScanner{whole, split} {
+				if s.events != tt.events || s.units != tt.units || s.found != tt.found {
+					t.Errorf("events, units, found = %d, %g, %v, want %d, %g, %v",
+						s.events, s.units, s.found, tt.events, tt.units, tt.found)
+				}
+			}
+		})
+	}
+}
+
+func TestSSEScannerOverflow(t *testing.T) {
+	s := newSSEScanner("usage")
+	s.feed([]byte(`data: {"usage":1,"pad":"`))
+	s.feed(bytes.Repeat([]byte("x"), maxUsageBodyBytes))
+	s.feed([]byte("\"}\n\ndata: {\"usage\":2}\n\n"))
+
+	// The oversized event still counts, the next one is read in full
+	if s.events != 2 || s.units != 2 || !s.found {
+		t.Errorf("events, units, found = %d, %g, %v", s.events, s.units, s.found)
+	}
+}
+
+// wsFrame is a frame carrying length zero bytes.
+func wsFrame(fin bool, opcode byte, masked bool, length int) []byte {
+	first := opcode
+	if fin {
+		first |= 0x80
+	}
+	var mask byte
+	if masked {
+		mask = 0x80
+	}
+
+	frame := []byte{first}
+	switch {
+	case length < 126:
+		frame = append(frame, mask|byte(length))
+	case length <= 0xffff:
+		frame = append(frame, mask|126)
+		frame = binary.BigEndian.AppendUint16(frame, uint16(length))
+	default:
+		frame = append(frame, mask|127)
+		frame = binary.BigEndian.AppendUint64(frame, uint64(length))
+	}
+	if masked {
+		frame = append(frame, 1, 2, 3, 4)
+	}
+	return append(frame, make([]byte, length)...)
+}
+
+func TestWSFrameParser(t *testing.T) {
+	tests := []struct {
+		name     string
+		frames   [][]byte
+		messages int64
+	}{
+		{"text", [][]byte{wsFrame(true, 0x1, false, 5)}, 1},
+		{"binary", [][]byte{wsFrame(true, 0x2, false, 0)}, 1},
+		{"masked", [][]byte{wsFrame(true, 0x1, true, 5)}, 1},
+		{"16-bit length", [][]byte{wsFrame(true, 0x2, false, 300), wsFrame(true, 0x1, false, 1)}, 2},
+		{"64-bit length", [][]byte{wsFrame(true, 0x2, true, 70000), wsFrame(true, 0x1, false, 1)}, 2},
+		{"fragmented", [][]byte{
+			wsFrame(false, 0x1, false, 3),
+			wsFrame(false, 0x0, false, 3),
+			wsFrame(true, 0x0, false, 3),
+		}, 1},
+		{"control frames skipped", [][]byte{
+			wsFrame(true, 0x9, false, 2),
+			wsFrame(true, 0xa, false, 2),
+			wsFrame(true, 0x8, true, 2),
+		}, 0},
+		{"ping inside a fragmented message", [][]byte{
+			wsFrame(false, 0x1, false, 3),
+			wsFrame(true, 0x9, false, 0),
+			wsFrame(true, 0x0, false, 3),
+		}, 1},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			stream := bytes.Join(tt.frames, nil)
+
+			var whole wsFrameParser
+			if got := whole.feed(stream); got != tt.messages {
+				t.Errorf("whole: %d messages, want %d", got, tt.messages)
+			}
+
+			var split wsFrameParser
+			var messages int64
+			for i := range stream {
+				messages += split.feed(stream[i : i+1])
+			}
+			if messages != tt.messages {
+				t.Errorf("byte by byte: %d messages, want %d", messages, tt.messages)
+			}
+			if split.headerLen != 0 || split.remaining != 0 {
+				t.Errorf("parser left mid-frame: header %d, remaining %d", split.headerLen, split.remaining)
+			}
+		})
+	}
+}
+
+func TestWSFrameParserCarriesOver(t *testing.T) {
+	stream := bytes.Join([][]byte{wsFrame(true, 0x1, false, 10), wsFrame(true, 0x1, false, 10)}, nil)
+
+	var p wsFrameParser
+	if got := p.feed(stream[:5]); got != 1 {
+		t.Errorf("first chunk: %d messages, want 1", got)
+	}
+	if p.remaining != 7 {
+		t.Errorf("remaining = %d, want 7", p.remaining)
+	}
+	if got := p.feed(stream[5:]); got != 1 {
+		t.Errorf("second chunk: %d messages, want 1", got)
+	}
+	if p.remaining != 0 {
+		t.Errorf("remaining = %d after the last frame", p.remaining)
+	}
+}
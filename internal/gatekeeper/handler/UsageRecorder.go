@@ -1,6 +1,8 @@
 package gateKeeperHandler
 
 import (
+	"context"
+
 	gatekeeping "github.com/bignyap/go-admin/internal/gatekeeper/service/GateKeeping"
 	"github.com/gin-gonic/gin"
 )
@@ -8,7 +10,7 @@ import (
 func (h *GateKeeperHandler) UsageRecorderHandler(c *gin.Context) {
 	_, output, err := h.UsageRecorderCore(c)
 	if err != nil {
-		h.ResponseWriter.Error(c, err)
+		h.WriteError(c, err)
 		return
 	}
 	h.ResponseWriter.Success(c, output)
@@ -25,3 +27,27 @@ func (h *GateKeeperHandler) UsageRecorderCore(c *gin.Context) (*gatekeeping.Reco
 	}
 	return input, output, nil
 }
+
+// RecordIncomingUsageCore records the usage of a request that already went
+// through ValidateIncomingRequestCore, settling its credit hold. The usage
+// units are read from the response, see CaptureUsageUnits. A stream is
+// recorded once closed, often by the client leaving, so the recording doesn't
+// follow the cancellation of the request.
+func (h *GateKeeperHandler) RecordIncomingUsageCore(c *gin.Context, input *gatekeeping.ValidateRequestInput, output *gatekeeping.ValidationRequestOutput) (float64, error) {
+	return h.GateKeepingService.RecordUsage(context.WithoutCancel(c.Request.Context()), &gatekeeping.RecordUsageInput{
+		Method:           input.Method,
+		Path:             input.Path,
+		OrganizationName: input.OrganizationName,
+		Host:             input.Host,
+		Header:           input.Header,
+		HoldID:           output.HoldID,
+		IdempotencyKey:   c.GetHeader(gatekeeping.IdempotencyKeyHeader),
+		UsageUnits:       h.incomingUsageUnits(c, output),
+	})
+}
+
+// ReleaseIncomingHold gives back the credits held for a request that failed
+// and won't be billed.
+func (h *GateKeeperHandler) ReleaseIncomingHold(c *gin.Context, output *gatekeeping.ValidationRequestOutput) {
+	h.GateKeepingService.ReleaseHold(context.WithoutCancel(c.Request.Context()), output)
+}
@@ -0,0 +1,182 @@
+package gateKeeperHandler
+
+import (
+	"bufio"
+	"bytes"
+	"compress/gzip"
+	"io"
+	"net"
+	"strings"
+
+	"github.com/bignyap/go-admin/internal/common"
+	gatekeeping "github.com/bignyap/go-admin/internal/gatekeeper/service/GateKeeping"
+	"github.com/bignyap/go-utilities/logger/api"
+	"github.com/gin-gonic/gin"
+)
+
+// maxUsageBodyBytes caps how much of a response is kept to read the usage
+// units from, a larger body is still sent in full but counts as one unit.
+const maxUsageBodyBytes = 1 << 20
+
+// usageMeter measures the response while it is written to the client. It
+// keeps a copy of a JSON body, follows the events of a server-sent event
+// stream and, once a WebSocket upgrade hijacks the connection, the frames
+// going both ways. Flush is left to the wrapped writer so streams go out
+// as they come.
+type usageMeter struct {
+	gin.ResponseWriter
+	source string
+	path   string
+
+	started   bool
+	bytes     int64
+	sse       *sseScanner
+	body      bytes.Buffer
+	truncated bool
+	conn      *meteredConn
+}
+
+func (w *usageMeter) Write(b []byte) (int, error) {
+	n, err := w.ResponseWriter.Write(b)
+	w.measure(b[:n])
+	return n, err
+}
+
+func (w *usageMeter) WriteString(s string) (int, error) {
+	n, err := w.ResponseWriter.WriteString(s)
+	w.measure([]byte(s[:n]))
+	return n, err
+}
+
+func (w *usageMeter) Hijack() (net.Conn, *bufio.ReadWriter, error) {
+	conn, rw, err := w.ResponseWriter.Hijack()
+	if err != nil {
+		return nil, nil, err
+	}
+	w.conn = &meteredConn{Conn: conn}
+	return w.conn, rw, nil
+}
+
+func (w *usageMeter) measure(b []byte) {
+
+	if !w.started {
+		w.started = true
+		header := w.Header()
+		if strings.HasPrefix(header.Get("Content-Type"), "text/event-stream") && header.Get("Content-Encoding") == "" {
+			path := ""
+			if w.source == common.UsageUnitSourceJSON {
+				path = w.path
+			}
+			w.sse = newSSEScanner(path)
+		}
+	}
+
+	w.bytes += int64(len(b))
+	if w.sse != nil {
+		w.sse.feed(b)
+		return
+	}
+	if w.source != common.UsageUnitSourceJSON || w.truncated {
+		return
+	}
+	if w.body.Len()+len(b) > maxUsageBodyBytes {
+		w.truncated = true
+		w.body.Reset()
+		return
+	}
+	w.body.Write(b)
+}
+
+// CaptureUsageUnits lets RecordIncomingUsageCore measure the response when
+// the endpoint reads its usage units from it. Call it before the request is
+// served.
+func (h *GateKeeperHandler) CaptureUsageUnits(c *gin.Context, output *gatekeeping.ValidationRequestOutput) {
+	switch output.Endpoint.UsageUnitSource {
+	case common.UsageUnitSourceJSON, common.UsageUnitSourceMessages, common.UsageUnitSourceBytes:
+		c.Writer = &usageMeter{
+			ResponseWriter: c.Writer,
+			source:         output.Endpoint.UsageUnitSource,
+			path:           output.Endpoint.UsageUnitKey.String,
+		}
+	}
+}
+
+// incomingUsageUnits reads the usage units from the response that was just
+// served, nil when there are none and the call counts as one unit.
+func (h *GateKeeperHandler) incomingUsageUnits(c *gin.Context, output *gatekeeping.ValidationRequestOutput) *float64 {
+
+	w, metered := c.Writer.(*usageMeter)
+	if metered && w.sse != nil {
+		// A last event without its closing blank line still counts
+		w.sse.feed([]byte("\n\n"))
+	}
+
+	switch output.Endpoint.UsageUnitSource {
+	case common.UsageUnitSourceMessages:
+		var units float64
+		switch {
+		case !metered:
+			return nil
+		case w.conn != nil:
+			units = float64(w.conn.messages.Load())
+		case w.sse != nil:
+			units = float64(w.sse.events)
+		default:
+			// A plain response is a single message
+			return nil
+		}
+		return &units
+
+	case common.UsageUnitSourceBytes:
+		if !metered {
+			return nil
+		}
+		units := float64(w.bytes)
+		if w.conn != nil {
+			units = float64(w.conn.bytes.Load())
+		}
+		return &units
+	}
+
+	var body []byte
+	if metered && w.sse != nil {
+		// The usage of a stream comes with its last events
+		if w.sse.found {
+			return &w.sse.units
+		}
+		return nil
+	}
+	if metered && !w.truncated {
+		body = w.body.Bytes()
+		// The proxy passes the upstream encoding through untouched
+		if strings.EqualFold(c.Writer.Header().Get("Content-Encoding"), "gzip") {
+			body = gunzip(body)
+		}
+	}
+
+	units, found, err := gatekeeping.UsageUnitsFromResponse(output.Endpoint, c.Writer.Header(), body)
+	if err != nil {
+		h.Logger.Warn("couldn't read the usage units, counting one",
+			api.Field{Key: "endpoint", Value: output.Endpoint.EndpointName},
+			api.Field{Key: "error", Value: err.Error()},
+		)
+		return nil
+	}
+	if !found {
+		return nil
+	}
+	return &units
+}
+
+func gunzip(body []byte) []byte {
+	reader, err := gzip.NewReader(bytes.NewReader(body))
+	if err != nil {
+		return nil
+	}
+	defer reader.Close()
+	decoded, err := io.ReadAll(io.LimitReader(reader, maxUsageBodyBytes))
+	if err != nil {
+		return nil
+	}
+	return decoded
+}
@@ -8,9 +8,10 @@ import (
 func (h *GateKeeperHandler) ValidateRequestHandler(c *gin.Context) {
 	output, err := h.ValidateRequestCore(c)
 	if err != nil {
-		h.ResponseWriter.Error(c, err)
+		h.WriteError(c, err)
 		return
 	}
+	setDecisionHeaders(c, output)
 	h.ResponseWriter.Success(c, output)
 }
 
@@ -25,3 +26,18 @@ func (h *GateKeeperHandler) ValidateRequestCore(c *gin.Context) (*gatekeeping.Va
 	}
 	return output, nil
 }
+
+// ValidateIncomingRequestCore gates the current request in middleware and
+// proxy modes, the organization comes from the caller's API key.
+func (h *GateKeeperHandler) ValidateIncomingRequestCore(c *gin.Context) (*gatekeeping.ValidateRequestInput, *gatekeeping.ValidationRequestOutput, error) {
+	input, err := h.GateKeepingService.IncomingRequestValidator(c)
+	if err != nil {
+		return nil, nil, err
+	}
+	output, err := h.GateKeepingService.ValidateRequest(c.Request.Context(), input)
+	if err != nil {
+		return nil, nil, err
+	}
+	setDecisionHeaders(c, output)
+	return input, output, nil
+}
@@ -2,28 +2,36 @@ package initializer
 
 import (
 	"context"
+	"errors"
+	"fmt"
 	"log"
 	"os"
 	"strconv"
+	"strings"
 	"time"
 
 	"github.com/bignyap/go-admin/internal/caching"
 	"github.com/bignyap/go-admin/internal/common"
+	"github.com/bignyap/go-admin/internal/common/route"
 	"github.com/bignyap/go-admin/internal/database/sqlcgen"
+	gkgrpc "github.com/bignyap/go-admin/internal/gatekeeper/grpc"
 	cachemanagement "github.com/bignyap/go-admin/internal/gatekeeper/service/CacheManagement"
 	gatekeeping "github.com/bignyap/go-admin/internal/gatekeeper/service/GateKeeping"
 	pubsublistener "github.com/bignyap/go-admin/internal/gatekeeper/service/PubSubListener"
 	"github.com/bignyap/go-admin/internal/initialize"
 	"github.com/bignyap/go-admin/internal/router"
+	pb "github.com/bignyap/go-admin/pkg/gatekeeper/proto"
 	"github.com/bignyap/go-utilities/counter"
 	"github.com/bignyap/go-utilities/logger/api"
 	"github.com/bignyap/go-utilities/logger/config"
 	"github.com/bignyap/go-utilities/logger/factory"
 	"github.com/bignyap/go-utilities/pubsub"
 	"github.com/bignyap/go-utilities/server"
+	authv3 "github.com/envoyproxy/go-control-plane/envoy/service/auth/v3"
 	"github.com/go-playground/validator"
 	"github.com/jackc/pgx/v5/pgxpool"
 	"github.com/redis/go-redis/v9"
+	"google.golang.org/grpc"
 )
 
 type GateKeeperService struct {
@@ -35,11 +43,21 @@ type GateKeeperService struct {
 	CacheContoller *caching.CacheController
 	CacheManager   *cachemanagement.CacheManagementService
 	// CounterWorker  *counter.CounterWorker
-	Matcher      *gatekeeping.Matcher
+	Matcher      *route.Matcher
+	RateLimiter  *gatekeeping.RateLimiter
+	Reservations *gatekeeping.ReservationStore
+	Idempotency  *gatekeeping.IdempotencyStore
+	JWTValidator *gatekeeping.JWTValidator
+	Identity     *gatekeeping.OrgIdentity
+	Upstreams    *gatekeeping.UpstreamRouter
 	PubSubClient pubsub.PubSubClient
 	Mode         string
+	Enforcement  string
 	Target       string
-	stopFlush    chan struct{}
+	// How often the Matcher is reconciled with the database, never when 0
+	ReconcileInterval time.Duration
+	stopFlush         chan struct{}
+	stopReconcile     chan struct{}
 }
 
 func NewGateKeeperService(
@@ -50,7 +68,14 @@ func NewGateKeeperService(
 	cacheController *caching.CacheController,
 	redisClient redis.UniversalClient,
 	counterWorker *counter.CounterWorker,
+	rateLimitWindow time.Duration,
+	reservationTTL time.Duration,
+	idempotencyWindow time.Duration,
+	reconcileInterval time.Duration,
+	jwtValidator *gatekeeping.JWTValidator,
+	identity *gatekeeping.OrgIdentity,
 	mode string,
+	enforcement string,
 	target string,
 	flushInterval int64,
 ) *GateKeeperService {
@@ -62,6 +87,10 @@ func NewGateKeeperService(
 		cacheController, redisClient, counterWorker,
 	)
 
+	// A settled credit hold keeps counting until the counter worker has pushed
+	// its usage to redis
+	reservations := gatekeeping.NewReservationStore(redisClient, reservationTTL, 2*counterWorker.GetInterval())
+
 	return &GateKeeperService{
 		Logger:         logger,
 		Validator:      validator,
@@ -69,10 +98,19 @@ func NewGateKeeperService(
 		Conn:           conn,
 		CacheContoller: cacheController,
 		CacheManager:   cacheManager,
+		RateLimiter:    gatekeeping.NewRateLimiter(redisClient, rateLimitWindow),
+		Reservations:   reservations,
+		Idempotency:    gatekeeping.NewIdempotencyStore(redisClient, idempotencyWindow),
+		JWTValidator:   jwtValidator,
+		Identity:       identity,
 		PubSubClient:   pubSubClient,
 		Mode:           mode,
+		Enforcement:    enforcement,
 		Target:         target,
-		stopFlush:      make(chan struct{}),
+
+		ReconcileInterval: reconcileInterval,
+		stopFlush:         make(chan struct{}),
+		stopReconcile:     make(chan struct{}),
 	}
 }
 
@@ -92,37 +130,85 @@ func (s *GateKeeperService) Setup(server server.Server) error {
 		s.Matcher,
 		s.CacheContoller,
 		s.CacheManager.CounterWorker,
+		s.RateLimiter,
+		s.Reservations,
+		s.Idempotency,
+		s.JWTValidator,
+		s.Identity,
+		s.Upstreams,
 		s.Mode,
-		s.Target,
+		s.Enforcement,
 		s.CacheManager.FlushInterval,
 	)
 
-	// Start periodic DB flush (Redis -> DB only)
-	rediscacheFlushInterval := time.Duration(s.CacheManager.FlushInterval) * time.Second
-	cachemanagement.StartPeriodicFlush(s.CacheManager, rediscacheFlushInterval, s.stopFlush)
+	s.startPeriodicFlush()
+	s.startMatcherReconciler()
+
+	setupLogger.Info("Completed")
+	return nil
+}
+
+// RegisterGRPC is the SERVER_TYPE=grpc counterpart of Setup.
+func (s *GateKeeperService) RegisterGRPC(registrar grpc.ServiceRegistrar) error {
+	setupLogger := s.Logger.WithComponent("server.RegisterGRPC")
+	setupLogger.Info("Starting")
+
+	service := &gatekeeping.GateKeepingService{
+		Logger:        s.Logger,
+		Validator:     s.Validator,
+		DB:            s.DB,
+		Conn:          s.Conn,
+		Cache:         s.CacheContoller,
+		Match:         s.Matcher,
+		CounterWorker: s.CacheManager.CounterWorker,
+		RateLimiter:   s.RateLimiter,
+		Reservations:  s.Reservations,
+		Idempotency:   s.Idempotency,
+		JWT:           s.JWTValidator,
+		Identity:      s.Identity,
+		Enforcement:   s.Enforcement,
+		FlushInterval: s.CacheManager.FlushInterval,
+	}
+	pb.RegisterGatekeeperServiceServer(registrar, gkgrpc.NewGatekeeperGRPCHandler(service))
+	authv3.RegisterAuthorizationServer(registrar, gkgrpc.NewExtAuthzHandler(service))
+
+	s.startPeriodicFlush()
+	s.startMatcherReconciler()
 
 	setupLogger.Info("Completed")
 	return nil
 }
 
+// Start periodic DB flush (Redis -> DB only)
+func (s *GateKeeperService) startPeriodicFlush() {
+	rediscacheFlushInterval := time.Duration(s.CacheManager.FlushInterval) * time.Second
+	cachemanagement.StartPeriodicFlush(s.CacheManager, rediscacheFlushInterval, s.stopFlush)
+}
+
 func (s *GateKeeperService) Shutdown() error {
 	shtLogger := s.Logger.WithComponent("server.Shutdown")
 	shtLogger.Info("Starting")
 
 	// Stop periodic DB flush
 	close(s.stopFlush)
+	close(s.stopReconcile)
 
 	ctx := context.Background()
 
 	// Flush local counters to Redis
-	if err := s.CacheManager.CounterWorker.FlushNow(string(common.UsagePrefix), ctx); err != nil {
-		shtLogger.Error("Flush to Redis failed", err)
+	for _, prefix := range []common.RedisPrefix{common.UsagePrefix, common.ShadowPrefix} {
+		if err := s.CacheManager.CounterWorker.FlushNow(string(prefix), ctx); err != nil {
+			shtLogger.Error("Flush to Redis failed", err)
+		}
 	}
 
 	// Flush Redis -> DB
 	s.CacheManager.SyncAggregatedToDB(ctx, string(common.UsagePrefix), func(key string, val map[string]float64) error {
 		return s.CacheManager.IncrementUsageFromCacheKey(ctx, key, val)
 	})
+	s.CacheManager.SyncAggregatedToDB(ctx, string(common.ShadowPrefix), func(key string, val map[string]float64) error {
+		return s.CacheManager.IncrementShadowDenialsFromCacheKey(ctx, key, val)
+	})
 	shtLogger.Info("Cache flushed")
 
 	// Close Redis
@@ -142,44 +228,153 @@ func (s *GateKeeperService) Shutdown() error {
 }
 
 func (s *GateKeeperService) InitializeEPMatcher() {
-	limit := int32(10000)
+	endpoints, skipped, err := s.loadEndpoints(context.Background())
+	if err != nil {
+		s.Logger.Fatal("couldn't retrieve endpoints", err)
+	}
 
-	listEndpoints, err := common.FetchAll(
+	s.Matcher = route.NewMatcher()
+	if err := errors.Join(append(skipped, s.Matcher.Load(endpoints))...); err != nil {
+		s.Logger.Error("some endpoints were left out of the matcher", err)
+	}
+}
+
+// loadEndpoints reads the endpoints of the matcher from the database. The
+// ones whose header matchers can't be read are skipped, matching them
+// without their headers could let through requests meant for another
+// endpoint.
+func (s *GateKeeperService) loadEndpoints(ctx context.Context) (endpoints []route.Endpoint, skipped []error, err error) {
+	rows, err := common.FetchAll(
 		func(offset, limit int32) ([]sqlcgen.ListApiEndpointRow, error) {
-			endpoints, err := s.DB.ListApiEndpoint(context.Background(), sqlcgen.ListApiEndpointParams{
+			return s.DB.ListApiEndpoint(ctx, sqlcgen.ListApiEndpointParams{
 				Limit:  limit,
 				Offset: offset,
 			})
-			if err != nil {
-				s.Logger.Fatal("couldn't retrieve endpoints", err)
-			}
-			return endpoints, nil
-		}, limit,
+		}, 10000,
 	)
 	if err != nil {
-		s.Logger.Fatal("couldn't retrieve endpoints", err)
+		return nil, nil, err
 	}
 
-	var endpoints []gatekeeping.Endpoint
-	for _, endpoint := range listEndpoints {
-		endpoints = append(endpoints, gatekeeping.Endpoint{
-			Path:   endpoint.PathTemplate,
-			Method: endpoint.HttpMethod,
-			Code:   endpoint.EndpointName,
+	for _, endpoint := range rows {
+		headers, err := common.ParseHeaderMatchers(endpoint.HeaderMatchers.String)
+		if err != nil {
+			skipped = append(skipped, fmt.Errorf("endpoint %s: %w", endpoint.EndpointName, err))
+			continue
+		}
+		endpoints = append(endpoints, route.Endpoint{
+			Path:     endpoint.PathTemplate,
+			Method:   endpoint.HttpMethod,
+			Code:     endpoint.EndpointName,
+			Host:     endpoint.HostPattern.String,
+			Headers:  headers,
+			Priority: endpoint.MatchPriority,
 		})
 	}
+	return endpoints, skipped, nil
+}
+
+// startMatcherReconciler compares the Matcher with the database every
+// ReconcileInterval and swaps in the routes of the database when they
+// drifted apart, e.g. after a pub/sub message was lost while GateKeeper was
+// restarting.
+func (s *GateKeeperService) startMatcherReconciler() {
+	if s.ReconcileInterval <= 0 {
+		return
+	}
+
+	logger := s.Logger.WithComponent("MatcherReconciler")
+	logger.Info("Started")
+
+	go func() {
+		ticker := time.NewTicker(s.ReconcileInterval)
+		defer ticker.Stop()
+
+		for {
+			select {
+			case <-ticker.C:
+				s.reconcileMatcher(logger)
+			case <-s.stopReconcile:
+				logger.Info("Stopped")
+				return
+			}
+		}
+	}()
+}
+
+func (s *GateKeeperService) reconcileMatcher(logger api.Logger) {
+	ctx := context.Background()
+
+	// An event applied while the endpoints are read makes them stale
+	version := s.Matcher.Version()
+	endpoints, skipped, err := s.loadEndpoints(ctx)
+	if err != nil {
+		gatekeeping.MatcherReconcileFailures.Inc()
+		logger.Error("couldn't retrieve endpoints", err)
+		return
+	}
+
+	drift, err := s.Matcher.Reconcile(version, endpoints)
+	if errors.Is(err, route.ErrStaleSnapshot) {
+		logger.Info("routes changed while reconciling, retrying next time")
+		return
+	}
+	gatekeeping.MatcherDrift.Set(float64(len(drift)))
+	gatekeeping.MatcherDriftTotal.Add(float64(len(drift)))
+	gatekeeping.MatcherReconciledAt.SetToCurrentTime()
+	if len(drift) == 0 {
+		return
+	}
+
+	logger.Warn("matcher drifted from the database, routes reloaded", api.Field{Key: "endpoints", Value: drift})
+
+	// The missed events left the upstreams and the cached endpoints stale too
+	if s.Upstreams != nil {
+		if err := s.Upstreams.Reload(ctx); err != nil {
+			logger.Error("couldn't reload upstreams", err)
+		}
+	}
+	for _, code := range drift {
+		s.CacheContoller.Invalidate(ctx, common.RedisKeyFormatter(string(common.EndpointPrefix), code))
+	}
+
+	if err := errors.Join(append(skipped, err)...); err != nil {
+		logger.Error("some endpoints were left out of the matcher", err)
+	}
+}
+
+// InitializeUpstreams loads the upstreams the proxy routes the endpoints to,
+// PROXY_TARGET serves the endpoints without one. PROXY_SIGNING_SECRET signs
+// the identity headers sent upstream, none are sent without it.
+func (s *GateKeeperService) InitializeUpstreams() {
+	if s.Mode != "proxy" {
+		return
+	}
 
-	s.Matcher = gatekeeping.NewMatcher()
-	s.Matcher.Load(endpoints)
+	upstreams, err := gatekeeping.NewUpstreamRouter(s.DB, s.Target)
+	if err != nil {
+		s.Logger.Fatal("invalid PROXY_TARGET", err)
+	}
+	upstreams.Signer = gatekeeping.NewRequestSigner(os.Getenv("PROXY_SIGNING_SECRET"))
+	if upstreams.Signer == nil {
+		s.Logger.Warn("PROXY_SIGNING_SECRET is not set, no X-Gatari-* identity headers are sent upstream")
+	}
+	if err := upstreams.Reload(context.Background()); err != nil {
+		s.Logger.Fatal("couldn't load upstreams", err)
+	}
+	s.Upstreams = upstreams
 }
 
 func (s *GateKeeperService) InitializePubSubListener() {
 	pubSubListener := pubsublistener.NewPubSubListener(
-		s.Logger, s.CacheContoller, s.Matcher, s.PubSubClient,
+		s.Logger, s.CacheContoller, s.Matcher, s.PubSubClient, s.Upstreams,
 	)
 	if err := pubSubListener.UpdateEPMatcher(); err != nil {
 		s.Logger.Fatal("Failed to load pubsub listener", err)
 	}
+	if err := pubSubListener.UpdateUpstreams(); err != nil {
+		s.Logger.Fatal("Failed to load pubsub listener", err)
+	}
 	if err := pubSubListener.ResetGoAdminCache(); err != nil {
 		s.Logger.Fatal("Failed to load pubsub listener", err)
 	}
@@ -196,12 +391,9 @@ func InitializeGateKeeperServer() {
 		environment = "dev"
 	}
 	mode := os.Getenv("GATEKEEPER_MODE")
+	// Default upstream of the proxy mode, for the endpoints that have none
 	target := os.Getenv("PROXY_TARGET")
 
-	if mode == "proxy" && target == "" {
-		log.Fatal("PROXY_TARGET must be set in proxy mode")
-	}
-
 	serverType := os.Getenv("SERVER_TYPE")
 	if serverType == "" {
 		serverType = "http"
@@ -265,10 +457,75 @@ func InitializeGateKeeperServer() {
 		rediscacheFlushInterval = int64(common.DefaultRedisFlushInterval)
 	}
 
+	rateLimitWindow, err := strconv.ParseInt(os.Getenv("RATE_LIMIT_WINDOW"), 10, 32)
+	if err != nil {
+		rateLimitWindow = int64(gatekeeping.DefaultRateLimitWindow.Seconds())
+	}
+
+	reservationTTL, err := strconv.ParseInt(os.Getenv("RESERVATION_TTL"), 10, 32)
+	if err != nil {
+		reservationTTL = int64(gatekeeping.DefaultReservationTTL.Seconds())
+	}
+
+	idempotencyWindow, err := strconv.ParseInt(os.Getenv("IDEMPOTENCY_WINDOW"), 10, 32)
+	if err != nil {
+		idempotencyWindow = int64(gatekeeping.DefaultIdempotencyWindow.Seconds())
+	}
+
+	reconcileInterval, err := strconv.ParseInt(os.Getenv("MATCHER_RECONCILE_INTERVAL"), 10, 32)
+	if err != nil {
+		reconcileInterval = int64(gatekeeping.DefaultMatcherReconcileInterval.Seconds())
+	}
+
+	jwksRefreshInterval, err := strconv.ParseInt(os.Getenv("JWT_JWKS_REFRESH_INTERVAL"), 10, 32)
+	if err != nil {
+		jwksRefreshInterval = int64(gatekeeping.DefaultJWKSRefreshInterval.Seconds())
+	}
+	jwtValidator := gatekeeping.NewJWTValidator(
+		gatekeeping.JWTConfig{
+			RealmClaim:      os.Getenv("JWT_REALM_CLAIM"),
+			JWKSURLTemplate: os.Getenv("JWT_JWKS_URL_TEMPLATE"),
+			Audience:        strings.FieldsFunc(os.Getenv("JWT_AUDIENCE"), func(r rune) bool { return r == ',' || r == ' ' }),
+		},
+		time.Duration(jwksRefreshInterval)*time.Second,
+	)
+
+	// Only credentials identify the organization unless told otherwise, the
+	// header extractor trusts whatever the client sends
+	orgExtractors := os.Getenv("ORG_EXTRACTORS")
+	identity, err := gatekeeping.NewOrgIdentity(gatekeeping.OrgIdentityConfig{
+		Extractors:    strings.FieldsFunc(orgExtractors, func(r rune) bool { return r == ',' || r == ' ' }),
+		Header:        os.Getenv("ORG_HEADER"),
+		SubdomainBase: os.Getenv("ORG_SUBDOMAIN_BASE"),
+		PathPrefix:    os.Getenv("ORG_PATH_PREFIX"),
+		CertField:     os.Getenv("ORG_CERT_FIELD"),
+		CertHeader:    os.Getenv("ORG_CERT_HEADER"),
+		CertCA:        os.Getenv("ORG_CERT_CA"),
+	})
+	if err != nil {
+		log.Fatalf("Invalid ORG_EXTRACTORS: %v", err)
+	}
+
+	// Organizations can override it with the enforcement key of their config
+	enforcement := os.Getenv("ENFORCEMENT_MODE")
+	switch enforcement {
+	case "":
+		enforcement = common.EnforcementEnforce
+	case common.EnforcementEnforce, common.EnforcementShadow:
+	default:
+		log.Fatalf("Invalid ENFORCEMENT_MODE: %s", enforcement)
+	}
+
 	gkService := NewGateKeeperService(
 		logger, conn, validator, pubSubClient,
 		cacheController, redisClient, counterWorker,
-		mode, target, rediscacheFlushInterval,
+		time.Duration(rateLimitWindow)*time.Second,
+		time.Duration(reservationTTL)*time.Second,
+		time.Duration(idempotencyWindow)*time.Second,
+		time.Duration(reconcileInterval)*time.Second,
+		jwtValidator,
+		identity,
+		mode, enforcement, target, rediscacheFlushInterval,
 	)
 
 	logWithComponent("InitializeEPMatcher", func() error {
@@ -276,6 +533,11 @@ func InitializeGateKeeperServer() {
 		return nil
 	})
 
+	logWithComponent("InitializeUpstreams", func() error {
+		gkService.InitializeUpstreams()
+		return nil
+	})
+
 	logWithComponent("InitializePubSubListener", func() error {
 		gkService.InitializePubSubListener()
 		return nil
@@ -53,3 +53,29 @@ func (srvc *CacheManagementService) IncrementUsageFromCacheKey(ctx context.Conte
 	return nil
 
 }
+
+func (srvc *CacheManagementService) IncrementShadowDenialsFromCacheKey(ctx context.Context, key string, val map[string]float64) error {
+
+	orgID, endpointID, reason, timestamp, err := common.ParseShadowDenialKey(key)
+	if err != nil {
+		return err
+	}
+
+	_, err = srvc.DB.CreateShadowDenialSummary(ctx, sqlcgen.CreateShadowDenialSummaryParams{
+		DenialStartDate: timestamp - int32(srvc.FlushInterval),
+		DenialEndDate:   timestamp,
+		DenialReason:    reason,
+		TotalDenials:    int32(common.SafeGet(val, "count", 0)),
+		ApiEndpointID:   endpointID,
+		OrganizationID:  orgID,
+	})
+	if err != nil {
+		return server.NewError(
+			server.ErrorInternal,
+			fmt.Sprintf("failed to record shadow denials for org=%d endpoint=%d reason=%s", orgID, endpointID, reason),
+			err,
+		)
+	}
+
+	return nil
+}
@@ -28,6 +28,9 @@ func StartPeriodicFlush(cm *CacheManagementService, interval time.Duration, stop
 				cm.SyncAggregatedToDB(ctx, string(common.UsagePrefix), func(key string, val map[string]float64) error {
 					return cm.IncrementUsageFromCacheKey(ctx, key, val)
 				})
+				cm.SyncAggregatedToDB(ctx, string(common.ShadowPrefix), func(key string, val map[string]float64) error {
+					return cm.IncrementShadowDenialsFromCacheKey(ctx, key, val)
+				})
 
 			case <-stopCh:
 				cm.Logger.WithComponent("StartPeriodicFlush").Info("Stopped")
@@ -47,6 +50,9 @@ func HandleShutdown(cm *CacheManagementService, stopFlush chan struct{}) {
 	cm.SyncAggregatedToDB(ctx, string(common.UsagePrefix), func(key string, val map[string]float64) error {
 		return cm.IncrementUsageFromCacheKey(ctx, key, val)
 	})
+	cm.SyncAggregatedToDB(ctx, string(common.ShadowPrefix), func(key string, val map[string]float64) error {
+		return cm.IncrementShadowDenialsFromCacheKey(ctx, key, val)
+	})
 
 	cm.Logger.WithComponent("HandleShutdown").Info("Gatekeeper shutdown complete")
 
@@ -0,0 +1,77 @@
+package gatekeeping
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/bignyap/go-admin/internal/caching"
+	"github.com/bignyap/go-admin/internal/common"
+	"github.com/bignyap/go-admin/internal/database/sqlcgen"
+	"github.com/bignyap/go-utilities/converter"
+	"github.com/bignyap/go-utilities/server"
+)
+
+// ErrInvalidApiKey is wrapped by every API key failure so the HTTP layer can
+// answer with 401 without leaking why the key was rejected.
+var ErrInvalidApiKey = errors.New("invalid api key")
+
+// last_used_at is informational, writing it on every request would turn each
+// call into a DB write. Each replica updates it at most once per interval.
+const apiKeyLastUsedInterval = time.Minute
+
+// ResolveApiKey looks the key up by its hash and returns the organization it
+// belongs to. Lookups are cached under apikey:<hash>, revocation drops the
+// entry through the apiKey:revoked channel.
+func (s *GateKeepingService) ResolveApiKey(ctx context.Context, key string) (*sqlcgen.GetApiKeyByHashRow, error) {
+
+	if !common.IsApiKey(key) {
+		return nil, server.NewError(server.ErrorUnauthorized, "invalid api key", ErrInvalidApiKey)
+	}
+
+	hash := common.HashApiKey(key)
+	cacheKey := common.RedisKeyFormatter(string(common.ApiKeyPrefix), hash)
+	apiKey, err := caching.GetFromCache(ctx, s.Cache, cacheKey, func() (sqlcgen.GetApiKeyByHashRow, error) {
+		return s.DB.GetApiKeyByHash(ctx, hash)
+	})
+	if err != nil {
+		return nil, server.NewError(server.ErrorUnauthorized, "invalid api key", ErrInvalidApiKey)
+	}
+
+	if apiKey.Revoked {
+		return nil, server.NewError(
+			server.ErrorUnauthorized, "invalid api key", fmt.Errorf("%w: revoked", ErrInvalidApiKey),
+		)
+	}
+	now := time.Now()
+	if apiKey.ExpiresAt.Valid && now.Unix() >= int64(apiKey.ExpiresAt.Int32) {
+		return nil, server.NewError(
+			server.ErrorUnauthorized, "invalid api key", fmt.Errorf("%w: expired", ErrInvalidApiKey),
+		)
+	}
+
+	s.touchApiKey(apiKey.ID, now)
+
+	return &apiKey, nil
+}
+
+func (s *GateKeepingService) touchApiKey(id int32, now time.Time) {
+
+	if last, ok := s.apiKeyLastUsed.Load(id); ok && now.Sub(last.(time.Time)) < apiKeyLastUsedInterval {
+		return
+	}
+	s.apiKeyLastUsed.Store(id, now)
+
+	go func() {
+		ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+		defer cancel()
+		err := s.DB.UpdateApiKeyLastUsed(ctx, sqlcgen.UpdateApiKeyLastUsedParams{
+			ApiKeyLastUsedAt: converter.ToPgInt4FromTime(now),
+			ApiKeyID:         id,
+		})
+		if err != nil {
+			s.Logger.Error("couldn't update api key last used", err)
+		}
+	}()
+}
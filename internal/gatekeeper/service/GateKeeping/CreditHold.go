@@ -0,0 +1,262 @@
+package gatekeeping
+
+import (
+	"context"
+	"crypto/rand"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/bignyap/go-admin/internal/common"
+	"github.com/bignyap/go-utilities/server"
+	"github.com/jackc/pgx/v5"
+	"github.com/redis/go-redis/v9"
+)
+
+// Reserves credits against what is left of the quota. Holds live in a sorted
+// set scored by their expiry, so the ones nobody settled are dropped on the
+// next call, and a hash with their amounts. Both keys share a hash tag so the
+// script also runs on Redis Cluster.
+//
+// KEYS[1] = hold id -> expiry (ms) sorted set
+// KEYS[2] = hold id -> amount hash
+// ARGV[1] = now in ms
+// ARGV[2] = expiry of the new hold in ms
+// ARGV[3] = hold id
+// ARGV[4] = amount
+// ARGV[5] = credits available (quota limit - recorded usage)
+// returns {granted, credits held including the new hold}
+var creditHoldScript = redis.NewScript(`
+local expired = redis.call("ZRANGEBYSCORE", KEYS[1], "-inf", ARGV[1])
+if #expired > 0 then
+  redis.call("ZREMRANGEBYSCORE", KEYS[1], "-inf", ARGV[1])
+  redis.call("HDEL", KEYS[2], unpack(expired))
+end
+local held = 0
+for _, v in ipairs(redis.call("HVALS", KEYS[2])) do
+  held = held + tonumber(v)
+end
+local amount = tonumber(ARGV[4])
+if held + amount > tonumber(ARGV[5]) then
+  return {0, tostring(held)}
+end
+redis.call("ZADD", KEYS[1], ARGV[2], ARGV[3])
+redis.call("HSET", KEYS[2], ARGV[3], ARGV[4])
+local ttl = tonumber(ARGV[2]) - tonumber(ARGV[1])
+for _, key in ipairs(KEYS) do
+  if redis.call("PTTL", key) < ttl then
+    redis.call("PEXPIRE", key, ttl)
+  end
+end
+return {1, tostring(held + amount)}
+`)
+
+// Settles a hold: it is renamed settled:<id>, so it keeps counting until
+// ARGV[2] but can't be settled or released again.
+//
+// KEYS as creditHoldScript
+// ARGV[1] = until when the settled hold counts, in ms
+// ARGV[2] = hold id
+// returns 1 if a hold was settled
+var settleHoldScript = redis.NewScript(`
+local id = ARGV[2]
+local amount = redis.call("HGET", KEYS[2], id)
+if not amount then
+  return 0
+end
+redis.call("ZREM", KEYS[1], id)
+redis.call("HDEL", KEYS[2], id)
+redis.call("ZADD", KEYS[1], ARGV[1], "settled:" .. id)
+redis.call("HSET", KEYS[2], "settled:" .. id, amount)
+return 1
+`)
+
+// CreditHold is the outcome of ReservationStore.Hold.
+type CreditHold struct {
+	// Empty when no hold was taken
+	ID      string
+	Granted bool
+	// Credits held by the requests in flight, this one included when granted
+	Held float64
+}
+
+// Hold reserves amount credits of the subscription quota period for a call
+// to the endpoint if the holds in flight leave enough of available. Holds
+// that are never settled or released expire after the store TTL, so a
+// crashed caller can't lock credits forever.
+func (r *ReservationStore) Hold(ctx context.Context, subID int32, periodStart int64, amount, available float64) (*CreditHold, error) {
+	if r == nil || r.Redis == nil {
+		return &CreditHold{Granted: true}, nil
+	}
+
+	idBytes := make([]byte, 12)
+	if _, err := rand.Read(idBytes); err != nil {
+		return nil, err
+	}
+	id := fmt.Sprintf("%d.%d.%s", subID, periodStart, hex.EncodeToString(idBytes))
+
+	now := time.Now()
+	expiryKey, amountKey := creditHoldKeys(subID, periodStart)
+	res, err := creditHoldScript.Run(
+		ctx, r.Redis,
+		[]string{expiryKey, amountKey},
+		now.UnixMilli(),
+		now.Add(r.TTL).UnixMilli(),
+		id,
+		strconv.FormatFloat(amount, 'f', -1, 64),
+		strconv.FormatFloat(available, 'f', -1, 64),
+	).Slice()
+	if err != nil {
+		return nil, fmt.Errorf("credit hold: %w", err)
+	}
+	if len(res) != 2 {
+		return nil, fmt.Errorf("credit hold: unexpected script result %v", res)
+	}
+
+	granted, _ := res[0].(int64)
+	heldStr, _ := res[1].(string)
+	held, err := strconv.ParseFloat(heldStr, 64)
+	if err != nil {
+		return nil, fmt.Errorf("credit hold: %w", err)
+	}
+
+	hold := &CreditHold{Granted: granted == 1, Held: held}
+	if hold.Granted {
+		hold.ID = id
+	}
+	return hold, nil
+}
+
+// Settle is called once the usage of the hold has been recorded. The usage
+// counters only reach Redis on the next counter worker flush, so the hold
+// keeps counting for SettleDelay instead of being dropped right away, which
+// would briefly free the credits twice.
+func (r *ReservationStore) Settle(ctx context.Context, id string) error {
+	if r == nil || r.Redis == nil || id == "" {
+		return nil
+	}
+	subID, periodStart, err := parseCreditHoldID(id)
+	if err != nil {
+		return err
+	}
+	expiryKey, amountKey := creditHoldKeys(subID, periodStart)
+	return settleHoldScript.Run(
+		ctx, r.Redis,
+		[]string{expiryKey, amountKey},
+		time.Now().Add(r.SettleDelay).UnixMilli(),
+		id,
+	).Err()
+}
+
+// Release drops a hold whose request was never billed.
+func (r *ReservationStore) Release(ctx context.Context, id string) error {
+	if r == nil || r.Redis == nil || id == "" {
+		return nil
+	}
+	subID, periodStart, err := parseCreditHoldID(id)
+	if err != nil {
+		return err
+	}
+	expiryKey, amountKey := creditHoldKeys(subID, periodStart)
+	_, err = r.Redis.TxPipelined(ctx, func(pipe redis.Pipeliner) error {
+		pipe.ZRem(ctx, expiryKey, id)
+		pipe.HDel(ctx, amountKey, id)
+		return nil
+	})
+	return err
+}
+
+func creditHoldKeys(subID int32, periodStart int64) (string, string) {
+	tag := fmt.Sprintf("{%d.%d}", subID, periodStart)
+	return common.RedisKeyFormatter(string(common.CreditHoldPrefix), tag, "expiry"),
+		common.RedisKeyFormatter(string(common.CreditHoldPrefix), tag, "amount")
+}
+
+func parseCreditHoldID(id string) (int32, int64, error) {
+	parts := strings.SplitN(id, ".", 3)
+	if len(parts) != 3 {
+		return 0, 0, fmt.Errorf("invalid credit hold id")
+	}
+	subID, err := strconv.ParseInt(parts[0], 10, 32)
+	if err != nil {
+		return 0, 0, fmt.Errorf("invalid credit hold id")
+	}
+	periodStart, err := strconv.ParseInt(parts[1], 10, 64)
+	if err != nil {
+		return 0, 0, fmt.Errorf("invalid credit hold id")
+	}
+	return int32(subID), periodStart, nil
+}
+
+// holdCredits reserves the cost of a paid request against the subscription
+// quota, so concurrent requests can't all pass on the last credits. The hold
+// is settled when the usage is recorded and expires if nothing records it.
+// Dynamic pricing is held for the expected units and not at all without
+// them, a one unit hold wouldn't stop a call billed thousands of units.
+func (s *GateKeepingService) holdCredits(ctx context.Context, orgSubDetails *GetOrgSubDetailsOutput, units *float64) error {
+
+	sub := orgSubDetails.Subscription
+	if sub.ApiLimit.Int32 <= 0 || orgSubDetails.Endpoint.AccessType != "paid" {
+		return nil
+	}
+
+	pricing, err := s.getPricingFromCache(ctx, orgSubDetails)
+	if err != nil {
+		if errors.Is(err, pgx.ErrNoRows) {
+			return nil
+		}
+		return server.NewError(server.ErrorInternal, "pricing error", err)
+	}
+	cost := pricing.CostPerCall
+	if strings.EqualFold(pricing.CostMode, "dynamic") {
+		if units == nil {
+			return nil
+		}
+		cost *= *units
+	}
+	if cost <= 0 {
+		return nil
+	}
+
+	periodStart := QuotaPeriodStart(sub.QuotaResetInterval.String, time.Now())
+	available := float64(sub.ApiLimit.Int32) - orgSubDetails.QuotaUsed
+	hold, err := s.Reservations.Hold(ctx, sub.ID, periodStart, cost, available)
+	if err != nil {
+		// Fail open like the rate limiter, the quota check above still applies
+		s.Logger.Error("credit hold unavailable", err)
+		return nil
+	}
+	if !hold.Granted {
+		return server.NewError(
+			server.ErrorUnauthorized, "quota exceeded",
+			quotaExceeded(sub, fmt.Sprintf("%g credits held by requests in flight", hold.Held)),
+		)
+	}
+
+	orgSubDetails.HoldID = hold.ID
+	orgSubDetails.Remaining = max(int32(available-hold.Held), 0)
+	return nil
+}
+
+// settleCredits and releaseCredits only log failures, a hold left behind
+// expires on its own.
+func (s *GateKeepingService) settleCredits(ctx context.Context, holdID string) {
+	if err := s.Reservations.Settle(ctx, holdID); err != nil {
+		s.Logger.Error("couldn't settle credit hold", err)
+	}
+}
+
+func (s *GateKeepingService) releaseCredits(ctx context.Context, holdID string) {
+	if err := s.Reservations.Release(ctx, holdID); err != nil {
+		s.Logger.Error("couldn't release credit hold", err)
+	}
+}
+
+// ReleaseHold gives back the credits held for a validated request that won't
+// be billed, e.g. because the upstream failed.
+func (s *GateKeepingService) ReleaseHold(ctx context.Context, output *ValidationRequestOutput) {
+	s.releaseCredits(ctx, output.HoldID)
+}
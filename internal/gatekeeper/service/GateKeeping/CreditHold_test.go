@@ -0,0 +1,192 @@
+package gatekeeping
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/alicebob/miniredis/v2"
+	"github.com/redis/go-redis/v9"
+)
+
+func newTestReservationStore(t *testing.T, ttl, settleDelay time.Duration) *ReservationStore {
+	t.Helper()
+	server := miniredis.RunT(t)
+	client := redis.NewClient(&redis.Options{Addr: server.Addr()})
+	t.Cleanup(func() { client.Close() })
+	return NewReservationStore(client, ttl, settleDelay)
+}
+
+func mustHold(t *testing.T, store *ReservationStore, amount, available float64) *CreditHold {
+	t.Helper()
+	hold, err := store.Hold(context.Background(), 7, 1700000000, amount, available)
+	if err != nil {
+		t.Fatalf("Hold: %v", err)
+	}
+	return hold
+}
+
+func TestHold(t *testing.T) {
+	tests := []struct {
+		name      string
+		amounts   []float64
+		available float64
+		granted   []bool
+		held      float64
+	}{
+		{"fits", []float64{4, 4}, 10, []bool{true, true}, 8},
+		{"exactly the limit", []float64{5, 5}, 10, []bool{true, true}, 10},
+		{"over the limit", []float64{4, 4, 4}, 10, []bool{true, true, false}, 8},
+		{"smaller one still fits", []float64{8, 4, 2}, 10, []bool{true, false, true}, 10},
+		{"nothing available", []float64{1}, 0, []bool{false}, 0},
+		{"fractional credits", []float64{0.25, 0.5}, 0.75, []bool{true, true}, 0.75},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			store := newTestReservationStore(t, time.Minute, time.Minute)
+			var last *CreditHold
+			for i, amount := range tt.amounts {
+				last = mustHold(t, store, amount, tt.available)
+				if last.Granted != tt.granted[i] {
+					t.Fatalf("hold %d of %g granted = %v, want %v", i, amount, last.Granted, tt.granted[i])
+				}
+				if last.Granted != (last.ID != "") {
+					t.Errorf("hold %d: granted = %v with id %q", i, last.Granted, last.ID)
+				}
+			}
+			if last.Held != tt.held {
+				t.Errorf("held = %g, want %g", last.Held, tt.held)
+			}
+		})
+	}
+}
+
+func TestSettleKeepsCountingUntilTheDelay(t *testing.T) {
+	ctx := context.Background()
+	store := newTestReservationStore(t, time.Minute, 50*time.Millisecond)
+
+	hold := mustHold(t, store, 6, 10)
+	if err := store.Settle(ctx, hold.ID); err != nil {
+		t.Fatal(err)
+	}
+	// Not flushed to the usage counters yet, still counts
+	if got := mustHold(t, store, 6, 10); got.Granted {
+		t.Errorf("a settled hold stopped counting before the delay")
+	}
+
+	time.Sleep(100 * time.Millisecond)
+	if got := mustHold(t, store, 6, 10); !got.Granted {
+		t.Errorf("a settled hold still counts after the delay, held %g", got.Held)
+	}
+}
+
+func TestSettleOnlyOnce(t *testing.T) {
+	ctx := context.Background()
+	store := newTestReservationStore(t, time.Minute, time.Minute)
+
+	hold := mustHold(t, store, 6, 10)
+	for i := 0; i < 2; i++ {
+		if err := store.Settle(ctx, hold.ID); err != nil {
+			t.Fatal(err)
+		}
+	}
+	// Releasing a settled hold must not free the credits it billed
+	if err := store.Release(ctx, hold.ID); err != nil {
+		t.Fatal(err)
+	}
+	if got := mustHold(t, store, 4, 10); !got.Granted || got.Held != 10 {
+		t.Errorf("held = %g after settling twice and releasing, want 10", got.Held)
+	}
+	if got := mustHold(t, store, 1, 10); got.Granted {
+		t.Errorf("the settled hold was freed")
+	}
+}
+
+func TestSettleLeavesOtherHolds(t *testing.T) {
+	ctx := context.Background()
+	store := newTestReservationStore(t, time.Minute, 0)
+
+	first := mustHold(t, store, 3, 10)
+	second := mustHold(t, store, 3, 10)
+	if err := store.Settle(ctx, first.ID); err != nil {
+		t.Fatal(err)
+	}
+	time.Sleep(10 * time.Millisecond)
+
+	// Only the settled one is gone, the other is still in flight
+	if got := mustHold(t, store, 7, 10); !got.Granted || got.Held != 10 {
+		t.Errorf("held = %g, want 10", got.Held)
+	}
+	if err := store.Release(ctx, second.ID); err != nil {
+		t.Fatal(err)
+	}
+	if got := mustHold(t, store, 3, 10); !got.Granted {
+		t.Errorf("the released hold still counts, held %g", got.Held)
+	}
+}
+
+func TestReleaseFreesTheCredits(t *testing.T) {
+	ctx := context.Background()
+	store := newTestReservationStore(t, time.Minute, time.Minute)
+
+	hold := mustHold(t, store, 10, 10)
+	if got := mustHold(t, store, 1, 10); got.Granted {
+		t.Fatal("a hold over the limit was granted")
+	}
+	if err := store.Release(ctx, hold.ID); err != nil {
+		t.Fatal(err)
+	}
+	if got := mustHold(t, store, 10, 10); !got.Granted {
+		t.Errorf("the released credits weren't freed, held %g", got.Held)
+	}
+}
+
+func TestExpiredHoldsAreDropped(t *testing.T) {
+	store := newTestReservationStore(t, 50*time.Millisecond, time.Minute)
+
+	mustHold(t, store, 10, 10)
+	time.Sleep(100 * time.Millisecond)
+	if got := mustHold(t, store, 10, 10); !got.Granted || got.Held != 10 {
+		t.Errorf("an expired hold still counts, held %g", got.Held)
+	}
+}
+
+func TestHoldWithoutRedis(t *testing.T) {
+	var store *ReservationStore
+	hold, err := store.Hold(context.Background(), 7, 1700000000, 5, 0)
+	if err != nil || !hold.Granted || hold.ID != "" {
+		t.Errorf("Hold = %+v, %v, want granted without an id", hold, err)
+	}
+	if err := store.Settle(context.Background(), "7.1700000000.abc"); err != nil {
+		t.Error(err)
+	}
+}
+
+func TestParseCreditHoldID(t *testing.T) {
+	tests := []struct {
+		id          string
+		subID       int32
+		periodStart int64
+		wantErr     bool
+	}{
+		{id: "7.1700000000.9f86d081", subID: 7, periodStart: 1700000000},
+		{id: "7.0.abc.def", subID: 7, periodStart: 0},
+		{id: "7.1700000000", wantErr: true},
+		{id: "x.1700000000.abc", wantErr: true},
+		{id: "7.y.abc", wantErr: true},
+		{id: "99999999999.1.abc", wantErr: true},
+		{id: "", wantErr: true},
+	}
+
+	for _, tt := range tests {
+		subID, periodStart, err := parseCreditHoldID(tt.id)
+		if (err != nil) != tt.wantErr {
+			t.Errorf("parseCreditHoldID(%q) err = %v, want error %v", tt.id, err, tt.wantErr)
+			continue
+		}
+		if !tt.wantErr && (subID != tt.subID || periodStart != tt.periodStart) {
+			t.Errorf("parseCreditHoldID(%q) = %d, %d, want %d, %d", tt.id, subID, periodStart, tt.subID, tt.periodStart)
+		}
+	}
+}
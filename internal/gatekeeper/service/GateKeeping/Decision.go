@@ -0,0 +1,182 @@
+package gatekeeping
+
+import (
+	"errors"
+	"math"
+	"net/http"
+	"strconv"
+	"time"
+)
+
+// DenialCode tells why a request was denied. Every transport answers with
+// the same codes: the JSON body of the HTTP modes, the DenialReason of gRPC
+// and the shadow denials.
+type DenialCode string
+
+const (
+	DenialUnknownEndpoint     DenialCode = "unknown_endpoint"
+	DenialOrgNotFound         DenialCode = "org_not_found"
+	DenialInvalidCredentials  DenialCode = "invalid_credentials"
+	DenialPermissionDenied    DenialCode = "permission_denied"
+	DenialInsufficientScope   DenialCode = "insufficient_scope"
+	DenialNoSubscription      DenialCode = "no_subscription"
+	DenialSubscriptionExpired DenialCode = "subscription_expired"
+	DenialQuotaExceeded       DenialCode = "quota_exceeded"
+	DenialRateLimited         DenialCode = "rate_limited"
+)
+
+// HTTPStatus is the status a denial is answered with: 404 when nothing
+// matches the request, 401 when the caller isn't known, 403 when it isn't
+// allowed, 402 when its subscription doesn't cover the call and 429 when it
+// goes too fast.
+func (c DenialCode) HTTPStatus() int {
+	switch c {
+	case DenialUnknownEndpoint:
+		return http.StatusNotFound
+	case DenialOrgNotFound, DenialInvalidCredentials:
+		return http.StatusUnauthorized
+	case DenialNoSubscription, DenialSubscriptionExpired, DenialQuotaExceeded:
+		return http.StatusPaymentRequired
+	case DenialRateLimited:
+		return http.StatusTooManyRequests
+	}
+	return http.StatusForbidden
+}
+
+var denialSentinels = []struct {
+	sentinel error
+	code     DenialCode
+}{
+	{ErrInvalidApiKey, DenialInvalidCredentials},
+	{ErrInvalidToken, DenialInvalidCredentials},
+	{ErrUnknownEndpoint, DenialUnknownEndpoint},
+	{ErrOrgNotFound, DenialOrgNotFound},
+	{ErrPermissionDenied, DenialPermissionDenied},
+	{ErrInsufficientScope, DenialInsufficientScope},
+	{ErrNoSubscription, DenialNoSubscription},
+	{ErrSubscriptionExpired, DenialSubscriptionExpired},
+	{ErrQuotaExceeded, DenialQuotaExceeded},
+}
+
+// denialCode is the code of the access decision err, empty when err isn't
+// one (bad input, internal failures).
+func denialCode(err error) DenialCode {
+	var rlErr *RateLimitError
+	if errors.As(err, &rlErr) {
+		return DenialRateLimited
+	}
+	for _, s := range denialSentinels {
+		if errors.Is(err, s.sentinel) {
+			return s.code
+		}
+	}
+	return ""
+}
+
+// QuotaStatus is where the subscription stands against its quota.
+type QuotaStatus struct {
+	Limit     int32
+	Remaining int32
+	// Unix time the quota period resets, 0 if never
+	ResetsAt int64
+}
+
+// Decision is the outcome of an access check as the transports report it.
+type Decision struct {
+	Allowed bool
+	// Empty when allowed
+	Code    DenialCode
+	Message string
+	// Tightest rate limit that applied, nil if none did
+	RateLimit *RateLimitResult
+	// nil when the subscription is unlimited or unknown
+	Quota *QuotaStatus
+	// Set on the denials that pass with time, rate limits and quotas
+	RetryAfter time.Duration
+}
+
+// AllowedDecision describes a request ValidateRequest allowed.
+func AllowedDecision(output *ValidationRequestOutput, now time.Time) *Decision {
+	decision := &Decision{Allowed: true, RateLimit: output.RateLimit}
+	if sub := output.Subscription; sub.ApiLimit.Int32 > 0 {
+		decision.Quota = &QuotaStatus{
+			Limit:     sub.ApiLimit.Int32,
+			Remaining: output.Remaining,
+			ResetsAt:  QuotaPeriodEnd(sub.QuotaResetInterval.String, now),
+		}
+	}
+	return decision
+}
+
+// DeniedDecision reads the access decision out of err. ok is false when err
+// isn't one, it should then be reported as an error.
+func DeniedDecision(err error, now time.Time) (*Decision, bool) {
+
+	code := denialCode(err)
+	if code == "" {
+		return nil, false
+	}
+	decision := &Decision{Code: code}
+
+	var rlErr *RateLimitError
+	var quotaErr *QuotaExceededError
+	switch {
+	case errors.As(err, &rlErr):
+		decision.Message = rlErr.Error()
+		decision.RateLimit = &RateLimitResult{
+			Scope:   rlErr.Scope,
+			Limit:   rlErr.Limit,
+			ResetIn: rlErr.RetryAfter,
+		}
+		decision.RetryAfter = rlErr.RetryAfter
+	case errors.As(err, &quotaErr):
+		decision.Message = quotaErr.Error()
+		decision.Quota = &QuotaStatus{Limit: quotaErr.Limit, ResetsAt: quotaErr.ResetsAt}
+		if quotaErr.ResetsAt > 0 {
+			decision.RetryAfter = time.Unix(quotaErr.ResetsAt, 0).Sub(now)
+		}
+	default:
+		for _, s := range denialSentinels {
+			if errors.Is(err, s.sentinel) {
+				decision.Message = s.sentinel.Error()
+				break
+			}
+		}
+	}
+	return decision, true
+}
+
+// HTTPStatus is 200 for an allowed request.
+func (d *Decision) HTTPStatus() int {
+	if d.Allowed {
+		return http.StatusOK
+	}
+	return d.Code.HTTPStatus()
+}
+
+// Headers are the rate limit and quota headers describing the decision, set
+// on allowed and denied responses alike.
+func (d *Decision) Headers() http.Header {
+	header := make(http.Header)
+	if rl := d.RateLimit; rl != nil {
+		header.Set("X-RateLimit-Limit", strconv.Itoa(int(rl.Limit)))
+		header.Set("X-RateLimit-Remaining", strconv.Itoa(int(rl.Remaining)))
+		header.Set("X-RateLimit-Reset", strconv.Itoa(ceilSeconds(rl.ResetIn)))
+		header.Set("X-RateLimit-Scope", rl.Scope)
+	}
+	if q := d.Quota; q != nil {
+		header.Set("X-Quota-Limit", strconv.Itoa(int(q.Limit)))
+		header.Set("X-Quota-Remaining", strconv.Itoa(int(q.Remaining)))
+		if q.ResetsAt > 0 {
+			header.Set("X-Quota-Reset", strconv.FormatInt(q.ResetsAt, 10))
+		}
+	}
+	if d.RetryAfter > 0 {
+		header.Set("Retry-After", strconv.Itoa(max(ceilSeconds(d.RetryAfter), 1)))
+	}
+	return header
+}
+
+func ceilSeconds(d time.Duration) int {
+	return int(math.Ceil(d.Seconds()))
+}
@@ -0,0 +1,141 @@
+package gatekeeping
+
+import (
+	"errors"
+	"fmt"
+	"net/http"
+	"testing"
+	"time"
+
+	"github.com/jackc/pgx/v5/pgtype"
+)
+
+func TestDeniedDecision(t *testing.T) {
+	now := time.Unix(1700000000, 0)
+
+	tests := []struct {
+		name    string
+		err     error
+		code    DenialCode
+		status  int
+		message string
+	}{
+		{"unknown endpoint", ErrUnknownEndpoint, DenialUnknownEndpoint, http.StatusNotFound, "unknown endpoint"},
+		{"organization not found", denied(ErrOrgNotFound, errors.New("no rows")), DenialOrgNotFound, http.StatusUnauthorized, "organization not found"},
+		{"invalid API key", ErrInvalidApiKey, DenialInvalidCredentials, http.StatusUnauthorized, "invalid api key"},
+		{"invalid token", fmt.Errorf("parse: %w", ErrInvalidToken), DenialInvalidCredentials, http.StatusUnauthorized, "invalid bearer token"},
+		{"permission denied", ErrPermissionDenied, DenialPermissionDenied, http.StatusForbidden, "permission denied"},
+		{"insufficient scope", ErrInsufficientScope, DenialInsufficientScope, http.StatusForbidden, "insufficient scope"},
+		{"no subscription", ErrNoSubscription, DenialNoSubscription, http.StatusPaymentRequired, "no active subscription"},
+		{"subscription expired", ErrSubscriptionExpired, DenialSubscriptionExpired, http.StatusPaymentRequired, "subscription expired"},
+		{"quota exceeded", &QuotaExceededError{Limit: 100, Cause: "100 of 100 used"}, DenialQuotaExceeded, http.StatusPaymentRequired, "quota exceeded: 100 of 100 used"},
+		{"rate limited", &RateLimitError{Scope: RateLimitScopeEndpoint, Limit: 5, Window: time.Minute}, DenialRateLimited, http.StatusTooManyRequests, "endpoint rate limit of 5 requests per 1m0s exceeded"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			decision, ok := DeniedDecision(tt.err, now)
+			if !ok {
+				t.Fatal("not read as a denial")
+			}
+			if decision.Allowed || decision.Code != tt.code || decision.HTTPStatus() != tt.status {
+				t.Errorf("allowed, code, status = %v, %s, %d, want false, %s, %d",
+					decision.Allowed, decision.Code, decision.HTTPStatus(), tt.code, tt.status)
+			}
+			if decision.Message != tt.message {
+				t.Errorf("message = %q, want %q", decision.Message, tt.message)
+			}
+		})
+	}
+
+	for _, err := range []error{nil, errors.New("database is down"), &CircuitOpenError{Upstream: "api"}} {
+		if decision, ok := DeniedDecision(err, now); ok {
+			t.Errorf("%v read as the denial %s", err, decision.Code)
+		}
+	}
+}
+
+func TestDecisionRetryAfter(t *testing.T) {
+	now := time.Unix(1700000000, 0)
+
+	tests := []struct {
+		name   string
+		err    error
+		want   time.Duration
+		header string
+	}{
+		{"rate limit", &RateLimitError{Limit: 5, RetryAfter: 1500 * time.Millisecond}, 1500 * time.Millisecond, "2"},
+		{"rate limit about to reset", &RateLimitError{Limit: 5, RetryAfter: time.Millisecond}, time.Millisecond, "1"},
+		{"quota with a reset", &QuotaExceededError{Limit: 100, ResetsAt: now.Unix() + 3600}, time.Hour, "3600"},
+		{"quota never reset", &QuotaExceededError{Limit: 100}, 0, ""},
+		{"permission", ErrPermissionDenied, 0, ""},
+	}
+
+	for _, tt := range tests {
+		decision, _ := DeniedDecision(tt.err, now)
+		if decision.RetryAfter != tt.want {
+			t.Errorf("%s: RetryAfter = %v, want %v", tt.name, decision.RetryAfter, tt.want)
+		}
+		if got := decision.Headers().Get("Retry-After"); got != tt.header {
+			t.Errorf("%s: Retry-After = %q, want %q", tt.name, got, tt.header)
+		}
+	}
+}
+
+func TestDecisionHeaders(t *testing.T) {
+	now := time.Date(2026, time.October, 18, 12, 0, 0, 0, time.UTC)
+	nextMonth := time.Date(2026, time.November, 1, 0, 0, 0, 0, time.UTC).Unix()
+
+	limited := &ValidationRequestOutput{
+		Remaining: 40,
+		RateLimit: &RateLimitResult{Scope: RateLimitScopeOrganization, Limit: 10, Remaining: 3, ResetIn: 2500 * time.Millisecond},
+	}
+	limited.Subscription.ApiLimit = pgtype.Int4{Int32: 100, Valid: true}
+	limited.Subscription.QuotaResetInterval = pgtype.Text{String: QuotaResetMonthly, Valid: true}
+
+	rateLimited, _ := DeniedDecision(&RateLimitError{Scope: RateLimitScopeEndpoint, Limit: 5, RetryAfter: time.Second}, now)
+
+	tests := []struct {
+		name     string
+		decision *Decision
+		status   int
+		want     map[string]string
+	}{
+		{"unlimited", AllowedDecision(&ValidationRequestOutput{Remaining: -1}, now), http.StatusOK, map[string]string{
+			"X-RateLimit-Limit": "",
+			"X-Quota-Limit":     "",
+			"Retry-After":       "",
+		}},
+		{"limited", AllowedDecision(limited, now), http.StatusOK, map[string]string{
+			"X-RateLimit-Limit":     "10",
+			"X-RateLimit-Remaining": "3",
+			"X-RateLimit-Reset":     "3",
+			"X-RateLimit-Scope":     "organization",
+			"X-Quota-Limit":         "100",
+			"X-Quota-Remaining":     "40",
+			"X-Quota-Reset":         fmt.Sprint(nextMonth),
+			"Retry-After":           "",
+		}},
+		{"rate limited", rateLimited, http.StatusTooManyRequests, map[string]string{
+			"X-RateLimit-Limit":     "5",
+			"X-RateLimit-Remaining": "0",
+			"X-RateLimit-Reset":     "1",
+			"X-RateLimit-Scope":     "endpoint",
+			"Retry-After":           "1",
+		}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if status := tt.decision.HTTPStatus(); status != tt.status {
+				t.Errorf("status = %d, want %d", status, tt.status)
+			}
+			header := tt.decision.Headers()
+			for name, value := range tt.want {
+				if got := header.Get(name); got != value {
+					t.Errorf("%s = %q, want %q", name, got, value)
+				}
+			}
+		})
+	}
+}
@@ -0,0 +1,98 @@
+package gatekeeping
+
+import (
+	"errors"
+	"fmt"
+	"math"
+	"time"
+
+	"github.com/bignyap/go-admin/internal/database/sqlcgen"
+)
+
+// Sentinels wrapped by the access decisions of GetOrgSubDetailsFromCache so the
+// transports can tell why a request was denied without parsing messages.
+var (
+	ErrUnknownEndpoint     = errors.New("unknown endpoint")
+	ErrOrgNotFound         = errors.New("organization not found")
+	ErrPermissionDenied    = errors.New("permission denied")
+	ErrInsufficientScope   = errors.New("insufficient scope")
+	ErrNoSubscription      = errors.New("no active subscription")
+	ErrSubscriptionExpired = errors.New("subscription expired")
+	ErrQuotaExceeded       = errors.New("quota exceeded")
+)
+
+// denied wraps the sentinel with the underlying cause, if any.
+func denied(sentinel error, cause error) error {
+	if cause == nil {
+		return sentinel
+	}
+	return fmt.Errorf("%w: %v", sentinel, cause)
+}
+
+// RateLimitError is returned when a request is rejected by a rate limit.
+// It is kept separate from server.InternalError so callers can answer with
+// 429 and a Retry-After hint instead of a generic authorization failure.
+type RateLimitError struct {
+	Scope      string
+	Limit      int32
+	Window     time.Duration
+	RetryAfter time.Duration
+}
+
+const (
+	RateLimitScopeOrganization = "organization"
+	RateLimitScopeEndpoint     = "endpoint"
+)
+
+func (e *RateLimitError) Error() string {
+	return fmt.Sprintf("%s rate limit of %d requests per %s exceeded", e.Scope, e.Limit, e.Window)
+}
+
+// RetryAfterSeconds rounds up so clients never retry before the window resets.
+func (e *RateLimitError) RetryAfterSeconds() int {
+	return int(math.Ceil(e.RetryAfter.Seconds()))
+}
+
+// QuotaExceededError is the ErrQuotaExceeded denial with the quota that ran
+// out, so the transports can tell when to come back.
+type QuotaExceededError struct {
+	Limit int32
+	// Unix time the quota period resets, 0 if never
+	ResetsAt int64
+	Cause    string
+}
+
+func quotaExceeded(sub sqlcgen.GetActiveSubscriptionRow, cause string) *QuotaExceededError {
+	return &QuotaExceededError{
+		Limit:    sub.ApiLimit.Int32,
+		ResetsAt: QuotaPeriodEnd(sub.QuotaResetInterval.String, time.Now()),
+		Cause:    cause,
+	}
+}
+
+func (e *QuotaExceededError) Error() string {
+	if e.Cause == "" {
+		return ErrQuotaExceeded.Error()
+	}
+	return fmt.Sprintf("%s: %s", ErrQuotaExceeded, e.Cause)
+}
+
+func (e *QuotaExceededError) Unwrap() error {
+	return ErrQuotaExceeded
+}
+
+// CircuitOpenError is returned by the proxy when every target of the upstream
+// is ejected. It is not an access decision, the request is answered with 503
+// and never billed.
+type CircuitOpenError struct {
+	Upstream   string
+	RetryAfter time.Duration
+}
+
+func (e *CircuitOpenError) Error() string {
+	return fmt.Sprintf("circuit open for upstream %s", e.Upstream)
+}
+
+func (e *CircuitOpenError) RetryAfterSeconds() int {
+	return int(math.Ceil(e.RetryAfter.Seconds()))
+}
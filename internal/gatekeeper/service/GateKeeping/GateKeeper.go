@@ -2,15 +2,19 @@ package gatekeeping
 
 import (
 	"context"
+	"errors"
+	"fmt"
 	"strconv"
 	"strings"
 	"time"
 
 	"github.com/bignyap/go-admin/internal/caching"
 	"github.com/bignyap/go-admin/internal/common"
+	"github.com/bignyap/go-admin/internal/common/route"
 	"github.com/bignyap/go-admin/internal/database/sqlcgen"
 	"github.com/bignyap/go-utilities/counter"
 	"github.com/bignyap/go-utilities/server"
+	"github.com/jackc/pgx/v5"
 )
 
 func (s *GateKeepingService) FlushAllCache(ctx context.Context) {
@@ -19,62 +23,265 @@ func (s *GateKeepingService) FlushAllCache(ctx context.Context) {
 }
 
 func (s *GateKeepingService) ValidateRequest(ctx context.Context, input *ValidateRequestInput) (*ValidationRequestOutput, error) {
-	orgSubDetails, err := s.GetOrgSubDetailsFromCache(ctx, input.Method, input.Path, input.OrganizationName)
+	orgSubDetails, err := s.GetOrgSubDetailsFromCache(ctx, input.Route(), input.OrganizationName)
 	if err != nil {
 		return nil, server.NewError(
 			server.ErrorUnauthorized, "failed to validate request", err,
 		)
 	}
+	if input.Scopes != nil && !hasScope(input.Scopes, orgSubDetails.Endpoint.ResourceTypeName, orgSubDetails.Endpoint.PermissionCode) {
+		err := server.NewError(
+			server.ErrorUnauthorized, "insufficient scope",
+			fmt.Errorf("%w: token lacks the %s scope", ErrInsufficientScope, orgSubDetails.Endpoint.PermissionCode),
+		)
+		if err := s.shadow(orgSubDetails, err); err != nil {
+			return nil, err
+		}
+	}
+	orgLimit, err := s.checkOrganizationRateLimit(ctx, orgSubDetails)
+	if err := s.shadow(orgSubDetails, err); err != nil {
+		return nil, err
+	}
+	endpointLimit, err := s.checkEndpointRateLimit(ctx, orgSubDetails)
+	if err := s.shadow(orgSubDetails, err); err != nil {
+		return nil, err
+	}
+	orgSubDetails.RateLimit = tighterRateLimit(orgLimit, endpointLimit)
+	if !input.SkipHold {
+		if err := s.shadow(orgSubDetails, s.holdCredits(ctx, orgSubDetails, input.UsageUnits)); err != nil {
+			return nil, err
+		}
+	}
 	return &orgSubDetails.ValidationRequestOutput, nil
 }
 
 func (s *GateKeepingService) RecordUsage(ctx context.Context, input *RecordUsageInput) (float64, error) {
-	orgSubDetails, err := s.GetOrgSubDetailsFromCache(ctx, input.Method, input.Path, input.OrganizationName)
+	orgSubDetails, err := s.getOrgSubDetails(ctx, input.Route(), input.OrganizationName, false)
 	if err != nil {
 		return 0.0, server.NewError(
 			server.ErrorUnauthorized, "failed to validate request", err,
 		)
 	}
+
+	units := 1.0
+	if input.UsageUnits != nil {
+		units = *input.UsageUnits
+	}
+	cost, duplicate, err := s.recordUsageOnce(ctx, orgSubDetails, units, input.IdempotencyKey)
+	if err != nil {
+		return 0, err
+	}
+	// The first call settled the hold, a retry must not settle another one
+	if !duplicate {
+		s.settleCredits(ctx, input.HoldID)
+	}
+	return cost, nil
+}
+
+// recordUsageOnce is recordUsage deduplicated on the idempotency key: a key
+// already recorded in the window isn't billed again, returns the cost
+// charged the first time and reports the call as a duplicate.
+func (s *GateKeepingService) recordUsageOnce(ctx context.Context, orgSubDetails *GetOrgSubDetailsOutput, units float64, idempotencyKey string) (float64, bool, error) {
+	if idempotencyKey == "" || orgSubDetails.Endpoint.AccessType != "paid" {
+		cost, err := s.recordUsage(ctx, orgSubDetails, units)
+		return cost, false, err
+	}
+
+	cost, err := s.usageCost(ctx, orgSubDetails, units)
+	if err != nil {
+		return 0, false, err
+	}
+
+	first, original, err := s.Idempotency.Claim(ctx, orgSubDetails.Organization.ID, idempotencyKey, cost)
+	if err != nil {
+		// Billing without the dedupe beats losing the usage
+		s.Logger.Error("idempotency check unavailable", err)
+	} else if !first {
+		return original, true, nil
+	}
+
+	updateUsageCounters(
+		s.CounterWorker,
+		orgSubDetails.Organization.ID,
+		orgSubDetails.Subscription.ID,
+		orgSubDetails.Endpoint.ApiEndpointID,
+		s.FlushInterval,
+		cost,
+	)
+	return cost, false, nil
+}
+
+// RecordValidatedUsage bills a request ValidateRequest has just allowed,
+// without looking the organization and subscription up a second time.
+func (s *GateKeepingService) RecordValidatedUsage(ctx context.Context, output *ValidationRequestOutput) (float64, error) {
+	cost, err := s.recordUsage(ctx, &GetOrgSubDetailsOutput{ValidationRequestOutput: *output}, 1)
+	if err != nil {
+		return 0, err
+	}
+	s.settleCredits(ctx, output.HoldID)
+	return cost, nil
+}
+
+func (s *GateKeepingService) recordUsage(ctx context.Context, orgSubDetails *GetOrgSubDetailsOutput, units float64) (float64, error) {
 	if orgSubDetails.Endpoint.AccessType != "paid" {
 		return 0, nil
 	}
 
+	effectivePricing, err := s.usageCost(ctx, orgSubDetails, units)
+	if err != nil {
+		return 0, err
+	}
+
+	updateUsageCounters(
+		s.CounterWorker,
+		orgSubDetails.Organization.ID,
+		orgSubDetails.Subscription.ID,
+		orgSubDetails.Endpoint.ApiEndpointID,
+		s.FlushInterval,
+		effectivePricing,
+	)
+
+	return effectivePricing, nil
+}
+
+// usageCost prices a call to a paid endpoint, dynamic pricing is multiplied
+// by the usage units of the call.
+func (s *GateKeepingService) usageCost(ctx context.Context, orgSubDetails *GetOrgSubDetailsOutput, units float64) (float64, error) {
+
+	pricing, err := s.getPricingFromCache(ctx, orgSubDetails)
+	if err != nil {
+		return 0, server.NewError(server.ErrorInternal, "pricing error", err)
+	}
+
+	effectivePricing := pricing.CostPerCall
+	if strings.EqualFold(pricing.CostMode, "dynamic") {
+		effectivePricing *= units
+	}
+	return effectivePricing, nil
+}
+
+func (s *GateKeepingService) getPricingFromCache(ctx context.Context, orgSubDetails *GetOrgSubDetailsOutput) (sqlcgen.GetPricingRow, error) {
+
 	pricingcacheKey := common.RedisKeyFormatter(
-		string(common.PricingPrefix), string(orgSubDetails.Subscription.ID),
-		string(common.OrganizationPrefix), string(orgSubDetails.Organization.ID),
-		string(common.EndpointPrefix), string(orgSubDetails.Endpoint.ApiEndpointID),
+		string(common.PricingPrefix), strconv.Itoa(int(orgSubDetails.Subscription.ID)),
+		string(common.OrganizationPrefix), strconv.Itoa(int(orgSubDetails.Organization.ID)),
+		string(common.EndpointPrefix), strconv.Itoa(int(orgSubDetails.Endpoint.ApiEndpointID)),
 	)
 
-	pricing, err := caching.GetFromCache(ctx, s.Cache, pricingcacheKey, func() (sqlcgen.GetPricingRow, error) {
-		val, err := s.DB.GetPricing(ctx, sqlcgen.GetPricingParams{
+	return caching.GetFromCache(ctx, s.Cache, pricingcacheKey, func() (sqlcgen.GetPricingRow, error) {
+		return s.DB.GetPricing(ctx, sqlcgen.GetPricingParams{
 			SubscriptionID: orgSubDetails.Subscription.ID,
 			ApiEndpointID:  orgSubDetails.Endpoint.ApiEndpointID,
 		})
-		return val, err
 	})
+}
+
+// checkEndpointRateLimit enforces the per subscription + endpoint limit.
+// custom_endpoint_pricing.custom_rate_limit overrides tier_base_pricing.base_rate_limit,
+// a missing pricing row or a zero limit means the endpoint is not rate limited.
+// The returned result is nil when the endpoint is not limited.
+func (s *GateKeepingService) checkEndpointRateLimit(ctx context.Context, orgSubDetails *GetOrgSubDetailsOutput) (*RateLimitResult, error) {
+
+	pricing, err := s.getPricingFromCache(ctx, orgSubDetails)
 	if err != nil {
-		return 0, server.NewError(server.ErrorInternal, "pricing error", err)
+		if errors.Is(err, pgx.ErrNoRows) {
+			return nil, nil
+		}
+		return nil, server.NewError(server.ErrorInternal, "error fetching the rate limit", err)
+	}
+	if pricing.RateLimit <= 0 {
+		return nil, nil
 	}
 
-	effectiveCalls := 1
-	if val := ctx.Value("x-effective-calls"); val != nil {
-		if intVal, ok := val.(int); ok && intVal > 0 {
-			effectiveCalls = intVal
+	limitKey := common.RedisKeyFormatter(
+		orgSubDetails.Subscription.ID,
+		orgSubDetails.Endpoint.ApiEndpointID,
+	)
+	result, err := s.RateLimiter.Allow(ctx, limitKey, pricing.RateLimit)
+	if err != nil {
+		// Fail open, an unavailable limiter should not take the gateway down
+		s.Logger.Error("rate limiter unavailable", err)
+		return nil, nil
+	}
+	result.Scope = RateLimitScopeEndpoint
+	if !result.Allowed {
+		return nil, &RateLimitError{
+			Scope:      RateLimitScopeEndpoint,
+			Limit:      result.Limit,
+			Window:     s.RateLimiter.Window,
+			RetryAfter: result.ResetIn,
 		}
 	}
-	effectivePricing := pricing.CostPerCall
-	if strings.EqualFold(pricing.CostMode, "dynamic") {
-		effectivePricing *= float64(effectiveCalls)
+	return result, nil
+}
+
+// checkOrganizationRateLimit enforces the org wide requests per second and
+// requests per minute limits from the rate_limit section of organization_config.
+// It runs before the endpoint limit so a noisy org is cut off as a whole.
+// The returned result is the tightest of the configured windows, nil if none.
+func (s *GateKeepingService) checkOrganizationRateLimit(ctx context.Context, orgSubDetails *GetOrgSubDetailsOutput) (*RateLimitResult, error) {
+
+	cfg, err := common.ParseOrganizationConfig(orgSubDetails.Organization.Config.String)
+	if err != nil {
+		s.Logger.Error("invalid organization config, skipping org rate limit", err)
+		return nil, nil
+	}
+	if cfg.RateLimit == nil {
+		return nil, nil
 	}
 
-	updateUsageCounters(s.CounterWorker, orgSubDetails, s.FlushInterval, effectivePricing)
+	windows := []struct {
+		suffix string
+		limit  int32
+		window time.Duration
+	}{
+		{"second", cfg.RateLimit.RequestsPerSecond, time.Second},
+		{"minute", cfg.RateLimit.RequestsPerMinute, time.Minute},
+	}
 
-	return effectivePricing, nil
+	var tightest *RateLimitResult
+	for _, w := range windows {
+		if w.limit <= 0 {
+			continue
+		}
+		limitKey := common.RedisKeyFormatter(
+			string(common.OrganizationPrefix),
+			strconv.Itoa(int(orgSubDetails.Organization.ID)),
+			w.suffix,
+		)
+		result, err := s.RateLimiter.AllowWindow(ctx, limitKey, w.limit, w.window)
+		if err != nil {
+			s.Logger.Error("rate limiter unavailable", err)
+			return nil, nil
+		}
+		result.Scope = RateLimitScopeOrganization
+		if !result.Allowed {
+			return nil, &RateLimitError{
+				Scope:      RateLimitScopeOrganization,
+				Limit:      result.Limit,
+				Window:     w.window,
+				RetryAfter: result.ResetIn,
+			}
+		}
+		tightest = tighterRateLimit(tightest, result)
+	}
+	return tightest, nil
+}
+
+// tighterRateLimit returns the result with the fewest requests left.
+func tighterRateLimit(a, b *RateLimitResult) *RateLimitResult {
+	if a == nil {
+		return b
+	}
+	if b == nil || a.Remaining <= b.Remaining {
+		return a
+	}
+	return b
 }
 
 func updateUsageCounters(
 	countWorker *counter.CounterWorker,
-	orgSubDetails *GetOrgSubDetailsOutput,
+	orgID, subID, endpointID int32,
 	interval int64,
 	pricing float64,
 ) {
@@ -82,11 +289,7 @@ func updateUsageCounters(
 	timestamp := common.NextIntervalUnix(time.Now(), time.Duration(interval)*time.Second)
 	timestampStr := strconv.FormatInt(timestamp, 10)
 
-	usageKey := common.UsageKey(
-		orgSubDetails.Organization.ID,
-		orgSubDetails.Subscription.ID,
-		orgSubDetails.Endpoint.ApiEndpointID,
-	)
+	usageKey := common.UsageKey(orgID, subID, endpointID)
 
 	countWorker.Increment(
 		string(common.UsagePrefix),
@@ -100,10 +303,7 @@ func updateUsageCounters(
 		1,
 	)
 
-	totalUsageKey := common.RedisKeyFormatter(
-		orgSubDetails.Organization.ID,
-		orgSubDetails.Subscription.ID,
-	)
+	totalUsageKey := common.RedisKeyFormatter(orgID, subID)
 
 	countWorker.Increment(
 		string(common.UsagePrefix),
@@ -125,12 +325,20 @@ func updateUsageCounters(
 // 4. Check organization permission details
 // 5. Get active subscription
 // 6. Check usage details
-func (s *GateKeepingService) GetOrgSubDetailsFromCache(ctx context.Context, method, path, orgName string) (*GetOrgSubDetailsOutput, error) {
+//
+// An organization in shadow mode goes through every check, a missing
+// permission, an expired subscription or an exhausted quota is logged and
+// counted but doesn't stop the request.
+func (s *GateKeepingService) GetOrgSubDetailsFromCache(ctx context.Context, req route.RouteRequest, orgName string) (*GetOrgSubDetailsOutput, error) {
+	return s.getOrgSubDetails(ctx, req, orgName, true)
+}
+
+func (s *GateKeepingService) getOrgSubDetails(ctx context.Context, req route.RouteRequest, orgName string, reportShadow bool) (*GetOrgSubDetailsOutput, error) {
 
 	// Match the endpoint with the code using cache system
-	endpointCode, found := s.Match.Match(method, path)
+	endpointCode, params, found := s.Match.MatchParams(req, nil)
 	if !found {
-		return nil, server.NewError(server.ErrorNotFound, "no matching endpoint", nil)
+		return nil, server.NewError(server.ErrorNotFound, "no matching endpoint", ErrUnknownEndpoint)
 	}
 
 	// Get the organization details
@@ -139,7 +347,7 @@ func (s *GateKeepingService) GetOrgSubDetailsFromCache(ctx context.Context, meth
 		return s.DB.GetOrganizationByName(ctx, orgName)
 	})
 	if err != nil {
-		return nil, server.NewError(server.ErrorNotFound, "organization not found", err)
+		return nil, server.NewError(server.ErrorNotFound, "organization not found", denied(ErrOrgNotFound, err))
 	}
 
 	// Get api endpoint details
@@ -148,7 +356,19 @@ func (s *GateKeepingService) GetOrgSubDetailsFromCache(ctx context.Context, meth
 		return s.DB.GetApiEndpointByName(ctx, endpointCode)
 	})
 	if err != nil {
-		return nil, server.NewError(server.ErrorNotFound, "endpoint not found", err)
+		return nil, server.NewError(server.ErrorNotFound, "endpoint not found", denied(ErrUnknownEndpoint, err))
+	}
+
+	orgSubDetails := &GetOrgSubDetailsOutput{
+		ValidationRequestOutput: ValidationRequestOutput{
+			Organization: org,
+			Endpoint:     endpoint,
+			Remaining:    -1,
+			Params:       params,
+		},
+		EndpointCode: endpointCode,
+		shadowMode:   s.enforcement(org) == common.EnforcementShadow,
+		reportShadow: reportShadow,
 	}
 
 	// Check organization permission details
@@ -165,71 +385,51 @@ func (s *GateKeepingService) GetOrgSubDetailsFromCache(ctx context.Context, meth
 		})
 	})
 	if err != nil || !orgPerExists {
-		return nil, server.NewError(server.ErrorUnauthorized, "insufficient permission", err)
+		err = server.NewError(server.ErrorUnauthorized, "insufficient permission", denied(ErrPermissionDenied, err))
+		if err := s.shadow(orgSubDetails, err); err != nil {
+			return nil, err
+		}
 	}
 
-	// Get active subscription
+	// Get the active subscription covering this endpoint. When several apply the
+	// query prefers non expired ones, then the most recently started one.
 	subKey := common.RedisKeyFormatter(
 		string(common.SubscriptionPrefix),
 		strconv.Itoa(int(org.ID)),
 		strconv.Itoa(int(endpoint.ApiEndpointID)),
 	)
 	sub, err := caching.GetFromCache(ctx, s.Cache, subKey, func() (sqlcgen.GetActiveSubscriptionRow, error) {
-		return s.DB.GetActiveSubscription(ctx, org.ID)
+		return s.DB.GetActiveSubscription(ctx, sqlcgen.GetActiveSubscriptionParams{
+			OrganizationID: org.ID,
+			ApiEndpointID:  endpoint.ApiEndpointID,
+		})
 	})
 	if err != nil || !sub.Active.Bool {
-		return nil, server.NewError(server.ErrorUnauthorized, "no active subscription", nil)
+		return nil, server.NewError(server.ErrorUnauthorized, "no active subscription", ErrNoSubscription)
 	}
+	orgSubDetails.Subscription = sub
 	if sub.ExpiryTimestamp.Int32 > 0 && time.Now().Unix() > int64(sub.ExpiryTimestamp.Int32) {
-		return nil, server.NewError(server.ErrorUnauthorized, "subscription expired", nil)
+		err := server.NewError(server.ErrorUnauthorized, "subscription expired", ErrSubscriptionExpired)
+		if err := s.shadow(orgSubDetails, err); err != nil {
+			return nil, err
+		}
 	}
 
-	// Check if the ratelimit had reached for the endpoint + subscriptionid
-	// We might need to implement a ratelimiter for this. Let's think through this
-	// I definitely do not want to have a full blown Ratelimiter implementation here
-
-	var remaining int32 = -1
+	// Check usage for the current quota period (monthly / yearly / total)
 	if sub.ApiLimit.Int32 > 0 {
-		usage, err := s.GetUsageDetailFromCache(ctx, org.ID, sub.ID, endpoint.ApiEndpointID)
+		usage, err := s.GetUsageDetailFromCache(ctx, org.ID, sub)
 		if err != nil {
 			return nil, server.NewError(server.ErrorInternal, "error fetching the total usage", err)
 		}
-		if usage >= sub.ApiLimit.Int32 {
-			return nil, server.NewError(server.ErrorUnauthorized, "quota exceeded", nil)
+		if usage >= float64(sub.ApiLimit.Int32) {
+			err := server.NewError(server.ErrorUnauthorized, "quota exceeded", quotaExceeded(sub, ""))
+			if err := s.shadow(orgSubDetails, err); err != nil {
+				return nil, err
+			}
 		}
-		remaining = max(sub.ApiLimit.Int32-usage, 0)
+		orgSubDetails.Remaining = max(sub.ApiLimit.Int32-int32(usage), 0)
+		orgSubDetails.QuotaUsed = usage
 	}
 
-	return &GetOrgSubDetailsOutput{
-		ValidationRequestOutput: ValidationRequestOutput{
-			Organization: org,
-			Endpoint:     endpoint,
-			Subscription: sub,
-			Remaining:    remaining,
-		},
-		EndpointCode: endpointCode,
-	}, nil
-}
-
-func (s *GateKeepingService) GetUsageDetailFromCache(ctx context.Context, orgId, subId, endpointId int32) (int32, error) {
-
-	timestamp := common.NextIntervalUnix(time.Now(), time.Duration(s.FlushInterval)*time.Second)
-	timestampStr := strconv.FormatInt(timestamp, 10)
-
-	usageRedisKey := common.RedisKeyFormatter(
-		string(common.UsagePrefix), string(orgId), string(subId),
-		timestampStr, string(common.TotalCostPrefix),
-	)
-
-	usage, err := caching.GetFromCache(ctx, s.Cache, usageRedisKey, func() (int32, error) {
-		orgUsageDetails, err := s.DB.GetQuotaUsageBySubscriptionID(ctx, subId)
-		if err != nil {
-			return 0, server.NewError(server.ErrorInternal, "error fetching usage details", err)
-		}
-		return orgUsageDetails.CostsUsed, nil
-	})
-	if err != nil {
-		return 0, err
-	}
-	return usage, nil
+	return orgSubDetails, nil
 }
@@ -0,0 +1,57 @@
+package gatekeeping
+
+import (
+	"context"
+	"errors"
+	"strconv"
+	"time"
+
+	"github.com/bignyap/go-admin/internal/common"
+	"github.com/redis/go-redis/v9"
+)
+
+// DefaultIdempotencyWindow is how long a recorded idempotency key is
+// remembered, a retry arriving later is billed again.
+const DefaultIdempotencyWindow = 24 * time.Hour
+
+// IdempotencyStore remembers the cost charged for each idempotency key so a
+// retried RecordUsage returns it instead of billing the call twice. The keys
+// live in Redis, so a retry landing on another replica is caught too.
+type IdempotencyStore struct {
+	Redis  redis.UniversalClient
+	Window time.Duration
+}
+
+func NewIdempotencyStore(client redis.UniversalClient, window time.Duration) *IdempotencyStore {
+	if window <= 0 {
+		window = DefaultIdempotencyWindow
+	}
+	return &IdempotencyStore{Redis: client, Window: window}
+}
+
+// Claim records cost under the key of the organization. It returns false and
+// the cost charged the first time when the key was already claimed.
+func (r *IdempotencyStore) Claim(ctx context.Context, orgID int32, key string, cost float64) (bool, float64, error) {
+	if r == nil || r.Redis == nil || key == "" {
+		return true, cost, nil
+	}
+
+	redisKey := common.RedisKeyFormatter(string(common.IdempotencyPrefix), strconv.Itoa(int(orgID)), key)
+	claimed, err := r.Redis.SetNX(ctx, redisKey, strconv.FormatFloat(cost, 'f', -1, 64), r.Window).Result()
+	if err != nil {
+		return false, 0, err
+	}
+	if claimed {
+		return true, cost, nil
+	}
+
+	original, err := r.Redis.Get(ctx, redisKey).Float64()
+	if errors.Is(err, redis.Nil) {
+		// Expired between the two calls, the window is over anyway
+		return r.Claim(ctx, orgID, key, cost)
+	}
+	if err != nil {
+		return false, 0, err
+	}
+	return false, original, nil
+}
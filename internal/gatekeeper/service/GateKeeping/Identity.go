@@ -0,0 +1,298 @@
+package gatekeeping
+
+import (
+	"crypto/x509"
+	"encoding/base64"
+	"encoding/pem"
+	"fmt"
+	"net"
+	"net/url"
+	"os"
+	"strings"
+)
+
+// Names accepted in ORG_EXTRACTORS.
+const (
+	OrgExtractorSubdomain  = "subdomain"
+	OrgExtractorPathPrefix = "path-prefix"
+	OrgExtractorHeader     = "header"
+	OrgExtractorClientCert = "client-cert"
+)
+
+const DefaultOrgPathPrefix = "/t/"
+
+// IdentityRequest is the part of a request the extractors look at. Each
+// transport fills it from what it has (gin request, gRPC metadata, Envoy
+// CheckRequest).
+type IdentityRequest struct {
+	Host string
+	// Stripped of the tenant segment by the path-prefix extractor
+	Path   string
+	Header func(name string) string
+	// Verified client certificates, when TLS ends at GateKeeper
+	PeerCertificates []*x509.Certificate
+}
+
+func (r *IdentityRequest) header(name string) string {
+	if r.Header == nil {
+		return ""
+	}
+	return strings.TrimSpace(r.Header(name))
+}
+
+// OrgExtractor finds the organization (realm) a request is made for.
+type OrgExtractor interface {
+	// Extract returns the organization, "" when the request doesn't carry it
+	Extract(req *IdentityRequest) string
+}
+
+// OrgIdentity runs the configured extractors in priority order, the first
+// one that finds an organization wins.
+type OrgIdentity struct {
+	Extractors []OrgExtractor
+}
+
+type OrgIdentityConfig struct {
+	// Extractor names in priority order, see the OrgExtractor* constants
+	Extractors    []string
+	Header        string
+	SubdomainBase string
+	PathPrefix    string
+	CertField     string
+	CertHeader    string
+	// PEM file of the CAs the certificates of CertHeader must chain to
+	CertCA string
+}
+
+func NewOrgIdentity(cfg OrgIdentityConfig) (*OrgIdentity, error) {
+
+	identity := &OrgIdentity{}
+	for _, name := range cfg.Extractors {
+		switch strings.ToLower(strings.TrimSpace(name)) {
+		case OrgExtractorSubdomain:
+			identity.Extractors = append(identity.Extractors, &SubdomainExtractor{BaseDomain: cfg.SubdomainBase})
+		case OrgExtractorPathPrefix:
+			identity.Extractors = append(identity.Extractors, NewPathPrefixExtractor(cfg.PathPrefix))
+		case OrgExtractorHeader:
+			header := cfg.Header
+			if header == "" {
+				header = DefaultOrgHeader
+			}
+			identity.Extractors = append(identity.Extractors, &HeaderExtractor{Header: header})
+		case OrgExtractorClientCert:
+			extractor, err := NewClientCertExtractor(cfg.CertField, cfg.CertHeader, cfg.CertCA)
+			if err != nil {
+				return nil, err
+			}
+			identity.Extractors = append(identity.Extractors, extractor)
+		case "":
+		default:
+			return nil, fmt.Errorf("unknown organization extractor %q", name)
+		}
+	}
+	return identity, nil
+}
+
+// Identify returns the organization of the request, "" if no extractor found
+// one. Every extractor runs, even after a match, so the path-prefix one still
+// strips the tenant segment when the organization came from elsewhere.
+func (o *OrgIdentity) Identify(req *IdentityRequest) string {
+	if o == nil {
+		return ""
+	}
+	org := ""
+	for _, extractor := range o.Extractors {
+		found := extractor.Extract(req)
+		if org == "" {
+			org = found
+		}
+	}
+	return org
+}
+
+// SubdomainExtractor takes the label left of BaseDomain, acme for
+// acme.api.example.com and a base of api.example.com. Without a base the
+// first label of hosts with at least three labels is used.
+type SubdomainExtractor struct {
+	BaseDomain string
+}
+
+func (e *SubdomainExtractor) Extract(req *IdentityRequest) string {
+
+	host := strings.ToLower(req.Host)
+	if h, _, err := net.SplitHostPort(host); err == nil {
+		host = h
+	}
+	if host == "" || net.ParseIP(host) != nil {
+		return ""
+	}
+
+	base := strings.Trim(strings.ToLower(e.BaseDomain), ".")
+	if base == "" {
+		labels := strings.Split(host, ".")
+		if len(labels) < 3 {
+			return ""
+		}
+		return labels[0]
+	}
+
+	sub, ok := strings.CutSuffix(host, "."+base)
+	if !ok || sub == "" {
+		return ""
+	}
+	// Only the label right before the base, a.b.api.example.com is b
+	if i := strings.LastIndexByte(sub, '.'); i >= 0 {
+		sub = sub[i+1:]
+	}
+	return sub
+}
+
+// PathPrefixExtractor reads the tenant from /t/<org>/... and strips that
+// segment so the Matcher sees the path the endpoint was registered with.
+type PathPrefixExtractor struct {
+	Prefix string
+}
+
+func NewPathPrefixExtractor(prefix string) *PathPrefixExtractor {
+	if prefix == "" {
+		prefix = DefaultOrgPathPrefix
+	}
+	return &PathPrefixExtractor{Prefix: "/" + strings.Trim(prefix, "/") + "/"}
+}
+
+func (e *PathPrefixExtractor) Extract(req *IdentityRequest) string {
+
+	rest, ok := strings.CutPrefix(req.Path, e.Prefix)
+	if !ok {
+		return ""
+	}
+	org, path, _ := strings.Cut(rest, "/")
+	if org == "" {
+		return ""
+	}
+	req.Path = "/" + path
+	return org
+}
+
+type HeaderExtractor struct {
+	Header string
+}
+
+func (e *HeaderExtractor) Extract(req *IdentityRequest) string {
+	return req.header(e.Header)
+}
+
+// ClientCertExtractor reads the organization from a subject field of the
+// client certificate: cn (default), o or ou.
+type ClientCertExtractor struct {
+	Field string
+	// Header holding the client certificate when TLS ends at the proxy,
+	// either URL escaped PEM (NGINX $ssl_client_escaped_cert) or base64 DER
+	// (Traefik). Only the verified TLS peer is used when empty
+	Header string
+	// CAs a certificate read from Header must chain to, anyone can send
+	// a self-signed one
+	Roots *x509.CertPool
+}
+
+func NewClientCertExtractor(field, header, caFile string) (*ClientCertExtractor, error) {
+	field = strings.ToLower(field)
+	switch field {
+	case "":
+		field = "cn"
+	case "cn", "o", "ou":
+	default:
+		return nil, fmt.Errorf("unknown client certificate field %q, expected cn, o or ou", field)
+	}
+
+	extractor := &ClientCertExtractor{Field: field, Header: header}
+	if header == "" {
+		return extractor, nil
+	}
+	if caFile == "" {
+		return nil, fmt.Errorf("a client certificate header needs the CAs to verify it against")
+	}
+	pemCerts, err := os.ReadFile(caFile)
+	if err != nil {
+		return nil, fmt.Errorf("couldn't read the client certificate CAs: %w", err)
+	}
+	extractor.Roots = x509.NewCertPool()
+	if !extractor.Roots.AppendCertsFromPEM(pemCerts) {
+		return nil, fmt.Errorf("no certificate found in %s", caFile)
+	}
+	return extractor, nil
+}
+
+func (e *ClientCertExtractor) Extract(req *IdentityRequest) string {
+
+	var cert *x509.Certificate
+	if len(req.PeerCertificates) > 0 {
+		cert = req.PeerCertificates[0]
+	} else if e.Header != "" {
+		cert = e.verifiedHeaderCertificate(req)
+	}
+	if cert == nil {
+		return ""
+	}
+
+	var values []string
+	switch e.Field {
+	case "o":
+		values = cert.Subject.Organization
+	case "ou":
+		values = cert.Subject.OrganizationalUnit
+	default:
+		values = []string{cert.Subject.CommonName}
+	}
+	if len(values) == 0 {
+		return ""
+	}
+	return values[0]
+}
+
+// verifiedHeaderCertificate returns the certificate forwarded in Header if it
+// chains to Roots, nil otherwise.
+func (e *ClientCertExtractor) verifiedHeaderCertificate(req *IdentityRequest) *x509.Certificate {
+
+	cert := ParseForwardedCertificate(req.header(e.Header))
+	if cert == nil {
+		return nil
+	}
+	_, err := cert.Verify(x509.VerifyOptions{
+		Roots:     e.Roots,
+		KeyUsages: []x509.ExtKeyUsage{x509.ExtKeyUsageClientAuth},
+	})
+	if err != nil {
+		return nil
+	}
+	return cert
+}
+
+// ParseForwardedCertificate decodes a certificate forwarded by a proxy that
+// terminated TLS, URL escaped PEM or base64 DER. It returns nil if the value
+// is neither.
+func ParseForwardedCertificate(value string) *x509.Certificate {
+
+	if value == "" {
+		return nil
+	}
+	if unescaped, err := url.PathUnescape(value); err == nil {
+		value = unescaped
+	}
+
+	der := []byte(nil)
+	if block, _ := pem.Decode([]byte(value)); block != nil {
+		der = block.Bytes
+	} else if decoded, err := base64.StdEncoding.DecodeString(value); err == nil {
+		der = decoded
+	}
+	if der == nil {
+		return nil
+	}
+
+	cert, err := x509.ParseCertificate(der)
+	if err != nil {
+		return nil
+	}
+	return cert
+}
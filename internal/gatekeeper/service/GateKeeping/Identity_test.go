@@ -0,0 +1,294 @@
+package gatekeeping
+
+import (
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"encoding/base64"
+	"encoding/pem"
+	"math/big"
+	"net/http"
+	"net/url"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+)
+
+func TestSubdomainExtractor(t *testing.T) {
+	tests := []struct {
+		base string
+		host string
+		want string
+	}{
+		{"api.example.com", "acme.api.example.com", "acme"},
+		{"api.example.com", "ACME.api.example.com:8443", "acme"},
+		{"api.example.com", "a.acme.api.example.com", "acme"},
+		{".api.example.com.", "acme.api.example.com", "acme"},
+		{"api.example.com", "api.example.com", ""},
+		{"api.example.com", "acme.other.com", ""},
+		{"api.example.com", "acmeapi.example.com", ""},
+		{"", "acme.api.example.com", "acme"},
+		{"", "example.com", ""},
+		{"", "10.0.0.1", ""},
+		{"", "[::1]:8080", ""},
+		{"", "", ""},
+	}
+
+	for _, tt := range tests {
+		extractor := &SubdomainExtractor{BaseDomain: tt.base}
+		if got := extractor.Extract(&IdentityRequest{Host: tt.host}); got != tt.want {
+			t.Errorf("base %q, host %q: got %q, want %q", tt.base, tt.host, got, tt.want)
+		}
+	}
+}
+
+func TestPathPrefixExtractor(t *testing.T) {
+	tests := []struct {
+		prefix   string
+		path     string
+		want     string
+		wantPath string
+	}{
+		{"", "/t/acme/users/1", "acme", "/users/1"},
+		{"", "/t/acme", "acme", "/"},
+		{"", "/t/acme/", "acme", "/"},
+		{"", "/t//users", "", "/t//users"},
+		{"", "/users/1", "", "/users/1"},
+		{"", "/tenant/acme", "", "/tenant/acme"},
+		{"orgs", "/orgs/acme/users", "acme", "/users"},
+		{"/orgs/", "/orgs/acme/users", "acme", "/users"},
+	}
+
+	for _, tt := range tests {
+		req := &IdentityRequest{Path: tt.path}
+		got := NewPathPrefixExtractor(tt.prefix).Extract(req)
+		if got != tt.want || req.Path != tt.wantPath {
+			t.Errorf("prefix %q, path %q: got %q, %q, want %q, %q", tt.prefix, tt.path, got, req.Path, tt.want, tt.wantPath)
+		}
+	}
+}
+
+func TestHeaderExtractor(t *testing.T) {
+	header := http.Header{}
+	header.Set("X-Org", "  acme ")
+	extractor := &HeaderExtractor{Header: "X-Org"}
+
+	if got := extractor.Extract(&IdentityRequest{Header: header.Get}); got != "acme" {
+		t.Errorf("got %q, want acme", got)
+	}
+	if got := extractor.Extract(&IdentityRequest{}); got != "" {
+		t.Errorf("got %q without headers", got)
+	}
+}
+
+func TestOrgIdentity(t *testing.T) {
+	header := http.Header{}
+	header.Set(DefaultOrgHeader, "from-header")
+
+	tests := []struct {
+		name       string
+		extractors []string
+		host       string
+		path       string
+		want       string
+		wantPath   string
+	}{
+		{"first one wins", []string{"header", "path-prefix"}, "", "/t/from-path/users", "from-header", "/users"},
+		{"priority order", []string{"path-prefix", "header"}, "", "/t/from-path/users", "from-path", "/users"},
+		{"falls through", []string{"subdomain", "header"}, "localhost", "/users", "from-header", "/users"},
+		{"names normalized", []string{" Subdomain ", ""}, "acme.api.example.com", "/users", "acme", "/users"},
+		{"none", nil, "acme.api.example.com", "/users", "", "/users"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			identity, err := NewOrgIdentity(OrgIdentityConfig{Extractors: tt.extractors})
+			if err != nil {
+				t.Fatal(err)
+			}
+			req := &IdentityRequest{Host: tt.host, Path: tt.path, Header: header.Get}
+			if got := identity.Identify(req); got != tt.want || req.Path != tt.wantPath {
+				t.Errorf("got %q, %q, want %q, %q", got, req.Path, tt.want, tt.wantPath)
+			}
+		})
+	}
+
+	if _, err := NewOrgIdentity(OrgIdentityConfig{Extractors: []string{"cookie"}}); err == nil {
+		t.Error("an unknown extractor was accepted")
+	}
+	var none *OrgIdentity
+	if got := none.Identify(&IdentityRequest{}); got != "" {
+		t.Errorf("nil identity found %q", got)
+	}
+}
+
+type testCA struct {
+	cert *x509.Certificate
+	key  *ecdsa.PrivateKey
+}
+
+func newTestCA(t *testing.T) *testCA {
+	t.Helper()
+	key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	if err != nil {
+		t.Fatal(err)
+	}
+	template := &x509.Certificate{
+		SerialNumber:          big.NewInt(1),
+		Subject:               pkix.Name{CommonName: "test ca"},
+		NotBefore:             time.Now().Add(-time.Hour),
+		NotAfter:              time.Now().Add(time.Hour),
+		IsCA:                  true,
+		BasicConstraintsValid: true,
+		KeyUsage:              x509.KeyUsageCertSign,
+	}
+	der, err := x509.CreateCertificate(rand.Reader, template, template, &key.PublicKey, key)
+	if err != nil {
+		t.Fatal(err)
+	}
+	cert, err := x509.ParseCertificate(der)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return &testCA{cert: cert, key: key}
+}
+
+func (ca *testCA) issue(t *testing.T, subject pkix.Name) *x509.Certificate {
+	t.Helper()
+	key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	if err != nil {
+		t.Fatal(err)
+	}
+	template := &x509.Certificate{
+		SerialNumber: big.NewInt(2),
+		Subject:      subject,
+		NotBefore:    time.Now().Add(-time.Hour),
+		NotAfter:     time.Now().Add(time.Hour),
+		ExtKeyUsage:  []x509.ExtKeyUsage{x509.ExtKeyUsageClientAuth},
+	}
+	der, err := x509.CreateCertificate(rand.Reader, template, ca.cert, &key.PublicKey, ca.key)
+	if err != nil {
+		t.Fatal(err)
+	}
+	cert, err := x509.ParseCertificate(der)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return cert
+}
+
+func (ca *testCA) writePEM(t *testing.T) string {
+	t.Helper()
+	file := filepath.Join(t.TempDir(), "ca.pem")
+	if err := os.WriteFile(file, pemCertificate(ca.cert), 0o600); err != nil {
+		t.Fatal(err)
+	}
+	return file
+}
+
+func pemCertificate(cert *x509.Certificate) []byte {
+	return pem.EncodeToMemory(&pem.Block{Type: "CERTIFICATE", Bytes: cert.Raw})
+}
+
+func TestParseForwardedCertificate(t *testing.T) {
+	cert := newTestCA(t).issue(t, pkix.Name{CommonName: "acme"})
+
+	tests := []struct {
+		name  string
+		value string
+		want  bool
+	}{
+		{"escaped PEM", url.PathEscape(string(pemCertificate(cert))), true},
+		{"PEM", string(pemCertificate(cert)), true},
+		{"base64 DER", base64.StdEncoding.EncodeToString(cert.Raw), true},
+		{"empty", "", false},
+		{"garbage", "not a certificate", false},
+		{"base64 of garbage", base64.StdEncoding.EncodeToString([]byte("nope")), false},
+	}
+
+	for _, tt := range tests {
+		got := ParseForwardedCertificate(tt.value)
+		if (got != nil) != tt.want {
+			t.Errorf("%s: got %v, want a certificate %v", tt.name, got, tt.want)
+		}
+		if got != nil && got.Subject.CommonName != "acme" {
+			t.Errorf("%s: common name %q", tt.name, got.Subject.CommonName)
+		}
+	}
+}
+
+func TestClientCertExtractor(t *testing.T) {
+	ca := newTestCA(t)
+	caFile := ca.writePEM(t)
+	cert := ca.issue(t, pkix.Name{CommonName: "acme", Organization: []string{"acme-o"}, OrganizationalUnit: []string{"acme-ou"}})
+	bare := ca.issue(t, pkix.Name{CommonName: "bare"})
+	forged := newTestCA(t).issue(t, pkix.Name{CommonName: "forged"})
+
+	tests := []struct {
+		name   string
+		field  string
+		header string
+		peers  []*x509.Certificate
+		sent   *x509.Certificate
+		want   string
+	}{
+		{"common name", "", "", []*x509.Certificate{cert}, nil, "acme"},
+		{"organization", "O", "", []*x509.Certificate{cert}, nil, "acme-o"},
+		{"organizational unit", "ou", "", []*x509.Certificate{cert}, nil, "acme-ou"},
+		{"field missing", "o", "", []*x509.Certificate{bare}, nil, ""},
+		{"no certificate", "", "", nil, nil, ""},
+		{"header ignored without config", "", "", nil, cert, ""},
+		{"forwarded", "", "X-Client-Cert", nil, cert, "acme"},
+		{"forwarded from another CA", "", "X-Client-Cert", nil, forged, ""},
+		{"peer preferred", "", "X-Client-Cert", []*x509.Certificate{bare}, cert, "bare"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			file := ""
+			if tt.header != "" {
+				file = caFile
+			}
+			extractor, err := NewClientCertExtractor(tt.field, tt.header, file)
+			if err != nil {
+				t.Fatal(err)
+			}
+			header := http.Header{}
+			if tt.sent != nil {
+				header.Set("X-Client-Cert", url.PathEscape(string(pemCertificate(tt.sent))))
+			}
+			req := &IdentityRequest{PeerCertificates: tt.peers, Header: header.Get}
+			if got := extractor.Extract(req); got != tt.want {
+				t.Errorf("got %q, want %q", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestNewClientCertExtractorErrors(t *testing.T) {
+	empty := filepath.Join(t.TempDir(), "empty.pem")
+	if err := os.WriteFile(empty, []byte("no certificates"), 0o600); err != nil {
+		t.Fatal(err)
+	}
+
+	tests := []struct {
+		name   string
+		field  string
+		header string
+		caFile string
+	}{
+		{"unknown field", "email", "", ""},
+		{"header without CAs", "", "X-Client-Cert", ""},
+		{"missing CA file", "", "X-Client-Cert", filepath.Join(t.TempDir(), "missing.pem")},
+		{"CA file without certificates", "", "X-Client-Cert", empty},
+	}
+
+	for _, tt := range tests {
+		if _, err := NewClientCertExtractor(tt.field, tt.header, tt.caFile); err == nil {
+			t.Errorf("%s: no error", tt.name)
+		}
+	}
+}
@@ -0,0 +1,236 @@
+package gatekeeping
+
+import (
+	"context"
+	"crypto"
+	"crypto/ecdsa"
+	"crypto/ed25519"
+	"crypto/elliptic"
+	"crypto/rsa"
+	"encoding/base64"
+	"encoding/json"
+	"fmt"
+	"io"
+	"math/big"
+	"net/http"
+	"os"
+	"strings"
+	"sync"
+	"time"
+)
+
+const DefaultJWKSRefreshInterval = 10 * time.Minute
+
+// An unknown kid usually means the IdP rotated its keys, the set is then
+// fetched again but never more often than this.
+const jwksMinRefetchInterval = 30 * time.Second
+
+// JWKSCache keeps the signing keys of every realm in memory. A source is
+// either an http(s) URL or a local file path (optionally prefixed by file://).
+type JWKSCache struct {
+	Client          *http.Client
+	RefreshInterval time.Duration
+
+	lock sync.RWMutex
+	sets map[string]*keySet
+}
+
+type keySet struct {
+	keys      map[string]crypto.PublicKey
+	fetchedAt time.Time
+}
+
+type jsonWebKey struct {
+	Kty string `json:"kty"`
+	Kid string `json:"kid"`
+	Use string `json:"use"`
+	Crv string `json:"crv"`
+	N   string `json:"n"`
+	E   string `json:"e"`
+	X   string `json:"x"`
+	Y   string `json:"y"`
+}
+
+func NewJWKSCache(refreshInterval time.Duration) *JWKSCache {
+	if refreshInterval <= 0 {
+		refreshInterval = DefaultJWKSRefreshInterval
+	}
+	return &JWKSCache{
+		Client:          &http.Client{Timeout: 5 * time.Second},
+		RefreshInterval: refreshInterval,
+		sets:            make(map[string]*keySet),
+	}
+}
+
+// Key returns the public key identified by kid in the given source. Stale sets
+// are refreshed, and a missing kid triggers a refetch to pick up rotated keys.
+func (c *JWKSCache) Key(ctx context.Context, source, kid string) (crypto.PublicKey, error) {
+
+	c.lock.RLock()
+	set := c.sets[source]
+	c.lock.RUnlock()
+
+	now := time.Now()
+	if set == nil || now.Sub(set.fetchedAt) > c.RefreshInterval {
+		fresh, err := c.load(ctx, source)
+		if err != nil {
+			if set == nil {
+				return nil, err
+			}
+			// Keep serving the previous keys if the IdP is briefly unreachable
+		} else {
+			set = fresh
+		}
+	}
+
+	if key, ok := set.lookup(kid); ok {
+		return key, nil
+	}
+
+	if now.Sub(set.fetchedAt) > jwksMinRefetchInterval {
+		fresh, err := c.load(ctx, source)
+		if err != nil {
+			return nil, err
+		}
+		if key, ok := fresh.lookup(kid); ok {
+			return key, nil
+		}
+	}
+
+	return nil, fmt.Errorf("jwks: no key with kid %q in %s", kid, source)
+}
+
+func (c *JWKSCache) load(ctx context.Context, source string) (*keySet, error) {
+
+	raw, err := c.read(ctx, source)
+	if err != nil {
+		return nil, fmt.Errorf("jwks: couldn't load %s: %w", source, err)
+	}
+
+	set, err := parseJWKS(raw)
+	if err != nil {
+		return nil, fmt.Errorf("jwks: couldn't parse %s: %w", source, err)
+	}
+
+	c.lock.Lock()
+	c.sets[source] = set
+	c.lock.Unlock()
+	return set, nil
+}
+
+func (c *JWKSCache) read(ctx context.Context, source string) ([]byte, error) {
+
+	if !strings.HasPrefix(source, "http://") && !strings.HasPrefix(source, "https://") {
+		return os.ReadFile(strings.TrimPrefix(source, "file://"))
+	}
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, source, nil)
+	if err != nil {
+		return nil, err
+	}
+	resp, err := c.Client.Do(req)
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		return nil, fmt.Errorf("unexpected status %d", resp.StatusCode)
+	}
+	return io.ReadAll(io.LimitReader(resp.Body, 1<<20))
+}
+
+func (s *keySet) lookup(kid string) (crypto.PublicKey, bool) {
+	if kid == "" && len(s.keys) == 1 {
+		for _, key := range s.keys {
+			return key, true
+		}
+	}
+	key, ok := s.keys[kid]
+	return key, ok
+}
+
+func parseJWKS(raw []byte) (*keySet, error) {
+
+	var doc struct {
+		Keys []jsonWebKey `json:"keys"`
+	}
+	if err := json.Unmarshal(raw, &doc); err != nil {
+		return nil, err
+	}
+
+	set := &keySet{keys: make(map[string]crypto.PublicKey), fetchedAt: time.Now()}
+	for _, jwk := range doc.Keys {
+		if jwk.Use != "" && jwk.Use != "sig" {
+			continue
+		}
+		key, err := jwk.publicKey()
+		if err != nil {
+			// Unsupported key types are skipped, the rest of the set is still usable
+			continue
+		}
+		set.keys[jwk.Kid] = key
+	}
+	if len(set.keys) == 0 {
+		return nil, fmt.Errorf("no usable signing keys")
+	}
+	return set, nil
+}
+
+func (k jsonWebKey) publicKey() (crypto.PublicKey, error) {
+	switch k.Kty {
+	case "RSA":
+		n, err := decodeBigInt(k.N)
+		if err != nil {
+			return nil, err
+		}
+		e, err := decodeBigInt(k.E)
+		if err != nil {
+			return nil, err
+		}
+		return &rsa.PublicKey{N: n, E: int(e.Int64())}, nil
+	case "EC":
+		var curve elliptic.Curve
+		switch k.Crv {
+		case "P-256":
+			curve = elliptic.P256()
+		case "P-384":
+			curve = elliptic.P384()
+		case "P-521":
+			curve = elliptic.P521()
+		default:
+			return nil, fmt.Errorf("unsupported curve %q", k.Crv)
+		}
+		x, err := decodeBigInt(k.X)
+		if err != nil {
+			return nil, err
+		}
+		y, err := decodeBigInt(k.Y)
+		if err != nil {
+			return nil, err
+		}
+		return &ecdsa.PublicKey{Curve: curve, X: x, Y: y}, nil
+	case "OKP":
+		if k.Crv != "Ed25519" {
+			return nil, fmt.Errorf("unsupported curve %q", k.Crv)
+		}
+		x, err := base64.RawURLEncoding.DecodeString(k.X)
+		if err != nil {
+			return nil, err
+		}
+		if len(x) != ed25519.PublicKeySize {
+			return nil, fmt.Errorf("invalid ed25519 key size")
+		}
+		return ed25519.PublicKey(x), nil
+	default:
+		return nil, fmt.Errorf("unsupported key type %q", k.Kty)
+	}
+}
+
+func decodeBigInt(value string) (*big.Int, error) {
+	b, err := base64.RawURLEncoding.DecodeString(strings.TrimRight(value, "="))
+	if err != nil {
+		return nil, err
+	}
+	return new(big.Int).SetBytes(b), nil
+}
@@ -0,0 +1,193 @@
+package gatekeeping
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"slices"
+	"strings"
+	"time"
+
+	"github.com/bignyap/go-admin/internal/caching"
+	"github.com/bignyap/go-admin/internal/common"
+	"github.com/bignyap/go-admin/internal/database/sqlcgen"
+	"github.com/bignyap/go-utilities/server"
+	"github.com/golang-jwt/jwt/v5"
+)
+
+// ErrInvalidToken is wrapped by every bearer JWT failure, like ErrInvalidApiKey
+// it is answered with 401.
+var ErrInvalidToken = errors.New("invalid bearer token")
+
+const jwtLeeway = 30 * time.Second
+
+var jwtSigningMethods = []string{
+	"RS256", "RS384", "RS512",
+	"PS256", "PS384", "PS512",
+	"ES256", "ES384", "ES512",
+	"EdDSA",
+}
+
+// JWTConfig holds the GateKeeper wide defaults, each organization can override
+// the issuer, audience and JWKS source through the auth section of its config.
+type JWTConfig struct {
+	// Claim carrying the realm. When empty the realm is taken from the issuer,
+	// the segment after /realms/ for Keycloak style issuers.
+	RealmClaim string
+	// JWKS source used when the organization has none, {realm} is replaced
+	// by the realm, e.g. https://idp/realms/{realm}/protocol/openid-connect/certs
+	JWKSURLTemplate string
+	Audience        []string
+}
+
+type JWTValidator struct {
+	Config JWTConfig
+	JWKS   *JWKSCache
+}
+
+// TokenIdentity is what a validated token tells about the caller.
+type TokenIdentity struct {
+	Realm   string
+	Subject string
+	Scopes  []string
+}
+
+func NewJWTValidator(cfg JWTConfig, refreshInterval time.Duration) *JWTValidator {
+	return &JWTValidator{
+		Config: cfg,
+		JWKS:   NewJWKSCache(refreshInterval),
+	}
+}
+
+// ResolveBearerToken validates a JWT issued by an organization's identity
+// provider. The realm is read from the token first, it selects the
+// organization and with it the JWKS, issuer and audience the token is checked
+// against.
+func (s *GateKeepingService) ResolveBearerToken(ctx context.Context, token string) (*TokenIdentity, error) {
+
+	if s.JWT == nil {
+		return nil, invalidToken(fmt.Errorf("jwt validation is not configured"))
+	}
+	cfg := s.JWT.Config
+
+	unverified := jwt.MapClaims{}
+	if _, _, err := jwt.NewParser().ParseUnverified(token, unverified); err != nil {
+		return nil, invalidToken(err)
+	}
+	realm := realmFromClaims(unverified, cfg.RealmClaim)
+	if realm == "" {
+		return nil, invalidToken(fmt.Errorf("no realm in token"))
+	}
+
+	orgKey := common.RedisKeyFormatter(string(common.OrganizationPrefix), realm)
+	org, err := caching.GetFromCache(ctx, s.Cache, orgKey, func() (sqlcgen.GetOrganizationByNameRow, error) {
+		return s.DB.GetOrganizationByName(ctx, realm)
+	})
+	if err != nil {
+		return nil, invalidToken(fmt.Errorf("unknown realm %q", realm))
+	}
+
+	orgCfg, err := common.ParseOrganizationConfig(org.Config.String)
+	if err != nil {
+		return nil, invalidToken(err)
+	}
+	auth := orgCfg.Auth
+	if auth == nil {
+		auth = &common.OrganizationAuth{}
+	}
+
+	source := auth.JWKSFile
+	if source == "" {
+		source = auth.JWKSURL
+	}
+	if source == "" && cfg.JWKSURLTemplate != "" {
+		source = strings.ReplaceAll(cfg.JWKSURLTemplate, "{realm}", realm)
+	}
+	if source == "" {
+		return nil, invalidToken(fmt.Errorf("no jwks configured for realm %q", realm))
+	}
+
+	opts := []jwt.ParserOption{
+		jwt.WithValidMethods(jwtSigningMethods),
+		jwt.WithExpirationRequired(),
+		jwt.WithLeeway(jwtLeeway),
+	}
+	if auth.Issuer != "" {
+		opts = append(opts, jwt.WithIssuer(auth.Issuer))
+	}
+
+	claims := jwt.MapClaims{}
+	_, err = jwt.NewParser(opts...).ParseWithClaims(token, claims, func(t *jwt.Token) (interface{}, error) {
+		kid, _ := t.Header["kid"].(string)
+		return s.JWT.JWKS.Key(ctx, source, kid)
+	})
+	if err != nil {
+		return nil, invalidToken(err)
+	}
+
+	audience := auth.Audience
+	if len(audience) == 0 {
+		audience = cfg.Audience
+	}
+	if len(audience) > 0 {
+		tokenAud, _ := claims.GetAudience()
+		if !slices.ContainsFunc(tokenAud, func(aud string) bool { return slices.Contains(audience, aud) }) {
+			return nil, invalidToken(fmt.Errorf("token audience %v not accepted", tokenAud))
+		}
+	}
+
+	subject, _ := claims.GetSubject()
+	return &TokenIdentity{
+		Realm:   realm,
+		Subject: subject,
+		Scopes:  scopesFromClaims(claims),
+	}, nil
+}
+
+func invalidToken(err error) error {
+	return server.NewError(server.ErrorUnauthorized, "invalid bearer token", fmt.Errorf("%w: %v", ErrInvalidToken, err))
+}
+
+func realmFromClaims(claims jwt.MapClaims, realmClaim string) string {
+	if realmClaim != "" {
+		realm, _ := claims[realmClaim].(string)
+		return realm
+	}
+	issuer, _ := claims.GetIssuer()
+	if _, realm, found := strings.Cut(issuer, "/realms/"); found {
+		realm, _, _ = strings.Cut(realm, "/")
+		return realm
+	}
+	return issuer
+}
+
+// scopesFromClaims reads the OAuth2 "scope" claim (space separated) and the
+// "scp" claim some providers use instead (string or array).
+func scopesFromClaims(claims jwt.MapClaims) []string {
+	scopes := []string{}
+	for _, name := range []string{"scope", "scp"} {
+		switch v := claims[name].(type) {
+		case string:
+			scopes = append(scopes, strings.Fields(v)...)
+		case []interface{}:
+			for _, item := range v {
+				if str, ok := item.(string); ok {
+					scopes = append(scopes, str)
+				}
+			}
+		}
+	}
+	return scopes
+}
+
+// hasScope tells whether the token grants the permission the endpoint needs.
+// A scope matches either the bare permission code or <resource type name>:<code>.
+func hasScope(scopes []string, resourceType string, permissionCode string) bool {
+	qualified := resourceType + ":" + permissionCode
+	for _, scope := range scopes {
+		if strings.EqualFold(scope, permissionCode) || strings.EqualFold(scope, qualified) {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,33 @@
+package gatekeeping
+
+import (
+	"time"
+
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/promauto"
+)
+
+// Endpoint changes reach the Matcher over pub/sub, it is also reconciled
+// with the database this often in case a message was lost
+const DefaultMatcherReconcileInterval = time.Minute
+
+// Metrics of the matcher reconciliation, served in the Prometheus format on
+// /gatekeeper/metrics.
+var (
+	MatcherDrift = promauto.NewGauge(prometheus.GaugeOpts{
+		Name: "gatekeeper_matcher_drift_endpoints",
+		Help: "Endpoints missing, extra or changed in the matcher at the last reconciliation.",
+	})
+	MatcherDriftTotal = promauto.NewCounter(prometheus.CounterOpts{
+		Name: "gatekeeper_matcher_drift_endpoints_total",
+		Help: "Endpoints the reconciliation had to fix in the matcher.",
+	})
+	MatcherReconcileFailures = promauto.NewCounter(prometheus.CounterOpts{
+		Name: "gatekeeper_matcher_reconcile_failures_total",
+		Help: "Reconciliations that couldn't read the endpoints.",
+	})
+	MatcherReconciledAt = promauto.NewGauge(prometheus.GaugeOpts{
+		Name: "gatekeeper_matcher_last_reconcile_timestamp_seconds",
+		Help: "Unix time of the last reconciliation of the matcher.",
+	})
+)
@@ -1,11 +1,8 @@
 package gatekeeping
 
 import (
-	"net/http"
-	"sync"
-
+	"github.com/bignyap/go-admin/internal/common/route"
 	"github.com/bignyap/go-admin/internal/database/sqlcgen"
-	"github.com/julienschmidt/httprouter"
 )
 
 type Organization struct {
@@ -31,28 +28,28 @@ type Pricing struct {
 	CostPerCall float64
 }
 
-type Endpoint struct {
-	Code   string `json:"code" form:"code"`
-	Method string `json:"method" form:"method"`
-	Path   string `json:"path" form:"path"`
-}
-
-type Matcher struct {
-	router    *httprouter.Router
-	lock      sync.RWMutex
-	endpoints map[string]Endpoint
-}
-
-type capture struct {
-	header http.Header
-	code   string
-	found  bool
-}
-
 type ValidateRequestInput struct {
 	Method           string `json:"method" form:"method"`
 	Path             string `json:"path" form:"path"`
 	OrganizationName string `json:"organization_name" form:"organization_name"`
+	// Host of the gated request, X-Forwarded-Host when empty
+	Host string `json:"host" form:"host"`
+	// Headers of the gated request, matched against the header matchers of
+	// the endpoints
+	Header func(string) string `json:"-" form:"-"`
+	// Scopes of the caller's bearer token, nil when the caller didn't use one
+	Scopes []string `json:"-" form:"-"`
+	// The cost of the request is held against the quota until its usage is
+	// recorded, so concurrent requests can't all pass on the last credits.
+	// Set by callers that never record the usage with the hold_id
+	SkipHold bool `json:"skip_hold" form:"skip_hold"`
+	// Expected usage units, sizes the hold of dynamic pricing. Dynamic
+	// pricing takes no hold without them
+	UsageUnits *float64 `json:"usage_units" form:"usage_units"`
+}
+
+func (i *ValidateRequestInput) Route() route.RouteRequest {
+	return route.RouteRequest{Method: i.Method, Host: i.Host, Path: i.Path, Header: i.Header}
 }
 
 type ValidationRequestOutput struct {
@@ -60,15 +57,61 @@ type ValidationRequestOutput struct {
 	Endpoint     sqlcgen.GetApiEndpointByNameRow  `json:"endpoint"`
 	Subscription sqlcgen.GetActiveSubscriptionRow `json:"subscription"`
 	Remaining    int32                            `json:"remaining"` // nil if unlimited
+	// Tightest rate limit that applied to the request, nil if none did
+	RateLimit *RateLimitResult `json:"rate_limit,omitempty"`
+	// Credits held for the request, send it back with the usage so the hold
+	// is settled
+	HoldID string `json:"hold_id,omitempty"`
+	// Reasons the request would have been denied for, when the organization
+	// is in shadow mode
+	ShadowDenials []DenialCode `json:"shadow_denials,omitempty"`
+	// Path parameters of the endpoint template with the values they matched
+	Params route.Params `json:"-"`
 }
 
 type RecordUsageInput struct {
 	Method           string `json:"method" form:"method"`
 	Path             string `json:"path" form:"path"`
 	OrganizationName string `json:"organization_name" form:"organization_name"`
+	// Host and headers of the recorded request, see ValidateRequestInput
+	Host   string              `json:"host" form:"host"`
+	Header func(string) string `json:"-" form:"-"`
+	// hold_id returned by the validation, settles its credit hold
+	HoldID string `json:"hold_id" form:"hold_id"`
+	// Retries with the same key are only billed once, the Idempotency-Key
+	// header is used when empty
+	IdempotencyKey string `json:"idempotency_key" form:"idempotency_key"`
+	// Multiplies the cost of dynamic pricing, e.g. the tokens of an LLM call.
+	// A call counts as one unit when nil
+	UsageUnits *float64 `json:"usage_units" form:"usage_units"`
+}
+
+func (i *RecordUsageInput) Route() route.RouteRequest {
+	return route.RouteRequest{Method: i.Method, Host: i.Host, Path: i.Path, Header: i.Header}
+}
+
+type ValidateAndRecordOutput struct {
+	ValidationRequestOutput
+	ReservationToken string  `json:"reservation_token"`
+	Cost             float64 `json:"cost"`
+	ExpiresAt        int64   `json:"expires_at"`
+}
+
+type ReservationInput struct {
+	ReservationToken string `json:"reservation_token" form:"reservation_token"`
+	// Usage units of the call, only read by commit. The reserved cost is for
+	// one unit
+	UsageUnits *float64 `json:"usage_units" form:"usage_units"`
 }
 
 type GetOrgSubDetailsOutput struct {
 	ValidationRequestOutput
 	EndpointCode string `json:"endpoint_code"`
+	// Credits recorded in the current quota period, 0 if the subscription is unlimited
+	QuotaUsed float64 `json:"-"`
+
+	shadowMode bool
+	// Whether shadow denials are logged and counted, a recorded request
+	// already had them reported when it was validated
+	reportShadow bool
 }
@@ -0,0 +1,131 @@
+package gatekeeping
+
+import (
+	"context"
+	"errors"
+	"strconv"
+	"time"
+
+	"github.com/bignyap/go-admin/internal/caching"
+	"github.com/bignyap/go-admin/internal/common"
+	"github.com/bignyap/go-admin/internal/database/sqlcgen"
+	"github.com/bignyap/go-utilities/server"
+	"github.com/redis/go-redis/v9"
+)
+
+const (
+	QuotaResetMonthly = "monthly"
+	QuotaResetYearly  = "yearly"
+	QuotaResetTotal   = "total"
+)
+
+// Number of flush buckets looked at when summing the usage that is still in
+// Redis. The periodic flush clears the bucket every interval, so anything
+// older than a couple of intervals has already been synced to the DB.
+const pendingUsageBuckets = 3
+
+// QuotaPeriodStart returns the unix time the current quota period started,
+// following subscription.subscription_quota_reset_interval. Periods are
+// calendar based (UTC), 'total' (or unset) never resets.
+func QuotaPeriodStart(interval string, now time.Time) int64 {
+	now = now.UTC()
+	switch interval {
+	case QuotaResetMonthly:
+		return time.Date(now.Year(), now.Month(), 1, 0, 0, 0, 0, time.UTC).Unix()
+	case QuotaResetYearly:
+		return time.Date(now.Year(), time.January, 1, 0, 0, 0, 0, time.UTC).Unix()
+	default:
+		return 0
+	}
+}
+
+// QuotaPeriodEnd returns when the current quota period resets, 0 if never.
+func QuotaPeriodEnd(interval string, now time.Time) int64 {
+	now = now.UTC()
+	switch interval {
+	case QuotaResetMonthly:
+		return time.Date(now.Year(), now.Month()+1, 1, 0, 0, 0, 0, time.UTC).Unix()
+	case QuotaResetYearly:
+		return time.Date(now.Year()+1, time.January, 1, 0, 0, 0, 0, time.UTC).Unix()
+	default:
+		return 0
+	}
+}
+
+// GetUsageDetailFromCache returns the cost consumed by the subscription in the
+// current quota period. It combines the usage already synced to the DB with
+// the counters still waiting in Redis for the next flush.
+func (s *GateKeepingService) GetUsageDetailFromCache(ctx context.Context, orgId int32, sub sqlcgen.GetActiveSubscriptionRow) (float64, error) {
+
+	now := time.Now()
+	interval := time.Duration(s.FlushInterval) * time.Second
+	periodStart := QuotaPeriodStart(sub.QuotaResetInterval.String, now)
+	timestamp := common.NextIntervalUnix(now, interval)
+
+	// The DB part only changes when a flush happens, so it is cached per flush bucket
+	quotaKey := common.RedisKeyFormatter(
+		string(common.QuotaPrefix),
+		strconv.Itoa(int(sub.ID)),
+		strconv.FormatInt(periodStart, 10),
+		strconv.FormatInt(timestamp, 10),
+	)
+	dbUsage, err := caching.GetFromCache(ctx, s.Cache, quotaKey, func() (float64, error) {
+		usage, err := s.DB.GetSubscriptionUsageSince(ctx, sqlcgen.GetSubscriptionUsageSinceParams{
+			SubscriptionID: sub.ID,
+			UsageStartDate: int32(periodStart),
+		})
+		if err != nil {
+			return 0, server.NewError(server.ErrorInternal, "error fetching usage details", err)
+		}
+		return usage.CostsUsed, nil
+	})
+	if err != nil {
+		return 0, err
+	}
+
+	return dbUsage + s.pendingUsage(ctx, orgId, sub.ID, timestamp, interval), nil
+}
+
+// pendingUsage sums the totalcost counters of the last few flush buckets that
+// have not been synced to the DB yet.
+func (s *GateKeepingService) pendingUsage(ctx context.Context, orgId, subId int32, timestamp int64, interval time.Duration) float64 {
+
+	redisClient := s.Cache.Redis()
+	if redisClient == nil {
+		return 0
+	}
+
+	keys := make([]string, 0, pendingUsageBuckets)
+	for i := 0; i < pendingUsageBuckets; i++ {
+		ts := timestamp - int64(i)*int64(interval.Seconds())
+		keys = append(keys, common.RedisKeyFormatter(
+			string(common.UsagePrefix),
+			strconv.Itoa(int(orgId)),
+			strconv.Itoa(int(subId)),
+			strconv.FormatInt(ts, 10),
+			string(common.TotalCostPrefix),
+		))
+	}
+
+	// One GET per bucket, the keys hash to different slots on Redis Cluster
+	// and MGET would fail with CROSSSLOT
+	cmds := make([]*redis.StringCmd, len(keys))
+	_, err := redisClient.Pipelined(ctx, func(pipe redis.Pipeliner) error {
+		for i, key := range keys {
+			cmds[i] = pipe.Get(ctx, key)
+		}
+		return nil
+	})
+	if err != nil && !errors.Is(err, redis.Nil) {
+		s.Logger.Error("couldn't read pending usage from redis", err)
+		return 0
+	}
+
+	var total float64
+	for _, cmd := range cmds {
+		if f, err := cmd.Float64(); err == nil {
+			total += f
+		}
+	}
+	return total
+}
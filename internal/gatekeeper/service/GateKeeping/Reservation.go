@@ -0,0 +1,214 @@
+package gatekeeping
+
+import (
+	"context"
+	"crypto/rand"
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/bignyap/go-admin/internal/common"
+	"github.com/bignyap/go-utilities/server"
+	"github.com/redis/go-redis/v9"
+)
+
+// DefaultReservationTTL is how long a reservation, or a credit hold, waits for
+// its commit. One that is neither committed nor cancelled in time expires and
+// is never billed.
+const DefaultReservationTTL = 5 * time.Minute
+
+// ErrReservationNotFound is returned for unknown, expired or already settled
+// reservation tokens.
+var ErrReservationNotFound = errors.New("reservation not found")
+
+// Reservation is what ValidateAndRecord remembers about an allowed request so
+// the commit can bill it without going through the lookups again.
+type Reservation struct {
+	OrganizationID int32   `json:"organization_id"`
+	SubscriptionID int32   `json:"subscription_id"`
+	EndpointID     int32   `json:"endpoint_id"`
+	Cost           float64 `json:"cost"`
+	// False for endpoints that aren't paid, committing them is a no-op
+	Billable bool `json:"billable"`
+	// Dynamic pricing, the commit multiplies Cost by the usage units
+	Dynamic   bool   `json:"dynamic"`
+	HoldID    string `json:"hold_id,omitempty"`
+	ExpiresAt int64  `json:"expires_at"`
+}
+
+// ReservationStore keeps the pending reservations and credit holds in Redis,
+// so a token issued by one replica can be committed or cancelled through any
+// other.
+type ReservationStore struct {
+	Redis redis.UniversalClient
+	TTL   time.Duration
+	// How long a settled credit hold keeps counting, see Settle
+	SettleDelay time.Duration
+}
+
+func NewReservationStore(client redis.UniversalClient, ttl, settleDelay time.Duration) *ReservationStore {
+	if ttl <= 0 {
+		ttl = DefaultReservationTTL
+	}
+	return &ReservationStore{Redis: client, TTL: ttl, SettleDelay: settleDelay}
+}
+
+// Save stores the reservation under a new random token and returns the token.
+func (r *ReservationStore) Save(ctx context.Context, reservation *Reservation) (string, error) {
+	if r == nil || r.Redis == nil {
+		return "", fmt.Errorf("reservations need redis")
+	}
+
+	tokenBytes := make([]byte, 24)
+	if _, err := rand.Read(tokenBytes); err != nil {
+		return "", err
+	}
+	token := base64.RawURLEncoding.EncodeToString(tokenBytes)
+
+	reservation.ExpiresAt = time.Now().Add(r.TTL).Unix()
+	value, err := json.Marshal(reservation)
+	if err != nil {
+		return "", err
+	}
+	if err := r.Redis.Set(ctx, reservationKey(token), value, r.TTL).Err(); err != nil {
+		return "", err
+	}
+	return token, nil
+}
+
+// Take removes the reservation and returns it. Getting and deleting in one
+// command makes sure a token is only ever settled once.
+func (r *ReservationStore) Take(ctx context.Context, token string) (*Reservation, error) {
+	if r == nil || r.Redis == nil {
+		return nil, fmt.Errorf("reservations need redis")
+	}
+
+	value, err := r.Redis.GetDel(ctx, reservationKey(token)).Bytes()
+	if errors.Is(err, redis.Nil) {
+		return nil, ErrReservationNotFound
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	var reservation Reservation
+	if err := json.Unmarshal(value, &reservation); err != nil {
+		return nil, err
+	}
+	return &reservation, nil
+}
+
+// Delete drops the reservation without billing it.
+func (r *ReservationStore) Delete(ctx context.Context, token string) error {
+	if r == nil || r.Redis == nil {
+		return fmt.Errorf("reservations need redis")
+	}
+
+	deleted, err := r.Redis.Del(ctx, reservationKey(token)).Result()
+	if err != nil {
+		return err
+	}
+	if deleted == 0 {
+		return ErrReservationNotFound
+	}
+	return nil
+}
+
+func reservationKey(token string) string {
+	return common.RedisKeyFormatter(string(common.ReservationPrefix), token)
+}
+
+// ValidateAndRecord validates the request and reserves its cost in a single
+// call. The returned token is then committed once the backend has answered,
+// or cancelled if the call should not be billed.
+func (s *GateKeepingService) ValidateAndRecord(ctx context.Context, input *ValidateRequestInput) (*ValidateAndRecordOutput, error) {
+
+	// The reservation settles or releases the hold
+	input.SkipHold = false
+	output, err := s.ValidateRequest(ctx, input)
+	if err != nil {
+		return nil, err
+	}
+
+	reservation := &Reservation{
+		OrganizationID: output.Organization.ID,
+		SubscriptionID: output.Subscription.ID,
+		EndpointID:     output.Endpoint.ApiEndpointID,
+		Billable:       output.Endpoint.AccessType == "paid",
+		HoldID:         output.HoldID,
+	}
+	if reservation.Billable {
+		details := &GetOrgSubDetailsOutput{ValidationRequestOutput: *output}
+		reservation.Cost, err = s.usageCost(ctx, details, 1)
+		if err != nil {
+			return nil, err
+		}
+		// usageCost just loaded the pricing, this is a cache hit
+		if pricing, err := s.getPricingFromCache(ctx, details); err == nil {
+			reservation.Dynamic = strings.EqualFold(pricing.CostMode, "dynamic")
+		}
+	}
+
+	token, err := s.Reservations.Save(ctx, reservation)
+	if err != nil {
+		s.releaseCredits(ctx, output.HoldID)
+		return nil, server.NewError(server.ErrorInternal, "couldn't reserve the request cost", err)
+	}
+
+	return &ValidateAndRecordOutput{
+		ValidationRequestOutput: *output,
+		ReservationToken:        token,
+		Cost:                    reservation.Cost,
+		ExpiresAt:               reservation.ExpiresAt,
+	}, nil
+}
+
+// CommitReservation bills the reserved request and returns its cost. For
+// dynamic pricing the reserved cost is that of one unit, it is multiplied by
+// the usage units given to the commit.
+func (s *GateKeepingService) CommitReservation(ctx context.Context, input *ReservationInput) (float64, error) {
+
+	reservation, err := s.Reservations.Take(ctx, input.ReservationToken)
+	if err != nil {
+		return 0, reservationError(err)
+	}
+	if !reservation.Billable {
+		return 0, nil
+	}
+	if reservation.Dynamic && input.UsageUnits != nil {
+		reservation.Cost *= *input.UsageUnits
+	}
+
+	defer s.settleCredits(ctx, reservation.HoldID)
+	updateUsageCounters(
+		s.CounterWorker,
+		reservation.OrganizationID,
+		reservation.SubscriptionID,
+		reservation.EndpointID,
+		s.FlushInterval,
+		reservation.Cost,
+	)
+
+	return reservation.Cost, nil
+}
+
+// CancelReservation releases the reservation, and its credit hold, without
+// billing it.
+func (s *GateKeepingService) CancelReservation(ctx context.Context, input *ReservationInput) error {
+	reservation, err := s.Reservations.Take(ctx, input.ReservationToken)
+	if err != nil {
+		return reservationError(err)
+	}
+	s.releaseCredits(ctx, reservation.HoldID)
+	return nil
+}
+
+func reservationError(err error) error {
+	if errors.Is(err, ErrReservationNotFound) {
+		return server.NewError(server.ErrorNotFound, "reservation not found or expired", err)
+	}
+	return server.NewError(server.ErrorInternal, "couldn't settle the reservation", err)
+}
@@ -1,7 +1,10 @@
 package gatekeeping
 
 import (
+	"sync"
+
 	"github.com/bignyap/go-admin/internal/caching"
+	"github.com/bignyap/go-admin/internal/common/route"
 	"github.com/bignyap/go-admin/internal/database/sqlcgen"
 	"github.com/bignyap/go-utilities/counter"
 	"github.com/bignyap/go-utilities/logger/api"
@@ -16,6 +19,15 @@ type GateKeepingService struct {
 	Logger        api.Logger
 	Validator     *validator.Validate
 	Cache         *caching.CacheController
-	Match         *Matcher
+	Match         *route.Matcher
 	CounterWorker *counter.CounterWorker
+	RateLimiter   *RateLimiter
+	Reservations  *ReservationStore
+	Idempotency   *IdempotencyStore
+	JWT           *JWTValidator
+	Identity      *OrgIdentity
+	// enforce or shadow, the organization config can override it
+	Enforcement string
+
+	apiKeyLastUsed sync.Map
 }
@@ -0,0 +1,77 @@
+package gatekeeping
+
+import (
+	"slices"
+	"strconv"
+	"time"
+
+	"github.com/bignyap/go-admin/internal/common"
+	"github.com/bignyap/go-admin/internal/database/sqlcgen"
+	"github.com/bignyap/go-utilities/logger/api"
+)
+
+// shadowReason is the code err is counted under, empty when it can't be
+// shadowed. A request that doesn't resolve to an organization, an endpoint
+// and a subscription is always denied, there would be nothing to bill.
+func shadowReason(err error) DenialCode {
+	switch code := denialCode(err); code {
+	case DenialPermissionDenied, DenialInsufficientScope, DenialSubscriptionExpired, DenialQuotaExceeded, DenialRateLimited:
+		return code
+	}
+	return ""
+}
+
+// enforcement is the mode of the organization, the enforcement key of its
+// config wins over the one of the deployment.
+func (s *GateKeepingService) enforcement(org sqlcgen.GetOrganizationByNameRow) string {
+	cfg, err := common.ParseOrganizationConfig(org.Config.String)
+	if err == nil && cfg.Enforcement != "" {
+		return cfg.Enforcement
+	}
+	if s.Enforcement == "" {
+		return common.EnforcementEnforce
+	}
+	return s.Enforcement
+}
+
+// shadow returns err unless the organization is in shadow mode and the
+// denial can be shadowed. The denial is then logged, counted and listed in
+// the shadow denials of the output, and the request goes on.
+func (s *GateKeepingService) shadow(orgSubDetails *GetOrgSubDetailsOutput, err error) error {
+
+	if !orgSubDetails.shadowMode {
+		return err
+	}
+	reason := shadowReason(err)
+	if reason == "" {
+		return err
+	}
+	if slices.Contains(orgSubDetails.ShadowDenials, reason) {
+		return nil
+	}
+	orgSubDetails.ShadowDenials = append(orgSubDetails.ShadowDenials, reason)
+	if !orgSubDetails.reportShadow {
+		return nil
+	}
+
+	s.Logger.Warn("shadow denial, request allowed",
+		api.Field{Key: "reason", Value: reason},
+		api.Field{Key: "organization", Value: orgSubDetails.Organization.Name},
+		api.Field{Key: "endpoint", Value: orgSubDetails.EndpointCode},
+		api.Field{Key: "error", Value: err.Error()},
+	)
+
+	timestamp := common.NextIntervalUnix(time.Now(), time.Duration(s.FlushInterval)*time.Second)
+	s.CounterWorker.Increment(
+		string(common.ShadowPrefix),
+		common.RedisKeyFormatter(
+			strconv.Itoa(int(orgSubDetails.Organization.ID)),
+			strconv.Itoa(int(orgSubDetails.Endpoint.ApiEndpointID)),
+			string(reason),
+			strconv.FormatInt(timestamp, 10),
+			string(common.CountPrefix),
+		),
+		1,
+	)
+	return nil
+}
@@ -0,0 +1,194 @@
+package gatekeeping
+
+import (
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"net/http"
+	"net/url"
+	"slices"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/bignyap/go-admin/internal/common/route"
+	"github.com/bignyap/go-admin/internal/database/sqlcgen"
+)
+
+// Headers the proxy sets on the requests it forwards, so the upstream knows
+// who the caller is. Any the client sent are dropped first.
+const (
+	GatariHeaderPrefix         = "X-Gatari-"
+	GatariOrgIDHeader          = "X-Gatari-Org-Id"
+	GatariSubscriptionIDHeader = "X-Gatari-Subscription-Id"
+	GatariEndpointCodeHeader   = "X-Gatari-Endpoint-Code"
+	// Credits left on the subscription, -1 when it is unlimited
+	GatariRemainingHeader = "X-Gatari-Remaining"
+	GatariTimestampHeader = "X-Gatari-Timestamp"
+	GatariSignatureHeader = "X-Gatari-Signature"
+)
+
+// UpstreamTransform is how the proxy changes a request before sending it to
+// the upstream.
+type UpstreamTransform struct {
+	// Drop the API key and Authorization header of the caller
+	StripCredentials bool
+	// Cut from the start of the path, then AddPrefix is put in front of it
+	StripPrefix string
+	AddPrefix   string
+}
+
+var DefaultUpstreamTransform = UpstreamTransform{StripCredentials: true}
+
+func upstreamTransform(upstream sqlcgen.Upstream) UpstreamTransform {
+	return UpstreamTransform{
+		StripCredentials: upstream.UpstreamStripCredentials,
+		StripPrefix:      upstream.UpstreamStripPrefix.String,
+		AddPrefix:        upstream.UpstreamAddPrefix.String,
+	}
+}
+
+// RequestSigner signs the X-Gatari-* headers with an HMAC-SHA256 of
+// PROXY_SIGNING_SECRET, so an upstream reachable by other means than the
+// proxy can tell they are genuine. The signature covers the timestamp, the
+// method, path and query sent upstream and the identity headers, see
+// signaturePayload.
+type RequestSigner struct {
+	secret []byte
+}
+
+// NewRequestSigner returns nil when there is no secret, the identity headers
+// are then not sent at all.
+func NewRequestSigner(secret string) *RequestSigner {
+	if secret == "" {
+		return nil
+	}
+	return &RequestSigner{secret: []byte(secret)}
+}
+
+// Sign returns the value of the X-Gatari-Signature header.
+func (s *RequestSigner) Sign(header http.Header, method, path, rawQuery string) string {
+	mac := hmac.New(sha256.New, s.secret)
+	mac.Write([]byte(signaturePayload(header, method, path, rawQuery)))
+	return "v2=" + hex.EncodeToString(mac.Sum(nil))
+}
+
+// signaturePayload is, one per line: the timestamp, the method, the path, the
+// raw query, the organization ID, the subscription ID, the endpoint code and
+// the remaining credits.
+func signaturePayload(header http.Header, method, path, rawQuery string) string {
+	return strings.Join([]string{
+		header.Get(GatariTimestampHeader),
+		method,
+		path,
+		rawQuery,
+		header.Get(GatariOrgIDHeader),
+		header.Get(GatariSubscriptionIDHeader),
+		header.Get(GatariEndpointCodeHeader),
+		header.Get(GatariRemainingHeader),
+	}, "\n")
+}
+
+// Transform readies a validated request for its upstream. path is the path
+// as matched, without the tenant segment. It goes through the rewrite of the
+// endpoint, then the prefixes of the upstream. The path is handled escaped,
+// so a %2F the client sent reaches the upstream as %2F rather than a '/'.
+func (r *UpstreamRouter) Transform(req *http.Request, upstream *Upstream, path string, output *ValidationRequestOutput) {
+
+	endpoint := output.Endpoint
+	requestPath := req.URL.EscapedPath()
+	escaped := escapedSuffix(requestPath, path)
+	if endpoint.PathRewrite.String != "" {
+		escaped = rewritePath(endpoint.PathRewrite.String, output.Params, requestPath)
+	}
+	transform := upstream.Transform
+	if transform.StripPrefix != "" {
+		if rest, ok := strings.CutPrefix(escaped, escapePath(transform.StripPrefix)); ok && (rest == "" || rest[0] == '/') {
+			escaped = rest
+		}
+	}
+	escaped = escapePath(transform.AddPrefix) + escaped
+	if escaped == "" {
+		escaped = "/"
+	}
+	path, err := url.PathUnescape(escaped)
+	if err != nil {
+		path = escaped
+	}
+	req.URL.Path = path
+	req.URL.RawPath = escaped
+
+	if transform.StripCredentials {
+		req.Header.Del(ApiKeyHeader)
+		req.Header.Del("Authorization")
+	}
+
+	for name := range req.Header {
+		if strings.HasPrefix(name, GatariHeaderPrefix) {
+			delete(req.Header, name)
+		}
+	}
+	// Unsigned identity headers could be forged by anyone reaching the
+	// upstream directly
+	if r.Signer == nil {
+		return
+	}
+	req.Header.Set(GatariOrgIDHeader, strconv.Itoa(int(output.Organization.ID)))
+	req.Header.Set(GatariSubscriptionIDHeader, strconv.Itoa(int(output.Subscription.ID)))
+	req.Header.Set(GatariEndpointCodeHeader, endpoint.EndpointName)
+	req.Header.Set(GatariRemainingHeader, strconv.Itoa(int(output.Remaining)))
+	req.Header.Set(GatariTimestampHeader, strconv.FormatInt(time.Now().Unix(), 10))
+	req.Header.Set(GatariSignatureHeader, r.Signer.Sign(req.Header, req.Method, path, req.URL.RawQuery))
+}
+
+// rewritePath fills the :name and *name segments of the rewrite template with
+// the path parameters the endpoint template matched. A :name value is escaped
+// as one segment, a *name one keeps the escaping of requestPath, the escaped
+// path of the request.
+func rewritePath(rewrite string, params route.Params, requestPath string) string {
+
+	segments := strings.Split(rewrite, "/")
+	for i, segment := range segments {
+		if !strings.HasPrefix(segment, ":") && !strings.HasPrefix(segment, "*") {
+			segments[i] = url.PathEscape(segment)
+			continue
+		}
+		j := slices.IndexFunc(params, func(p route.Param) bool { return p.Key == segment[1:] })
+		switch {
+		case j < 0:
+			segments[i] = url.PathEscape(segment)
+		case segment[0] == ':':
+			segments[i] = url.PathEscape(params[j].Value)
+		default:
+			segments[i] = escapedSuffix(requestPath, "/"+params[j].Value)[1:]
+		}
+	}
+	return strings.Join(segments, "/")
+}
+
+// escapedSuffix is the end of the escaped path that decodes to suffix, or
+// suffix escaped segment by segment when the path doesn't end with it.
+func escapedSuffix(escapedPath, suffix string) string {
+	for i := len(escapedPath) - 1; i >= 0; i-- {
+		if escapedPath[i] != '/' {
+			continue
+		}
+		tail, err := url.PathUnescape(escapedPath[i:])
+		if err != nil || len(tail) > len(suffix) {
+			break
+		}
+		if tail == suffix {
+			return escapedPath[i:]
+		}
+	}
+	return escapePath(suffix)
+}
+
+// escapePath escapes each segment of path.
+func escapePath(path string) string {
+	segments := strings.Split(path, "/")
+	for i, segment := range segments {
+		segments[i] = url.PathEscape(segment)
+	}
+	return strings.Join(segments, "/")
+}
@@ -0,0 +1,221 @@
+package gatekeeping
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/bignyap/go-admin/internal/common/route"
+	"github.com/jackc/pgx/v5/pgtype"
+)
+
+func TestRewritePath(t *testing.T) {
+	tests := []struct {
+		rewrite     string
+		params      route.Params
+		requestPath string
+		want        string
+	}{
+		{"/v2/users/:id", route.Params{{Key: "id", Value: "42"}}, "/users/42", "/v2/users/42"},
+		{"/v2/users/:id", route.Params{{Key: "id", Value: "a/b"}}, "/users/a%2Fb", "/v2/users/a%2Fb"},
+		{"/v2/users/:id", route.Params{{Key: "id", Value: "a b"}}, "/users/a%20b", "/v2/users/a%20b"},
+		{"/:b/:a", route.Params{{Key: "a", Value: "1"}, {Key: "b", Value: "2"}}, "/1/2", "/2/1"},
+		{"/files/*path", route.Params{{Key: "path", Value: "a/b/c"}}, "/static/a/b%2Fc", "/files/a/b%2Fc"},
+		{"/files/*path", route.Params{{Key: "path", Value: "a/b"}}, "/static/a/b", "/files/a/b"},
+		{"/x/:missing", nil, "/x", "/x/:missing"},
+		{"/a b/:id", route.Params{{Key: "id", Value: "1"}}, "/1", "/a%20b/1"},
+		{"/static", route.Params{{Key: "id", Value: "1"}}, "/1", "/static"},
+	}
+
+	for _, tt := range tests {
+		if got := rewritePath(tt.rewrite, tt.params, tt.requestPath); got != tt.want {
+			t.Errorf("rewritePath(%q, %v, %q) = %q, want %q", tt.rewrite, tt.params, tt.requestPath, got, tt.want)
+		}
+	}
+}
+
+func TestEscapedSuffix(t *testing.T) {
+	tests := []struct {
+		escapedPath string
+		suffix      string
+		want        string
+	}{
+		{"/a/b", "/b", "/b"},
+		{"/a/b", "/a/b", "/a/b"},
+		{"/a/b%2Fc", "/b/c", "/b%2Fc"},
+		{"/a/b%20c", "/b c", "/b%20c"},
+		{"/a/b", "/c", "/c"},
+		{"/a/b", "/c d", "/c%20d"},
+		{"/a/%zz", "/x", "/x"},
+		{"/a/b", "", ""},
+	}
+
+	for _, tt := range tests {
+		if got := escapedSuffix(tt.escapedPath, tt.suffix); got != tt.want {
+			t.Errorf("escapedSuffix(%q, %q) = %q, want %q", tt.escapedPath, tt.suffix, got, tt.want)
+		}
+	}
+}
+
+func TestEscapePath(t *testing.T) {
+	tests := []struct {
+		path string
+		want string
+	}{
+		{"", ""},
+		{"/", "/"},
+		{"/a/b", "/a/b"},
+		{"/a b/c", "/a%20b/c"},
+		{"/a?b", "/a%3Fb"},
+	}
+
+	for _, tt := range tests {
+		if got := escapePath(tt.path); got != tt.want {
+			t.Errorf("escapePath(%q) = %q, want %q", tt.path, got, tt.want)
+		}
+	}
+}
+
+func TestSign(t *testing.T) {
+	header := http.Header{}
+	header.Set(GatariTimestampHeader, "1700000000")
+	header.Set(GatariOrgIDHeader, "1")
+	header.Set(GatariSubscriptionIDHeader, "2")
+	header.Set(GatariEndpointCodeHeader, "users")
+	header.Set(GatariRemainingHeader, "-1")
+
+	want := "1700000000\nGET\n/users\na=1\n1\n2\nusers\n-1"
+	if got := signaturePayload(header, http.MethodGet, "/users", "a=1"); got != want {
+		t.Errorf("signaturePayload = %q, want %q", got, want)
+	}
+
+	signer := NewRequestSigner("secret")
+	signature := signer.Sign(header, http.MethodGet, "/users", "a=1")
+	if !strings.HasPrefix(signature, "v2=") {
+		t.Errorf("signature %q has no version", signature)
+	}
+	if again := signer.Sign(header, http.MethodGet, "/users", "a=1"); again != signature {
+		t.Errorf("signing twice gave %q and %q", signature, again)
+	}
+
+	changed := header.Clone()
+	changed.Set(GatariOrgIDHeader, "3")
+	others := map[string]string{
+		"query":    signer.Sign(header, http.MethodGet, "/users", "a=2"),
+		"no query": signer.Sign(header, http.MethodGet, "/users", ""),
+		"method":   signer.Sign(header, http.MethodDelete, "/users", "a=1"),
+		"path":     signer.Sign(header, http.MethodGet, "/admins", "a=1"),
+		"header":   signer.Sign(changed, http.MethodGet, "/users", "a=1"),
+		"secret":   NewRequestSigner("other").Sign(header, http.MethodGet, "/users", "a=1"),
+	}
+	for name, other := range others {
+		if other == signature {
+			t.Errorf("changing the %s kept the signature", name)
+		}
+	}
+
+	if NewRequestSigner("") != nil {
+		t.Error("a signer without a secret")
+	}
+}
+
+func newTestOutput(rewrite string, params route.Params) *ValidationRequestOutput {
+	output := &ValidationRequestOutput{Params: params, Remaining: -1}
+	output.Organization.ID = 1
+	output.Subscription.ID = 2
+	output.Endpoint.EndpointName = "users"
+	output.Endpoint.PathRewrite = pgtype.Text{String: rewrite, Valid: rewrite != ""}
+	return output
+}
+
+func TestTransformPath(t *testing.T) {
+	tests := []struct {
+		name      string
+		transform UpstreamTransform
+		rewrite   string
+		params    route.Params
+		target    string
+		path      string
+		want      string
+	}{
+		{"unchanged", UpstreamTransform{}, "", nil, "/acme/users/1", "/users/1", "/users/1"},
+		{"query kept", UpstreamTransform{}, "", nil, "/acme/users?a=1", "/users", "/users"},
+		{"escaped slash kept", UpstreamTransform{}, "", nil, "/acme/files/a%2Fb", "/files/a/b", "/files/a%2Fb"},
+		{"prefix stripped", UpstreamTransform{StripPrefix: "/api"}, "", nil, "/acme/api/users", "/api/users", "/users"},
+		{"only whole segments stripped", UpstreamTransform{StripPrefix: "/api"}, "", nil, "/acme/apiv2", "/apiv2", "/apiv2"},
+		{"whole path stripped", UpstreamTransform{StripPrefix: "/api"}, "", nil, "/acme/api", "/api", "/"},
+		{"prefix added", UpstreamTransform{AddPrefix: "/v1"}, "", nil, "/acme/users", "/users", "/v1/users"},
+		{"prefix replaced", UpstreamTransform{StripPrefix: "/api", AddPrefix: "/v1"}, "", nil, "/acme/api/users", "/api/users", "/v1/users"},
+		{"rewritten", UpstreamTransform{}, "/v2/people/:id", route.Params{{Key: "id", Value: "a/b"}}, "/acme/users/a%2Fb", "/users/a/b", "/v2/people/a%2Fb"},
+		{"rewritten then prefixed", UpstreamTransform{AddPrefix: "/v1"}, "/people/:id", route.Params{{Key: "id", Value: "1"}}, "/acme/users/1", "/users/1", "/v1/people/1"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			req := httptest.NewRequest(http.MethodGet, tt.target, nil)
+			query := req.URL.RawQuery
+			router := &UpstreamRouter{}
+			router.Transform(req, &Upstream{Transform: tt.transform}, tt.path, newTestOutput(tt.rewrite, tt.params))
+
+			if got := req.URL.EscapedPath(); got != tt.want {
+				t.Errorf("path = %q, want %q", got, tt.want)
+			}
+			if req.URL.RawQuery != query {
+				t.Errorf("query = %q, want %q", req.URL.RawQuery, query)
+			}
+		})
+	}
+}
+
+func TestTransformHeaders(t *testing.T) {
+	newRequest := func() *http.Request {
+		req := httptest.NewRequest(http.MethodGet, "/acme/users?a=1", nil)
+		req.Header.Set(ApiKeyHeader, "key")
+		req.Header.Set("Authorization", "Bearer token")
+		req.Header.Set(GatariOrgIDHeader, "99")
+		req.Header.Set("X-Gatari-Forged", "1")
+		return req
+	}
+
+	t.Run("unsigned", func(t *testing.T) {
+		req := newRequest()
+		router := &UpstreamRouter{}
+		router.Transform(req, &Upstream{Transform: DefaultUpstreamTransform}, "/users", newTestOutput("", nil))
+
+		for name := range req.Header {
+			if strings.HasPrefix(name, GatariHeaderPrefix) {
+				t.Errorf("%s sent without a signer", name)
+			}
+		}
+		if req.Header.Get(ApiKeyHeader) != "" || req.Header.Get("Authorization") != "" {
+			t.Error("the credentials weren't stripped")
+		}
+	})
+
+	t.Run("signed", func(t *testing.T) {
+		req := newRequest()
+		router := &UpstreamRouter{Signer: NewRequestSigner("secret")}
+		router.Transform(req, &Upstream{Transform: UpstreamTransform{}}, "/users", newTestOutput("", nil))
+
+		want := map[string]string{
+			GatariOrgIDHeader:          "1",
+			GatariSubscriptionIDHeader: "2",
+			GatariEndpointCodeHeader:   "users",
+			GatariRemainingHeader:      "-1",
+			"X-Gatari-Forged":          "",
+		}
+		for name, value := range want {
+			if got := req.Header.Get(name); got != value {
+				t.Errorf("%s = %q, want %q", name, got, value)
+			}
+		}
+		if req.Header.Get(ApiKeyHeader) != "key" {
+			t.Error("the credentials were stripped")
+		}
+		signature := router.Signer.Sign(req.Header, http.MethodGet, "/users", "a=1")
+		if got := req.Header.Get(GatariSignatureHeader); got != signature {
+			t.Errorf("signature = %q, want %q", got, signature)
+		}
+	})
+}
@@ -0,0 +1,257 @@
+package gatekeeping
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"net/url"
+	"slices"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"github.com/bignyap/go-admin/internal/common"
+	"github.com/bignyap/go-admin/internal/database/sqlcgen"
+)
+
+// UpstreamTarget is one instance of an upstream. Active counts the requests
+// it is serving, least-connections balancing picks the lowest.
+type UpstreamTarget struct {
+	URL          *url.URL
+	active       atomic.Int64
+	failures     atomic.Int64
+	ejectedUntil atomic.Int64
+}
+
+// Done must be called once the request sent to the target has completed.
+func (t *UpstreamTarget) Done() {
+	t.active.Add(-1)
+}
+
+func (t *UpstreamTarget) Active() int64 {
+	return t.active.Load()
+}
+
+func (t *UpstreamTarget) ejected(now int64) bool {
+	return t.ejectedUntil.Load() > now
+}
+
+// Upstream is a named set of targets serving the same endpoints. It is an
+// http.RoundTripper applying the policy of the upstream.
+type Upstream struct {
+	ID            int32
+	Name          string
+	LoadBalancing string
+	Policy        UpstreamPolicy
+	Transform     UpstreamTransform
+
+	targets   []*UpstreamTarget
+	next      atomic.Uint64
+	transport *http.Transport
+	budget    *retryBudget
+}
+
+// UpstreamRouter tells the proxy where to send the request of an endpoint.
+// An endpoint goes to its own upstream, else to the one of its resource
+// type, else to the default target (PROXY_TARGET).
+type UpstreamRouter struct {
+	DB *sqlcgen.Queries
+	// Signs the identity headers of the forwarded requests, nil to not send
+	// them at all
+	Signer *RequestSigner
+
+	lock          sync.RWMutex
+	fallback      *Upstream
+	upstreams     map[int32]*Upstream
+	endpoints     map[string]int32
+	resourceTypes map[int32]int32
+}
+
+func NewUpstreamRouter(db *sqlcgen.Queries, defaultTarget string) (*UpstreamRouter, error) {
+
+	router := &UpstreamRouter{
+		DB:            db,
+		upstreams:     make(map[int32]*Upstream),
+		endpoints:     make(map[string]int32),
+		resourceTypes: make(map[int32]int32),
+	}
+
+	if defaultTarget != "" {
+		upstream, err := newUpstream(
+			0, "default", common.LoadBalancingRoundRobin, []string{defaultTarget},
+			DefaultUpstreamPolicy, DefaultUpstreamTransform, nil,
+		)
+		if err != nil {
+			return nil, err
+		}
+		router.fallback = upstream
+	}
+
+	return router, nil
+}
+
+func newUpstream(
+	id int32, name, loadBalancing string, targets []string,
+	policy UpstreamPolicy, transform UpstreamTransform, previous *Upstream,
+) (*Upstream, error) {
+
+	upstream := &Upstream{ID: id, Name: name, LoadBalancing: loadBalancing, Policy: policy, Transform: transform}
+
+	// Keep the targets that didn't change so their requests in flight and
+	// ejections carry over
+	existing := make(map[string]*UpstreamTarget)
+	if previous != nil {
+		for _, target := range previous.targets {
+			existing[target.URL.String()] = target
+		}
+		if previous.Policy == policy {
+			upstream.transport = previous.transport
+			upstream.budget = previous.budget
+		} else {
+			previous.transport.CloseIdleConnections()
+		}
+	}
+	if upstream.transport == nil {
+		upstream.transport = newUpstreamTransport(policy)
+		upstream.budget = &retryBudget{percent: policy.RetryBudgetPercent}
+	}
+
+	for _, raw := range targets {
+		u, err := url.Parse(raw)
+		if err != nil || u.Scheme == "" || u.Host == "" {
+			return nil, fmt.Errorf("upstream %s: invalid target %q", name, raw)
+		}
+		if target, ok := existing[u.String()]; ok {
+			upstream.targets = append(upstream.targets, target)
+			continue
+		}
+		upstream.targets = append(upstream.targets, &UpstreamTarget{URL: u})
+	}
+	if len(upstream.targets) == 0 {
+		return nil, fmt.Errorf("upstream %s has no targets", name)
+	}
+
+	return upstream, nil
+}
+
+// Reload replaces the upstreams and routes with the ones stored in the
+// database.
+func (r *UpstreamRouter) Reload(ctx context.Context) error {
+
+	upstreams, err := r.DB.ListUpstreams(ctx)
+	if err != nil {
+		return fmt.Errorf("couldn't retrieve upstreams: %w", err)
+	}
+	endpoints, err := r.DB.ListEndpointUpstreams(ctx)
+	if err != nil {
+		return fmt.Errorf("couldn't retrieve endpoint upstreams: %w", err)
+	}
+	resourceTypes, err := r.DB.ListResourceTypeUpstreams(ctx)
+	if err != nil {
+		return fmt.Errorf("couldn't retrieve resource type upstreams: %w", err)
+	}
+
+	r.lock.Lock()
+	defer r.lock.Unlock()
+
+	loaded := make(map[int32]*Upstream, len(upstreams))
+	for _, upstream := range upstreams {
+		u, err := newUpstream(
+			upstream.UpstreamID, upstream.UpstreamName, upstream.UpstreamLoadBalancing,
+			upstream.UpstreamTargets, upstreamPolicy(upstream), upstreamTransform(upstream),
+			r.upstreams[upstream.UpstreamID],
+		)
+		if err != nil {
+			return err
+		}
+		loaded[upstream.UpstreamID] = u
+	}
+	for id, upstream := range r.upstreams {
+		if _, ok := loaded[id]; !ok {
+			upstream.transport.CloseIdleConnections()
+		}
+	}
+
+	r.upstreams = loaded
+	r.endpoints = make(map[string]int32, len(endpoints))
+	for _, endpoint := range endpoints {
+		r.endpoints[endpoint.EndpointName] = endpoint.UpstreamID
+	}
+	r.resourceTypes = make(map[int32]int32, len(resourceTypes))
+	for _, resourceType := range resourceTypes {
+		r.resourceTypes[resourceType.ResourceTypeID] = resourceType.UpstreamID
+	}
+
+	return nil
+}
+
+// RouteEndpoint sends the endpoint to the upstream, 0 lets it follow its
+// resource type.
+func (r *UpstreamRouter) RouteEndpoint(code string, upstreamID int32) {
+	r.lock.Lock()
+	defer r.lock.Unlock()
+
+	if upstreamID == 0 {
+		delete(r.endpoints, code)
+		return
+	}
+	r.endpoints[code] = upstreamID
+}
+
+func (r *UpstreamRouter) DropEndpoint(code string) {
+	r.RouteEndpoint(code, 0)
+}
+
+// Route returns the upstream serving the endpoint, false when it has none
+// and there is no default target.
+func (r *UpstreamRouter) Route(code string, resourceTypeID int32) (*Upstream, bool) {
+	r.lock.RLock()
+	defer r.lock.RUnlock()
+
+	if id, ok := r.endpoints[code]; ok && r.upstreams[id] != nil {
+		return r.upstreams[id], true
+	}
+	if id, ok := r.resourceTypes[resourceTypeID]; ok && r.upstreams[id] != nil {
+		return r.upstreams[id], true
+	}
+	return r.fallback, r.fallback != nil
+}
+
+// pick chooses the target of the next attempt, preferring the targets not
+// tried yet. It returns nil when every target is ejected.
+func (u *Upstream) pick(tried []*UpstreamTarget) *UpstreamTarget {
+
+	now := time.Now().UnixNano()
+	candidates := make([]*UpstreamTarget, 0, len(u.targets))
+	for _, target := range u.targets {
+		if !target.ejected(now) && !slices.Contains(tried, target) {
+			candidates = append(candidates, target)
+		}
+	}
+	if len(candidates) == 0 {
+		for _, target := range u.targets {
+			if !target.ejected(now) {
+				candidates = append(candidates, target)
+			}
+		}
+	}
+	if len(candidates) == 0 {
+		return nil
+	}
+
+	n := uint64(len(candidates))
+	start := u.next.Add(1) - 1
+	if u.LoadBalancing != common.LoadBalancingLeastConnections || n == 1 {
+		return candidates[start%n]
+	}
+
+	// Start from a rotating offset so ties don't all land on the first target
+	best := candidates[start%n]
+	for i := uint64(1); i < n; i++ {
+		target := candidates[(start+i)%n]
+		if target.Active() < best.Active() {
+			best = target
+		}
+	}
+	return best
+}
@@ -0,0 +1,299 @@
+package gatekeeping
+
+import (
+	"context"
+	"errors"
+	"io"
+	"net"
+	"net/http"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"github.com/bignyap/go-admin/internal/common"
+	"github.com/bignyap/go-admin/internal/database/sqlcgen"
+)
+
+// UpstreamPolicy is how the proxy protects itself from a slow or failing
+// upstream.
+type UpstreamPolicy struct {
+	ConnectTimeout time.Duration
+	// How long to wait for the response headers, a streamed body may take
+	// longer
+	ResponseTimeout time.Duration
+	// Longest stall of the response body between two reads, 0 waits forever
+	ReadTimeout time.Duration
+	// Retries of a failed idempotent request without a body
+	MaxRetries int
+	// Retries allowed as a percentage of the requests of the last
+	// retryBudgetWindow
+	RetryBudgetPercent int
+	// Consecutive failures ejecting a target for EjectFor, 0 never ejects
+	EjectAfterFailures int
+	EjectFor           time.Duration
+}
+
+var DefaultUpstreamPolicy = UpstreamPolicy{
+	ConnectTimeout:     common.DefaultUpstreamConnectTimeoutMs * time.Millisecond,
+	ResponseTimeout:    common.DefaultUpstreamResponseTimeoutMs * time.Millisecond,
+	ReadTimeout:        common.DefaultUpstreamReadTimeoutMs * time.Millisecond,
+	MaxRetries:         common.DefaultUpstreamMaxRetries,
+	RetryBudgetPercent: common.DefaultUpstreamRetryBudgetPercent,
+	EjectAfterFailures: common.DefaultUpstreamEjectAfterFailures,
+	EjectFor:           common.DefaultUpstreamEjectSeconds * time.Second,
+}
+
+func upstreamPolicy(upstream sqlcgen.Upstream) UpstreamPolicy {
+	return UpstreamPolicy{
+		ConnectTimeout:     time.Duration(upstream.UpstreamConnectTimeoutMs) * time.Millisecond,
+		ResponseTimeout:    time.Duration(upstream.UpstreamResponseTimeoutMs) * time.Millisecond,
+		ReadTimeout:        time.Duration(upstream.UpstreamReadTimeoutMs) * time.Millisecond,
+		MaxRetries:         int(upstream.UpstreamMaxRetries),
+		RetryBudgetPercent: int(upstream.UpstreamRetryBudgetPercent),
+		EjectAfterFailures: int(upstream.UpstreamEjectAfterFailures),
+		EjectFor:           time.Duration(upstream.UpstreamEjectSeconds) * time.Second,
+	}
+}
+
+// Idle keep-alive connections to a target are closed after this long
+const upstreamIdleConnTimeout = 90 * time.Second
+
+// ErrUpstreamReadTimeout fails the read of a response body that stalled for
+// longer than the ReadTimeout of the upstream.
+var ErrUpstreamReadTimeout = errors.New("upstream response body read timed out")
+
+func newUpstreamTransport(policy UpstreamPolicy) *http.Transport {
+	transport := http.DefaultTransport.(*http.Transport).Clone()
+	transport.DialContext = (&net.Dialer{
+		Timeout:   policy.ConnectTimeout,
+		KeepAlive: 30 * time.Second,
+	}).DialContext
+	transport.TLSHandshakeTimeout = policy.ConnectTimeout
+	transport.ResponseHeaderTimeout = policy.ResponseTimeout
+	transport.IdleConnTimeout = upstreamIdleConnTimeout
+	return transport
+}
+
+const (
+	retryBudgetWindow = 10 * time.Second
+	// Retries always allowed per window, so an upstream with little traffic
+	// still gets some
+	minRetriesPerWindow = 3
+)
+
+// retryBudget caps the retries to a share of the requests, so retries can't
+// multiply the load of an upstream that is already failing.
+type retryBudget struct {
+	percent int
+
+	lock        sync.Mutex
+	windowStart time.Time
+	requests    int
+	retries     int
+}
+
+func (b *retryBudget) roll(now time.Time) {
+	if now.Sub(b.windowStart) >= retryBudgetWindow {
+		b.windowStart = now
+		b.requests = 0
+		b.retries = 0
+	}
+}
+
+func (b *retryBudget) request() {
+	b.lock.Lock()
+	defer b.lock.Unlock()
+	b.roll(time.Now())
+	b.requests++
+}
+
+func (b *retryBudget) allowRetry() bool {
+	if b.percent <= 0 {
+		return false
+	}
+	b.lock.Lock()
+	defer b.lock.Unlock()
+	b.roll(time.Now())
+	if b.retries >= max(minRetriesPerWindow, b.requests*b.percent/100) {
+		return false
+	}
+	b.retries++
+	return true
+}
+
+// RoundTrip sends the request to a target of the upstream. Connection errors,
+// timeouts and 502/503/504 answers count as failures of the target and are
+// retried on another target when the request is safe to replay and the retry
+// budget allows it. It fails with a CircuitOpenError when every target is
+// ejected.
+func (u *Upstream) RoundTrip(req *http.Request) (*http.Response, error) {
+
+	u.budget.request()
+	replayable := isIdempotent(req.Method) && (req.Body == nil || req.Body == http.NoBody)
+
+	var tried []*UpstreamTarget
+	for attempt := 0; ; attempt++ {
+		target := u.pick(tried)
+		if target == nil {
+			return nil, &CircuitOpenError{Upstream: u.Name, RetryAfter: u.nextReturn()}
+		}
+		tried = append(tried, target)
+
+		// Cancelled when the response is done with, or its body stalls
+		ctx, cancel := context.WithCancel(req.Context())
+		out := req.Clone(ctx)
+		out.URL.Scheme = target.URL.Scheme
+		out.URL.Host = target.URL.Host
+		out.Host = target.URL.Host
+
+		target.active.Add(1)
+		resp, err := u.transport.RoundTrip(out)
+		if err != nil && req.Context().Err() != nil {
+			// The client went away, not the target's fault
+			cancel()
+			target.Done()
+			return nil, err
+		}
+
+		failed := err != nil || isUpstreamFailure(resp.StatusCode)
+		u.report(target, failed)
+		if !failed {
+			return holdTarget(resp, target, cancel, u.Policy.ReadTimeout), nil
+		}
+
+		if !replayable || attempt >= u.Policy.MaxRetries || !u.budget.allowRetry() {
+			if err != nil {
+				cancel()
+				target.Done()
+				return nil, err
+			}
+			return holdTarget(resp, target, cancel, u.Policy.ReadTimeout), nil
+		}
+
+		if resp != nil {
+			_, _ = io.Copy(io.Discard, io.LimitReader(resp.Body, 4<<10))
+			resp.Body.Close()
+		}
+		cancel()
+		target.Done()
+	}
+}
+
+// holdTarget keeps the request active on its target until the response has
+// been sent to the client, or the upgraded connection is closed. A body read
+// blocked for longer than readTimeout cancels the request, an upgraded
+// connection may stay idle.
+func holdTarget(resp *http.Response, target *UpstreamTarget, cancel context.CancelFunc, readTimeout time.Duration) *http.Response {
+	done := &targetDone{target: target, cancel: cancel}
+	if conn, ok := resp.Body.(io.ReadWriteCloser); ok && resp.StatusCode == http.StatusSwitchingProtocols {
+		// The proxy needs a ReadWriteCloser to switch protocols
+		resp.Body = &upstreamConn{ReadWriteCloser: conn, done: done}
+		return resp
+	}
+	body := &upstreamBody{ReadCloser: resp.Body, done: done, readTimeout: readTimeout}
+	if readTimeout > 0 {
+		body.stall = time.AfterFunc(readTimeout, func() {
+			body.timedOut.Store(true)
+			cancel()
+		})
+		body.stall.Stop()
+	}
+	resp.Body = body
+	return resp
+}
+
+type targetDone struct {
+	target *UpstreamTarget
+	cancel context.CancelFunc
+	once   sync.Once
+}
+
+func (d *targetDone) close() {
+	d.once.Do(func() {
+		d.cancel()
+		d.target.Done()
+	})
+}
+
+type upstreamBody struct {
+	io.ReadCloser
+	done *targetDone
+
+	readTimeout time.Duration
+	// Armed while a read is blocked, so a slow client doesn't count against
+	// the upstream
+	stall    *time.Timer
+	timedOut atomic.Bool
+}
+
+func (b *upstreamBody) Read(p []byte) (int, error) {
+	if b.stall == nil {
+		return b.ReadCloser.Read(p)
+	}
+	b.stall.Reset(b.readTimeout)
+	n, err := b.ReadCloser.Read(p)
+	b.stall.Stop()
+	if err != nil && b.timedOut.Load() {
+		return n, ErrUpstreamReadTimeout
+	}
+	return n, err
+}
+
+func (b *upstreamBody) Close() error {
+	if b.stall != nil {
+		b.stall.Stop()
+	}
+	err := b.ReadCloser.Close()
+	b.done.close()
+	return err
+}
+
+type upstreamConn struct {
+	io.ReadWriteCloser
+	done *targetDone
+}
+
+func (c *upstreamConn) Close() error {
+	err := c.ReadWriteCloser.Close()
+	c.done.close()
+	return err
+}
+
+func (u *Upstream) report(target *UpstreamTarget, failed bool) {
+	if !failed {
+		target.failures.Store(0)
+		return
+	}
+	if u.Policy.EjectAfterFailures <= 0 {
+		return
+	}
+	if target.failures.Add(1) >= int64(u.Policy.EjectAfterFailures) {
+		target.failures.Store(0)
+		target.ejectedUntil.Store(time.Now().Add(u.Policy.EjectFor).UnixNano())
+	}
+}
+
+// nextReturn is how long until the first ejected target is tried again.
+func (u *Upstream) nextReturn() time.Duration {
+	now := time.Now().UnixNano()
+	next := int64(0)
+	for _, target := range u.targets {
+		until := target.ejectedUntil.Load()
+		if next == 0 || until < next {
+			next = until
+		}
+	}
+	return time.Duration(max(next-now, 0))
+}
+
+func isIdempotent(method string) bool {
+	switch method {
+	case http.MethodGet, http.MethodHead, http.MethodOptions, http.MethodPut, http.MethodDelete:
+		return true
+	}
+	return false
+}
+
+func isUpstreamFailure(status int) bool {
+	return status == http.StatusBadGateway || status == http.StatusServiceUnavailable || status == http.StatusGatewayTimeout
+}
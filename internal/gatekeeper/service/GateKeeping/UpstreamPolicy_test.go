@@ -0,0 +1,266 @@
+package gatekeeping
+
+import (
+	"errors"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/bignyap/go-admin/internal/common"
+)
+
+func newTestUpstream(t *testing.T, policy UpstreamPolicy, targets ...string) *Upstream {
+	t.Helper()
+	upstream, err := newUpstream(1, "test", "", targets, policy, DefaultUpstreamTransform, nil)
+	if err != nil {
+		t.Fatalf("newUpstream: %v", err)
+	}
+	return upstream
+}
+
+func TestReadTimeout(t *testing.T) {
+	release := make(chan struct{})
+	defer close(release)
+	backend := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Write([]byte("partial"))
+		w.(http.Flusher).Flush()
+		select {
+		case <-release:
+		case <-r.Context().Done():
+		}
+	}))
+	defer backend.Close()
+
+	policy := DefaultUpstreamPolicy
+	policy.ReadTimeout = 50 * time.Millisecond
+	upstream := newTestUpstream(t, policy, backend.URL)
+
+	req := httptest.NewRequest(http.MethodGet, "/stream", nil)
+	resp, err := upstream.RoundTrip(req)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer resp.Body.Close()
+
+	done := make(chan error, 1)
+	go func() {
+		_, err := io.ReadAll(resp.Body)
+		done <- err
+	}()
+	select {
+	case err := <-done:
+		if !errors.Is(err, ErrUpstreamReadTimeout) {
+			t.Errorf("err = %v, want ErrUpstreamReadTimeout", err)
+		}
+	case <-time.After(5 * time.Second):
+		t.Fatal("the stalled body was never cut off")
+	}
+}
+
+func TestReadTimeoutIgnoresSlowClients(t *testing.T) {
+	backend := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		for i := 0; i < 3; i++ {
+			w.Write([]byte("chunk"))
+			w.(http.Flusher).Flush()
+		}
+	}))
+	defer backend.Close()
+
+	policy := DefaultUpstreamPolicy
+	policy.ReadTimeout = 20 * time.Millisecond
+	upstream := newTestUpstream(t, policy, backend.URL)
+
+	resp, err := upstream.RoundTrip(httptest.NewRequest(http.MethodGet, "/stream", nil))
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer resp.Body.Close()
+
+	// Only the time spent blocked in Read counts
+	time.Sleep(60 * time.Millisecond)
+	body, err := io.ReadAll(resp.Body)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if string(body) != "chunkchunkchunk" {
+		t.Errorf("body = %q", body)
+	}
+}
+
+func TestRetryBudget(t *testing.T) {
+	tests := []struct {
+		name     string
+		percent  int
+		requests int
+		want     int
+	}{
+		{"disabled", 0, 100, 0},
+		{"share of the requests", 20, 100, 20},
+		{"minimum on little traffic", 20, 5, minRetriesPerWindow},
+		{"no traffic yet", 50, 0, minRetriesPerWindow},
+		{"every request", 100, 10, 10},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			budget := &retryBudget{percent: tt.percent}
+			for i := 0; i < tt.requests; i++ {
+				budget.request()
+			}
+			allowed := 0
+			for i := 0; i < tt.requests+10; i++ {
+				if budget.allowRetry() {
+					allowed++
+				}
+			}
+			if allowed != tt.want {
+				t.Errorf("allowed %d retries, want %d", allowed, tt.want)
+			}
+		})
+	}
+}
+
+func TestRetryBudgetWindowRolls(t *testing.T) {
+	budget := &retryBudget{percent: 10}
+	for budget.allowRetry() {
+	}
+
+	budget.windowStart = budget.windowStart.Add(-retryBudgetWindow)
+	if !budget.allowRetry() {
+		t.Error("the budget wasn't renewed with the window")
+	}
+}
+
+func TestReportEjects(t *testing.T) {
+	tests := []struct {
+		name        string
+		ejectAfter  int
+		failures    []bool
+		wantEjected bool
+	}{
+		{"below the threshold", 3, []bool{true, true}, false},
+		{"at the threshold", 3, []bool{true, true, true}, true},
+		{"success resets the count", 3, []bool{true, true, false, true, true}, false},
+		{"never ejects", 0, []bool{true, true, true, true}, false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			policy := DefaultUpstreamPolicy
+			policy.EjectAfterFailures = tt.ejectAfter
+			policy.EjectFor = time.Minute
+			upstream := newTestUpstream(t, policy, "http://a.test")
+			target := upstream.targets[0]
+
+			for _, failed := range tt.failures {
+				upstream.report(target, failed)
+			}
+			if ejected := target.ejected(time.Now().UnixNano()); ejected != tt.wantEjected {
+				t.Errorf("ejected = %v, want %v", ejected, tt.wantEjected)
+			}
+		})
+	}
+}
+
+func TestPickSkipsEjectedTargets(t *testing.T) {
+	upstream := newTestUpstream(t, DefaultUpstreamPolicy, "http://a.test", "http://b.test")
+	a, b := upstream.targets[0], upstream.targets[1]
+	a.ejectedUntil.Store(time.Now().Add(time.Minute).UnixNano())
+
+	for i := 0; i < 4; i++ {
+		if got := upstream.pick(nil); got != b {
+			t.Fatalf("picked %s, want %s", got.URL, b.URL)
+		}
+	}
+	// A retry goes back to a tried target rather than an ejected one
+	if got := upstream.pick([]*UpstreamTarget{b}); got != b {
+		t.Errorf("retry picked %v, want %s", got, b.URL)
+	}
+
+	b.ejectedUntil.Store(time.Now().Add(time.Minute).UnixNano())
+	if got := upstream.pick(nil); got != nil {
+		t.Errorf("picked %s with every target ejected", got.URL)
+	}
+	if wait := upstream.nextReturn(); wait <= 0 || wait > time.Minute {
+		t.Errorf("nextReturn = %v", wait)
+	}
+}
+
+func TestPickLeastConnections(t *testing.T) {
+	upstream := newTestUpstream(t, DefaultUpstreamPolicy, "http://a.test", "http://b.test", "http://c.test")
+	upstream.LoadBalancing = common.LoadBalancingLeastConnections
+	upstream.targets[0].active.Store(3)
+	upstream.targets[1].active.Store(1)
+	upstream.targets[2].active.Store(2)
+
+	for i := 0; i < 3; i++ {
+		if got := upstream.pick(nil); got != upstream.targets[1] {
+			t.Errorf("picked %s, want the least busy target", got.URL)
+		}
+	}
+}
+
+func TestRoundTripRetries(t *testing.T) {
+	failing := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusServiceUnavailable)
+	}))
+	defer failing.Close()
+	healthy := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Write([]byte("ok"))
+	}))
+	defer healthy.Close()
+
+	tests := []struct {
+		name       string
+		method     string
+		body       io.Reader
+		maxRetries int
+		want       int
+	}{
+		{"idempotent request retried", http.MethodGet, nil, 1, http.StatusOK},
+		{"no retries allowed", http.MethodGet, nil, 0, http.StatusServiceUnavailable},
+		{"request with a body not retried", http.MethodPut, strings.NewReader("x"), 1, http.StatusServiceUnavailable},
+		{"unsafe method not retried", http.MethodPost, nil, 1, http.StatusServiceUnavailable},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			policy := DefaultUpstreamPolicy
+			policy.MaxRetries = tt.maxRetries
+			policy.EjectAfterFailures = 0
+			// Round robin starts on the failing target
+			upstream := newTestUpstream(t, policy, failing.URL, healthy.URL)
+
+			resp, err := upstream.RoundTrip(httptest.NewRequest(tt.method, "/", tt.body))
+			if err != nil {
+				t.Fatal(err)
+			}
+			resp.Body.Close()
+			if resp.StatusCode != tt.want {
+				t.Errorf("status = %d, want %d", resp.StatusCode, tt.want)
+			}
+			for _, target := range upstream.targets {
+				if active := target.Active(); active != 0 {
+					t.Errorf("%s still has %d requests active", target.URL, active)
+				}
+			}
+		})
+	}
+}
+
+func TestRoundTripCircuitOpen(t *testing.T) {
+	upstream := newTestUpstream(t, DefaultUpstreamPolicy, "http://a.test")
+	upstream.targets[0].ejectedUntil.Store(time.Now().Add(time.Minute).UnixNano())
+
+	_, err := upstream.RoundTrip(httptest.NewRequest(http.MethodGet, "/", nil))
+	var open *CircuitOpenError
+	if !errors.As(err, &open) {
+		t.Fatalf("err = %v, want a CircuitOpenError", err)
+	}
+	if open.RetryAfter <= 0 {
+		t.Errorf("RetryAfter = %v", open.RetryAfter)
+	}
+}
@@ -0,0 +1,121 @@
+package gatekeeping
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"math"
+	"net/http"
+	"strconv"
+	"strings"
+
+	"github.com/bignyap/go-admin/internal/common"
+	"github.com/bignyap/go-admin/internal/database/sqlcgen"
+)
+
+// DefaultUsageUnitHeader is read by endpoints using the header source that
+// don't name a header in usage_unit_key.
+const DefaultUsageUnitHeader = "X-Usage-Units"
+
+// UsageUnitsFromResponse reads the usage units of a call from the upstream
+// response, as configured on the endpoint. It returns false when the endpoint
+// has no source or the response doesn't carry the units, the call then counts
+// as a single unit.
+func UsageUnitsFromResponse(endpoint sqlcgen.GetApiEndpointByNameRow, header http.Header, body []byte) (float64, bool, error) {
+
+	switch endpoint.UsageUnitSource {
+	case common.UsageUnitSourceHeader:
+		name := endpoint.UsageUnitKey.String
+		if name == "" {
+			name = DefaultUsageUnitHeader
+		}
+		value := strings.TrimSpace(header.Get(name))
+		if value == "" {
+			return 0, false, nil
+		}
+		units, err := parseUsageUnits(value)
+		if err != nil {
+			return 0, false, fmt.Errorf("header %s: %w", name, err)
+		}
+		return units, true, nil
+
+	case common.UsageUnitSourceJSON:
+		if len(body) == 0 {
+			return 0, false, nil
+		}
+		return UsageUnitsFromJSON(body, endpoint.UsageUnitKey.String)
+	}
+	return 0, false, nil
+}
+
+// UsageUnitsFromJSON follows a dotted path such as usage.total_tokens into a
+// JSON document, numeric segments index arrays (choices.0.usage.tokens).
+func UsageUnitsFromJSON(body []byte, path string) (float64, bool, error) {
+
+	decoder := json.NewDecoder(bytes.NewReader(body))
+	decoder.UseNumber()
+	var value any
+	if err := decoder.Decode(&value); err != nil {
+		return 0, false, fmt.Errorf("response body: %w", err)
+	}
+
+	for _, segment := range strings.Split(path, ".") {
+		switch node := value.(type) {
+		case map[string]any:
+			next, ok := node[segment]
+			if !ok {
+				return 0, false, nil
+			}
+			value = next
+		case []any:
+			i, err := strconv.Atoi(segment)
+			if err != nil || i < 0 || i >= len(node) {
+				return 0, false, nil
+			}
+			value = node[i]
+		default:
+			return 0, false, nil
+		}
+	}
+
+	switch v := value.(type) {
+	case json.Number:
+		units, err := parseUsageUnits(v.String())
+		if err != nil {
+			return 0, false, fmt.Errorf("%s: %w", path, err)
+		}
+		return units, true, nil
+	case string:
+		units, err := parseUsageUnits(v)
+		if err != nil {
+			return 0, false, fmt.Errorf("%s: %w", path, err)
+		}
+		return units, true, nil
+	case nil:
+		return 0, false, nil
+	}
+	return 0, false, fmt.Errorf("%s is not a number", path)
+}
+
+func parseUsageUnits(value string) (float64, error) {
+	units, err := strconv.ParseFloat(value, 64)
+	if err != nil {
+		return 0, fmt.Errorf("invalid usage units %q", value)
+	}
+	if err := CheckUsageUnits(units); err != nil {
+		return 0, fmt.Errorf("%w, got %q", err, value)
+	}
+	return units, nil
+}
+
+// CheckUsageUnits rejects the units that can't be counted. Redis refuses NaN
+// and infinities, which would keep the counters from ever being flushed.
+func CheckUsageUnits(units float64) error {
+	if math.IsNaN(units) || math.IsInf(units, 0) {
+		return fmt.Errorf("usage units must be a finite number")
+	}
+	if units < 0 {
+		return fmt.Errorf("usage units can't be negative")
+	}
+	return nil
+}
@@ -0,0 +1,132 @@
+package gatekeeping
+
+import (
+	"math"
+	"net/http"
+	"testing"
+
+	"github.com/bignyap/go-admin/internal/common"
+	"github.com/bignyap/go-admin/internal/database/sqlcgen"
+	"github.com/jackc/pgx/v5/pgtype"
+)
+
+func TestCheckUsageUnits(t *testing.T) {
+	tests := []struct {
+		units   float64
+		wantErr bool
+	}{
+		{0, false},
+		{1, false},
+		{0.25, false},
+		{1e12, false},
+		{-1, true},
+		{math.NaN(), true},
+		{math.Inf(1), true},
+		{math.Inf(-1), true},
+	}
+
+	for _, tt := range tests {
+		if err := CheckUsageUnits(tt.units); (err != nil) != tt.wantErr {
+			t.Errorf("CheckUsageUnits(%g) = %v, want error %v", tt.units, err, tt.wantErr)
+		}
+	}
+}
+
+func TestParseUsageUnits(t *testing.T) {
+	tests := []struct {
+		value   string
+		want    float64
+		wantErr bool
+	}{
+		{"3", 3, false},
+		{"2.5", 2.5, false},
+		{"1e3", 1000, false},
+		{"0", 0, false},
+		{"-1", 0, true},
+		{"NaN", 0, true},
+		{"Inf", 0, true},
+		{"+Inf", 0, true},
+		{"1e400", 0, true},
+		{"ten", 0, true},
+		{"", 0, true},
+	}
+
+	for _, tt := range tests {
+		got, err := parseUsageUnits(tt.value)
+		if (err != nil) != tt.wantErr || got != tt.want {
+			t.Errorf("parseUsageUnits(%q) = %g, %v, want %g, error %v", tt.value, got, err, tt.want, tt.wantErr)
+		}
+	}
+}
+
+func TestUsageUnitsFromJSON(t *testing.T) {
+	tests := []struct {
+		body      string
+		path      string
+		want      float64
+		wantFound bool
+		wantErr   bool
+	}{
+		{`{"usage":{"total_tokens":42}}`, "usage.total_tokens", 42, true, false},
+		{`{"usage":{"total_tokens":"42"}}`, "usage.total_tokens", 42, true, false},
+		{`{"choices":[{"tokens":1},{"tokens":7}]}`, "choices.1.tokens", 7, true, false},
+		{`{"units":0.5}`, "units", 0.5, true, false},
+		{`{"usage":{}}`, "usage.total_tokens", 0, false, false},
+		{`{"usage":null}`, "usage", 0, false, false},
+		{`{"usage":5}`, "usage.total_tokens", 0, false, false},
+		{`{"choices":[]}`, "choices.0.tokens", 0, false, false},
+		{`{"choices":[1]}`, "choices.x", 0, false, false},
+		{`{"choices":[1]}`, "choices.-1", 0, false, false},
+		{`{"usage":-3}`, "usage", 0, false, true},
+		{`{"usage":"NaN"}`, "usage", 0, false, true},
+		{`{"usage":1e400}`, "usage", 0, false, true},
+		{`{"usage":true}`, "usage", 0, false, true},
+		{`{"usage":{"total_tokens":1}}`, "usage", 0, false, true},
+		{`not json`, "usage", 0, false, true},
+	}
+
+	for _, tt := range tests {
+		got, found, err := UsageUnitsFromJSON([]byte(tt.body), tt.path)
+		if (err != nil) != tt.wantErr || found != tt.wantFound || got != tt.want {
+			t.Errorf("UsageUnitsFromJSON(%s, %q) = %g, %v, %v, want %g, %v, error %v",
+				tt.body, tt.path, got, found, err, tt.want, tt.wantFound, tt.wantErr)
+		}
+	}
+}
+
+func TestUsageUnitsFromResponse(t *testing.T) {
+	header := http.Header{}
+	header.Set(DefaultUsageUnitHeader, "4")
+	header.Set("X-Tokens", " 9 ")
+	header.Set("X-Bad", "-2")
+	body := []byte(`{"usage":{"total_tokens":12}}`)
+
+	tests := []struct {
+		name      string
+		source    string
+		key       string
+		body      []byte
+		want      float64
+		wantFound bool
+		wantErr   bool
+	}{
+		{"default header", common.UsageUnitSourceHeader, "", nil, 4, true, false},
+		{"named header", common.UsageUnitSourceHeader, "X-Tokens", nil, 9, true, false},
+		{"header missing", common.UsageUnitSourceHeader, "X-Missing", nil, 0, false, false},
+		{"invalid header", common.UsageUnitSourceHeader, "X-Bad", nil, 0, false, true},
+		{"JSON body", common.UsageUnitSourceJSON, "usage.total_tokens", body, 12, true, false},
+		{"empty body", common.UsageUnitSourceJSON, "usage.total_tokens", nil, 0, false, false},
+		{"no source", "", "", body, 0, false, false},
+	}
+
+	for _, tt := range tests {
+		endpoint := sqlcgen.GetApiEndpointByNameRow{
+			UsageUnitSource: tt.source,
+			UsageUnitKey:    pgtype.Text{String: tt.key, Valid: tt.key != ""},
+		}
+		got, found, err := UsageUnitsFromResponse(endpoint, header, tt.body)
+		if (err != nil) != tt.wantErr || found != tt.wantFound || got != tt.want {
+			t.Errorf("%s: got %g, %v, %v, want %g, %v, error %v", tt.name, got, found, err, tt.want, tt.wantFound, tt.wantErr)
+		}
+	}
+}
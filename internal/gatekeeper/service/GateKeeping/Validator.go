@@ -1,21 +1,70 @@
 package gatekeeping
 
 import (
+	"context"
 	"fmt"
+	"strings"
 
+	"github.com/bignyap/go-admin/internal/common"
+	"github.com/bignyap/go-utilities/server"
 	"github.com/gin-gonic/gin"
 )
 
+const ApiKeyHeader = "X-API-Key"
+
+// Headers NGINX auth_request and Traefik ForwardAuth use to describe the
+// request being authorized.
+const (
+	OriginalMethodHeader  = "X-Original-Method"
+	OriginalURIHeader     = "X-Original-URI"
+	ForwardedMethodHeader = "X-Forwarded-Method"
+	ForwardedURIHeader    = "X-Forwarded-Uri"
+)
+
+// DefaultOrgHeader is read by the header organization extractor unless
+// ORG_HEADER names another one.
+const DefaultOrgHeader = "X-Organization-Name"
+
+// IdempotencyKeyHeader carries the idempotency key of a usage record when the
+// body doesn't.
+const IdempotencyKeyHeader = "Idempotency-Key"
+
+// MaxIdempotencyKeyLength bounds the idempotency keys kept in Redis.
+const MaxIdempotencyKeyLength = 255
+
+// Caller is the identity proven by the request credentials.
+type Caller struct {
+	Realm  string
+	Scopes []string
+}
+
 func (s *GateKeepingService) ValidateRequestHeader(c *gin.Context) (*ValidateRequestInput, error) {
 
 	var input ValidateRequestInput
 	if err := c.ShouldBind(&input); err != nil {
 		return nil, fmt.Errorf("input validation failed %s", err)
 	}
+	if input.UsageUnits != nil {
+		if err := CheckUsageUnits(*input.UsageUnits); err != nil {
+			return nil, server.NewError(server.ErrorBadRequest, err.Error(), nil)
+		}
+	}
 
-	// org := c.GetHeader("X-Org-Name")
-	// method := c.Request.Method
-	// path := c.Request.URL.Path
+	// Forwarded credentials, then the organization extractors, win over the
+	// organization_name in the body
+	if input.Host == "" {
+		input.Host = c.GetHeader("X-Forwarded-Host")
+	}
+	input.Header = c.GetHeader
+	who, path, err := s.resolveCaller(c, input.Host, input.Path)
+	if err != nil {
+		return nil, err
+	}
+	input.Path = path
+	if who != nil {
+		input.OrganizationName = who.Realm
+		input.Scopes = who.Scopes
+	}
 
 	if input.OrganizationName == "" || input.Path == "" {
 		return nil, fmt.Errorf("missing required headers")
@@ -30,10 +79,30 @@ func (s *GateKeepingService) RecordUsageValidator(c *gin.Context) (*RecordUsageI
 	if err := c.ShouldBind(&input); err != nil {
 		return nil, fmt.Errorf("input validation failed %s", err)
 	}
+	if input.IdempotencyKey == "" {
+		input.IdempotencyKey = c.GetHeader(IdempotencyKeyHeader)
+	}
+	if len(input.IdempotencyKey) > MaxIdempotencyKeyLength {
+		return nil, fmt.Errorf("idempotency key longer than %d characters", MaxIdempotencyKeyLength)
+	}
+	if input.UsageUnits != nil {
+		if err := CheckUsageUnits(*input.UsageUnits); err != nil {
+			return nil, server.NewError(server.ErrorBadRequest, err.Error(), nil)
+		}
+	}
 
-	// org := c.GetHeader("X-Org-Name")
-	// method := c.Request.Method
-	// path := c.Request.URL.Path
+	if input.Host == "" {
+		input.Host = c.GetHeader("X-Forwarded-Host")
+	}
+	input.Header = c.GetHeader
+	who, path, err := s.resolveCaller(c, input.Host, input.Path)
+	if err != nil {
+		return nil, err
+	}
+	input.Path = path
+	if who != nil {
+		input.OrganizationName = who.Realm
+	}
 
 	if input.OrganizationName == "" || input.Path == "" {
 		return nil, fmt.Errorf("missing required headers")
@@ -41,3 +110,200 @@ func (s *GateKeepingService) RecordUsageValidator(c *gin.Context) (*RecordUsageI
 
 	return &input, nil
 }
+
+func (s *GateKeepingService) ReservationValidator(c *gin.Context) (*ReservationInput, error) {
+
+	var input ReservationInput
+	if err := c.ShouldBind(&input); err != nil {
+		return nil, fmt.Errorf("input validation failed %s", err)
+	}
+
+	if input.ReservationToken == "" {
+		return nil, fmt.Errorf("missing reservation_token")
+	}
+	if input.UsageUnits != nil {
+		if err := CheckUsageUnits(*input.UsageUnits); err != nil {
+			return nil, err
+		}
+	}
+
+	return &input, nil
+}
+
+// IncomingRequestValidator is used by the middleware and proxy modes where the
+// request itself is the one being gated. The organization is taken from the
+// API key or bearer token, or else the organization extractors, the method
+// and path from the request line.
+func (s *GateKeepingService) IncomingRequestValidator(c *gin.Context) (*ValidateRequestInput, error) {
+
+	who, path, err := s.resolveCaller(c, c.Request.Host, c.Request.URL.Path)
+	if err != nil {
+		return nil, err
+	}
+	if who == nil {
+		return nil, server.NewError(
+			server.ErrorUnauthorized, "missing credentials", fmt.Errorf("%w: missing", ErrInvalidApiKey),
+		)
+	}
+
+	return &ValidateRequestInput{
+		Method:           c.Request.Method,
+		Path:             path,
+		OrganizationName: who.Realm,
+		Host:             c.Request.Host,
+		Header:           c.GetHeader,
+		Scopes:           who.Scopes,
+	}, nil
+}
+
+// ForwardAuthValidator reads a bodiless auth subrequest. The gated request is
+// described by the X-Original-* (NGINX) or X-Forwarded-* (Traefik) headers,
+// the organization from the credentials or else the organization extractors.
+func (s *GateKeepingService) ForwardAuthValidator(c *gin.Context) (*ValidateRequestInput, error) {
+
+	method := firstHeader(c, OriginalMethodHeader, ForwardedMethodHeader)
+	if method == "" {
+		method = "GET"
+	}
+	uri := firstHeader(c, OriginalURIHeader, ForwardedURIHeader)
+	path, _, _ := strings.Cut(uri, "?")
+	if path == "" {
+		return nil, server.NewError(
+			server.ErrorBadRequest,
+			fmt.Sprintf("missing %s or %s header", OriginalURIHeader, ForwardedURIHeader), nil,
+		)
+	}
+
+	host := firstHeader(c, "X-Forwarded-Host")
+	if host == "" {
+		host = c.Request.Host
+	}
+
+	who, path, err := s.resolveCaller(c, host, path)
+	if err != nil {
+		return nil, err
+	}
+	if who == nil {
+		return nil, server.NewError(
+			server.ErrorUnauthorized, "missing credentials", fmt.Errorf("%w: missing", ErrInvalidApiKey),
+		)
+	}
+
+	return &ValidateRequestInput{
+		Method:           strings.ToUpper(method),
+		Path:             path,
+		OrganizationName: who.Realm,
+		Host:             host,
+		Header:           c.GetHeader,
+		Scopes:           who.Scopes,
+	}, nil
+}
+
+func firstHeader(c *gin.Context, names ...string) string {
+	for _, name := range names {
+		if value := strings.TrimSpace(c.GetHeader(name)); value != "" {
+			return value
+		}
+	}
+	return ""
+}
+
+// resolveCaller runs ResolveCaller on a gin request. host and path are those
+// of the gated request, the returned path has the tenant segment stripped.
+func (s *GateKeepingService) resolveCaller(c *gin.Context, host, path string) (*Caller, string, error) {
+
+	req := &IdentityRequest{Host: host, Path: path, Header: c.GetHeader}
+	if c.Request.TLS != nil {
+		req.PeerCertificates = c.Request.TLS.PeerCertificates
+	}
+
+	who, err := s.ResolveCaller(c.Request.Context(), ExtractApiKey(c), ExtractBearerToken(c), req)
+	if err != nil {
+		return nil, "", err
+	}
+	return who, req.Path, nil
+}
+
+// ResolveCaller authenticates the request with its API key or, failing that,
+// its bearer JWT, then falls back to the organization extractors. req.Path is
+// left without the tenant segment. It returns nil without error when nothing
+// identifies the caller.
+func (s *GateKeepingService) ResolveCaller(ctx context.Context, key, token string, req *IdentityRequest) (*Caller, error) {
+
+	who, err := s.ResolveCredentials(ctx, key, token)
+	if err != nil {
+		return nil, err
+	}
+
+	// Always run, the path-prefix extractor has to strip the path either way
+	org := s.Identity.Identify(req)
+	if who != nil {
+		return who, nil
+	}
+	if org != "" {
+		return &Caller{Realm: org}, nil
+	}
+	return nil, nil
+}
+
+// ResolveCredentials checks an API key first, then a bearer JWT. Transports
+// other than HTTP (gRPC metadata) extract both themselves and call it directly.
+func (s *GateKeepingService) ResolveCredentials(ctx context.Context, key, token string) (*Caller, error) {
+
+	if key != "" {
+		apiKey, err := s.ResolveApiKey(ctx, key)
+		if err != nil {
+			return nil, err
+		}
+		return &Caller{Realm: apiKey.OrganizationRealm}, nil
+	}
+
+	if token != "" {
+		identity, err := s.ResolveBearerToken(ctx, token)
+		if err != nil {
+			return nil, err
+		}
+		return &Caller{Realm: identity.Realm, Scopes: identity.Scopes}, nil
+	}
+
+	return nil, nil
+}
+
+// ExtractApiKey reads the key from X-API-Key, or from an Authorization header
+// using the Bearer or ApiKey scheme. Bearer tokens that are not API keys are
+// left to ExtractBearerToken.
+func ExtractApiKey(c *gin.Context) string {
+
+	if key := strings.TrimSpace(c.GetHeader(ApiKeyHeader)); key != "" {
+		return key
+	}
+
+	scheme, token := authorizationHeader(c)
+	switch {
+	case strings.EqualFold(scheme, "ApiKey"):
+		return token
+	case strings.EqualFold(scheme, "Bearer") && common.IsApiKey(token):
+		return token
+	default:
+		return ""
+	}
+}
+
+// ExtractBearerToken returns the bearer token of the request unless it is one
+// of our API keys.
+func ExtractBearerToken(c *gin.Context) string {
+
+	scheme, token := authorizationHeader(c)
+	if !strings.EqualFold(scheme, "Bearer") || common.IsApiKey(token) {
+		return ""
+	}
+	return token
+}
+
+func authorizationHeader(c *gin.Context) (string, string) {
+	scheme, token, ok := strings.Cut(strings.TrimSpace(c.GetHeader("Authorization")), " ")
+	if !ok {
+		return "", ""
+	}
+	return scheme, strings.TrimSpace(token)
+}
@@ -24,6 +24,11 @@ func (s *PubsubListener) ResetGoAdminCache() error {
 		s.cacheInvalidationHandler(common.OrgPermissionModified, &common.OrgPermissionModifiedEvent{}),
 	)
 
-	s.Logger.Info("subscribed to pubsub channels: org/sub/pricing modified")
+	s.asyncSubscribe(
+		common.ApiKeyRevoked,
+		s.cacheInvalidationHandler(common.ApiKeyRevoked, &common.ApiKeyRevokedEvent{}),
+	)
+
+	s.Logger.Info("subscribed to pubsub channels: org/sub/pricing modified, api key revoked")
 	return nil
 }
@@ -31,6 +31,7 @@ func (s *PubsubListener) cacheInvalidationHandler(key common.PubSubChannel, evtP
 		if prefix == common.PricingPrefix {
 			s.Cache.DeleteRedisValue(ctx, string(prefix), "*")
 		} else {
+			s.Cache.Invalidate(ctx, common.RedisKeyFormatter(string(prefix), id))
 			s.Cache.DeleteRedisValue(ctx, string(prefix), fmt.Sprintf("%s:%s", id, "*"))
 		}
 		s.Logger.Info("cache removed", api.Field{Key: "event", Value: evtPtr})
@@ -49,6 +50,8 @@ func extractPrefixAndId(evt any) (common.RedisPrefix, string) {
 		// return common.PricingPrefix, strconv.Itoa(int(e.ID))
 	case *common.OrgPermissionModifiedEvent:
 		return common.OrganizationPrefix, strconv.Itoa(int(e.ID))
+	case *common.ApiKeyRevokedEvent:
+		return common.ApiKeyPrefix, e.Hash
 	default:
 		return "", "unknown"
 	}
@@ -5,7 +5,7 @@ import (
 	"encoding/json"
 
 	"github.com/bignyap/go-admin/internal/common"
-	gatekeeping "github.com/bignyap/go-admin/internal/gatekeeper/service/GateKeeping"
+	"github.com/bignyap/go-admin/internal/common/route"
 	"github.com/bignyap/go-utilities/logger/api"
 )
 
@@ -19,21 +19,62 @@ func (s *PubsubListener) UpdateEPMatcher() error {
 		if err := json.Unmarshal(payload, &evt); err != nil {
 			return s.logUnmarshalError(common.EndpointCreated, err)
 		}
-		s.Match.Add(gatekeeping.Endpoint{Path: evt.Path, Method: evt.Method, Code: evt.Code})
+		if err := s.Match.Add(matcherEndpoint(&evt)); err != nil {
+			s.Logger.Error("couldn't add the endpoint to the matcher", err, api.Field{Key: "event", Value: evt})
+			return err
+		}
+		if s.Upstreams != nil {
+			s.Upstreams.RouteEndpoint(evt.Code, evt.UpstreamID)
+		}
 		s.Logger.Info("endpoint added to matcher", api.Field{Key: "event", Value: evt})
 		return nil
 	})
 
+	s.asyncSubscribe(common.EndpointUpdated, func(ctx context.Context, payload []byte) error {
+		var evt common.EndpointUpdatedEvent
+		if err := json.Unmarshal(payload, &evt); err != nil {
+			return s.logUnmarshalError(common.EndpointUpdated, err)
+		}
+		if err := s.Match.Replace(evt.PreviousCode, matcherEndpoint(&evt.EndpointCreatedEvent)); err != nil {
+			s.Logger.Error("couldn't update the endpoint in the matcher", err, api.Field{Key: "event", Value: evt})
+			return err
+		}
+		if s.Upstreams != nil {
+			s.Upstreams.DropEndpoint(evt.PreviousCode)
+			s.Upstreams.RouteEndpoint(evt.Code, evt.UpstreamID)
+		}
+		// The permission and resource type of the endpoint are cached with it
+		for _, code := range []string{evt.PreviousCode, evt.Code} {
+			s.Cache.Invalidate(ctx, common.RedisKeyFormatter(string(common.EndpointPrefix), code))
+		}
+		s.Logger.Info("endpoint updated in matcher", api.Field{Key: "event", Value: evt})
+		return nil
+	})
+
 	s.asyncSubscribe(common.EndpointDeleted, func(ctx context.Context, payload []byte) error {
 		var evt common.EndpointDeletedEvent
 		if err := json.Unmarshal(payload, &evt); err != nil {
 			return s.logUnmarshalError(common.EndpointDeleted, err)
 		}
 		s.Match.Drop(evt.Code)
+		if s.Upstreams != nil {
+			s.Upstreams.DropEndpoint(evt.Code)
+		}
 		s.Logger.Info("endpoint removed from matcher", api.Field{Key: "event", Value: evt})
 		return nil
 	})
 
-	s.Logger.Info("subscribed to pubsub channels: endpoint:created, endpoint:deleted")
+	s.Logger.Info("subscribed to pubsub channels: endpoint:created, endpoint:updated, endpoint:deleted")
 	return nil
 }
+
+func matcherEndpoint(evt *common.EndpointCreatedEvent) route.Endpoint {
+	return route.Endpoint{
+		Path:     evt.Path,
+		Method:   evt.Method,
+		Code:     evt.Code,
+		Host:     evt.HostPattern,
+		Headers:  evt.HeaderMatchers,
+		Priority: evt.MatchPriority,
+	}
+}
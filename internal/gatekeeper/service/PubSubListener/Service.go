@@ -2,6 +2,7 @@ package pubsublistener
 
 import (
 	"github.com/bignyap/go-admin/internal/caching"
+	"github.com/bignyap/go-admin/internal/common/route"
 	gatekeeping "github.com/bignyap/go-admin/internal/gatekeeper/service/GateKeeping"
 	"github.com/bignyap/go-utilities/logger/api"
 	"github.com/bignyap/go-utilities/pubsub"
@@ -10,20 +11,24 @@ import (
 type PubsubListener struct {
 	Logger api.Logger
 	Cache  *caching.CacheController
-	Match  *gatekeeping.Matcher
+	Match  *route.Matcher
 	PubSub pubsub.PubSubClient
+	// Nil unless GateKeeper runs as a proxy
+	Upstreams *gatekeeping.UpstreamRouter
 }
 
 func NewPubSubListener(
 	logger api.Logger,
 	cache *caching.CacheController,
-	matcher *gatekeeping.Matcher,
+	matcher *route.Matcher,
 	pubsubClient pubsub.PubSubClient,
+	upstreams *gatekeeping.UpstreamRouter,
 ) *PubsubListener {
 	return &PubsubListener{
-		Logger: logger,
-		Cache:  cache,
-		Match:  matcher,
-		PubSub: pubsubClient,
+		Logger:    logger,
+		Cache:     cache,
+		Match:     matcher,
+		PubSub:    pubsubClient,
+		Upstreams: upstreams,
 	}
 }
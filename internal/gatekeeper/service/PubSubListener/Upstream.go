@@ -0,0 +1,31 @@
+package pubsublistener
+
+import (
+	"context"
+	"encoding/json"
+
+	"github.com/bignyap/go-admin/internal/common"
+	"github.com/bignyap/go-utilities/logger/api"
+)
+
+func (s *PubsubListener) UpdateUpstreams() error {
+	if s.PubSub == nil || s.Upstreams == nil {
+		return nil
+	}
+
+	s.asyncSubscribe(common.UpstreamModified, func(ctx context.Context, payload []byte) error {
+		var evt common.UpstreamModifiedEvent
+		if err := json.Unmarshal(payload, &evt); err != nil {
+			return s.logUnmarshalError(common.UpstreamModified, err)
+		}
+		if err := s.Upstreams.Reload(ctx); err != nil {
+			s.Logger.Error("couldn't reload upstreams", err)
+			return err
+		}
+		s.Logger.Info("upstreams reloaded", api.Field{Key: "event", Value: evt})
+		return nil
+	})
+
+	s.Logger.Info("subscribed to pubsub channels: upstream:modified")
+	return nil
+}
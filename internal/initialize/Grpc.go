@@ -0,0 +1,125 @@
+package initialize
+
+import (
+	"context"
+	"fmt"
+	"net"
+	"os"
+	"os/signal"
+	"syscall"
+
+	"github.com/bignyap/go-utilities/logger/api"
+	"github.com/bignyap/go-utilities/server"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/health"
+	healthpb "google.golang.org/grpc/health/grpc_health_v1"
+	"google.golang.org/grpc/reflection"
+)
+
+// GRPCHandler is implemented by the services that expose a gRPC API.
+// go-utilities' GRPCServer never hands its grpc.Server to the handlers (and
+// calls Setup with a nil server), so we run our own.
+type GRPCHandler interface {
+	server.Handler
+	RegisterGRPC(registrar grpc.ServiceRegistrar) error
+}
+
+type GRPCServer struct {
+	config     *server.Config
+	logger     api.Logger
+	handler    GRPCHandler
+	grpcServer *grpc.Server
+	health     *health.Server
+}
+
+func NewGRPCServer(config *server.Config, logger api.Logger, srvc server.Handler) (*GRPCServer, error) {
+
+	handler, ok := srvc.(GRPCHandler)
+	if !ok {
+		return nil, fmt.Errorf("%T does not expose a gRPC API", srvc)
+	}
+
+	opts := []grpc.ServerOption{}
+	if config.MaxRequestSize > 0 {
+		opts = append(opts, grpc.MaxRecvMsgSize(int(config.MaxRequestSize)))
+	}
+
+	return &GRPCServer{
+		config:     config,
+		logger:     logger,
+		handler:    handler,
+		grpcServer: grpc.NewServer(opts...),
+		health:     health.NewServer(),
+	}, nil
+}
+
+func (s *GRPCServer) Start() error {
+
+	if err := s.handler.RegisterGRPC(s.grpcServer); err != nil {
+		return fmt.Errorf("gRPC handler setup failed: %w", err)
+	}
+
+	// Health checks report every registered service plus the overall "" entry
+	healthpb.RegisterHealthServer(s.grpcServer, s.health)
+	for name := range s.grpcServer.GetServiceInfo() {
+		s.health.SetServingStatus(name, healthpb.HealthCheckResponse_SERVING)
+	}
+	s.health.SetServingStatus("", healthpb.HealthCheckResponse_SERVING)
+
+	reflection.Register(s.grpcServer)
+
+	lis, err := net.Listen("tcp", ":"+s.config.Port)
+	if err != nil {
+		return fmt.Errorf("failed to listen: %w", err)
+	}
+
+	s.logger.WithFields(
+		api.String("port", s.config.Port),
+		api.String("env", s.config.Environment),
+		api.String("version", s.config.Version),
+	).Info("Starting gRPC server")
+
+	go func() {
+		if err := s.grpcServer.Serve(lis); err != nil {
+			s.logger.Error("gRPC server failed", err)
+		}
+	}()
+
+	return s.waitForShutdown()
+}
+
+func (s *GRPCServer) waitForShutdown() error {
+	quit := make(chan os.Signal, 1)
+	signal.Notify(quit, syscall.SIGINT, syscall.SIGTERM)
+	<-quit
+
+	s.logger.Info("Shutdown signal received for gRPC")
+
+	ctx, cancel := context.WithTimeout(context.Background(), s.config.ShutdownTimeout)
+	defer cancel()
+	return s.Shutdown(ctx)
+}
+
+func (s *GRPCServer) Shutdown(ctx context.Context) error {
+
+	// Let load balancers drain before the listener goes away
+	s.health.Shutdown()
+
+	stopped := make(chan struct{})
+	go func() {
+		s.grpcServer.GracefulStop()
+		close(stopped)
+	}()
+	select {
+	case <-stopped:
+	case <-ctx.Done():
+		s.grpcServer.Stop()
+	}
+
+	if err := s.handler.Shutdown(); err != nil {
+		s.logger.Error("Handler shutdown error", err)
+	}
+
+	s.logger.Info("gRPC server shut down cleanly")
+	return nil
+}
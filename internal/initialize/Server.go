@@ -34,7 +34,7 @@ func InitializeWebServer(serverType server.ServerType, logger api.Logger, srvc s
 		},
 	)
 
-	var srv server.Server
+	var srv interface{ Start() error }
 	switch serverType {
 	case server.ServerHTTP:
 		srv = server.NewHTTPServer(config,
@@ -42,10 +42,11 @@ func InitializeWebServer(serverType server.ServerType, logger api.Logger, srvc s
 			server.WithHandler(srvc),
 		)
 	case server.ServerGRPC:
-		srv = server.NewGRPCServer(config,
-			server.WithLogger(logger),
-			server.WithHandler(srvc),
-		)
+		grpcSrv, err := NewGRPCServer(config, logger, srvc)
+		if err != nil {
+			return err
+		}
+		srv = grpcSrv
 	default:
 		return fmt.Errorf("unsupported server type: %s", serverType)
 	}
@@ -31,8 +31,10 @@ func EndpointHandler(r *gin.RouterGroup, h *adminHandler.AdminHandler) {
 	routerGrp := r.Group("/apiEndpoint")
 	routerGrp.POST("", h.RegisterEndpointHandler)
 	routerGrp.POST("/batch", h.RegisterEndpointInBatchHandler)
+	routerGrp.PUT("/:Id", h.UpdateEndpointHandler)
 	routerGrp.DELETE("/:Id", h.DeleteEndpointsByIdHandler)
 	routerGrp.GET("", h.ListEndpointsHandler)
+	routerGrp.GET("/match", h.MatchEndpointHandler)
 }
 
 func OrganizationHandler(r *gin.RouterGroup, h *adminHandler.AdminHandler) {
@@ -45,6 +47,13 @@ func OrganizationHandler(r *gin.RouterGroup, h *adminHandler.AdminHandler) {
 	routerGrp.PUT("", h.UpdateOrganizationandler)
 }
 
+func ApiKeyHandler(r *gin.RouterGroup, h *adminHandler.AdminHandler) {
+	routerGrp := r.Group("/apiKey")
+	routerGrp.POST("", h.CreateApiKeyHandler)
+	routerGrp.GET("/orgId/:organization_id", h.ListApiKeysByOrgIdHandler)
+	routerGrp.DELETE("/:Id", h.RevokeApiKeyHandler)
+}
+
 func TierPricingHandler(r *gin.RouterGroup, h *adminHandler.AdminHandler) {
 	routerGrp := r.Group("/tierPricing")
 	routerGrp.POST("", h.CreateTierPricingHandler)
@@ -80,6 +89,16 @@ func ResourceTypeHandler(r *gin.RouterGroup, h *adminHandler.AdminHandler) {
 	routerGrp.POST("/batch", h.CreateResurceTypeInBatchHandler)
 	routerGrp.DELETE("/:id", h.DeleteResourceTypeHandler)
 	routerGrp.GET("", h.ListResourceTypeHandler)
+	routerGrp.PUT("/:id/upstream", h.SetResourceTypeUpstreamHandler)
+}
+
+func UpstreamHandler(r *gin.RouterGroup, h *adminHandler.AdminHandler) {
+	routerGrp := r.Group("/upstream")
+	routerGrp.POST("", h.CreateUpstreamHandler)
+	routerGrp.GET("", h.ListUpstreamsHandler)
+	routerGrp.GET("/:id", h.GetUpstreamHandler)
+	routerGrp.PUT("/:id", h.UpdateUpstreamHandler)
+	routerGrp.DELETE("/:id", h.DeleteUpstreamHandler)
 }
 
 func PermissionTypeHandler(r *gin.RouterGroup, h *adminHandler.AdminHandler) {
@@ -114,6 +133,11 @@ func ApiUsageSummaryHandler(r *gin.RouterGroup, h *adminHandler.AdminHandler) {
 	routerGrp.GET("", h.GetApiUsageSummaryHandler)
 }
 
+func ShadowDenialHandler(r *gin.RouterGroup, h *adminHandler.AdminHandler) {
+	routerGrp := r.Group("/shadowDenial")
+	routerGrp.GET("", h.GetShadowDenialSummaryHandler)
+}
+
 func DashboardHandler(r *gin.RouterGroup, h *adminHandler.AdminHandler) {
 	routerGrp := r.Group("/dashboard")
 	routerGrp.GET("/counts", h.DashboardCountHandler)
@@ -142,14 +166,17 @@ func RegisterAdminHandlers(
 	SubTierHandler(adminGrpRouter, handler)
 	EndpointHandler(adminGrpRouter, handler)
 	OrganizationHandler(adminGrpRouter, handler)
+	ApiKeyHandler(adminGrpRouter, handler)
 	TierPricingHandler(adminGrpRouter, handler)
 	SubscriptionHandler(adminGrpRouter, handler)
 	CustomPricingHandler(adminGrpRouter, handler)
 	ResourceTypeHandler(adminGrpRouter, handler)
+	UpstreamHandler(adminGrpRouter, handler)
 	PermissionTypeHandler(adminGrpRouter, handler)
 	OrgPermissionHandler(adminGrpRouter, handler)
 	BillingHistoryHandler(adminGrpRouter, handler)
 	ApiUsageSummaryHandler(adminGrpRouter, handler)
+	ShadowDenialHandler(adminGrpRouter, handler)
 	DashboardHandler(adminGrpRouter, handler)
 
 	regRouterLogger.Info("Completed")
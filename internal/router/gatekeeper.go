@@ -2,11 +2,12 @@
 package router
 
 import (
+	"context"
 	"net/http"
 	"net/http/httputil"
-	"net/url"
 
 	"github.com/bignyap/go-admin/internal/caching"
+	"github.com/bignyap/go-admin/internal/common/route"
 	"github.com/bignyap/go-admin/internal/database/sqlcgen"
 	gateKeeperHandler "github.com/bignyap/go-admin/internal/gatekeeper/handler"
 	gatekeeping "github.com/bignyap/go-admin/internal/gatekeeper/service/GateKeeping"
@@ -16,12 +17,14 @@ import (
 	"github.com/gin-gonic/gin"
 	"github.com/go-playground/validator"
 	"github.com/jackc/pgx/v5/pgxpool"
+	"github.com/prometheus/client_golang/prometheus/promhttp"
 )
 
 func RegisterAuthMiddlewareRoutes(rg *gin.RouterGroup, h *gateKeeperHandler.GateKeeperHandler) {
 
 	ValidateRequestHandler(rg, h)
 	UsageRecorderHandler(rg, h)
+	ReservationHandler(rg, h)
 	FlushCacheHandler(rg, h)
 }
 
@@ -33,51 +36,96 @@ func UsageRecorderHandler(rg *gin.RouterGroup, h *gateKeeperHandler.GateKeeperHa
 	rg.POST("/recordUsage", h.UsageRecorderHandler)
 }
 
+func ReservationHandler(rg *gin.RouterGroup, h *gateKeeperHandler.GateKeeperHandler) {
+	rg.POST("/validateAndRecord", h.ValidateAndRecordHandler)
+	rg.POST("/reservation/commit", h.CommitReservationHandler)
+	rg.POST("/reservation/cancel", h.CancelReservationHandler)
+}
+
 func FlushCacheHandler(rg *gin.RouterGroup, h *gateKeeperHandler.GateKeeperHandler) {
 	rg.DELETE("/flushAllCache", h.FlushAllCacheHandler)
 }
 
+// RegisterForwardAuthRoutes serves the subrequests of NGINX auth_request and
+// Traefik ForwardAuth, which send no body and describe the gated request in
+// headers instead.
+func RegisterForwardAuthRoutes(rg *gin.RouterGroup, h *gateKeeperHandler.GateKeeperHandler) {
+	rg.GET("/auth", h.ForwardAuthHandler)
+	rg.HEAD("/auth", h.ForwardAuthHandler)
+}
+
 func RegisterMiddlewareRoutes(rg *gin.RouterGroup, h *gateKeeperHandler.GateKeeperHandler) {
 
 	rg.Use(func(c *gin.Context) {
-		if _, err := h.ValidateRequestCore(c); err != nil {
-			c.AbortWithStatusJSON(http.StatusForbidden, gin.H{"error": err.Error()})
+		input, output, err := h.ValidateIncomingRequestCore(c)
+		if err != nil {
+			h.AbortRequest(c, err)
 			return
 		}
+		h.CaptureUsageUnits(c, output)
 		c.Next()
 		if c.Writer.Status() < 400 {
-			_, _, _ = h.UsageRecorderCore(c)
+			_, _ = h.RecordIncomingUsageCore(c, input, output)
+		} else {
+			h.ReleaseIncomingHold(c, output)
 		}
 	})
 
 }
 
-func RegisterProxyRoutes(rg *gin.RouterGroup, h *gateKeeperHandler.GateKeeperHandler, proxyTarget string) {
-	backendURL, err := url.Parse(proxyTarget)
-	if err != nil {
-		panic("invalid proxy target")
-	}
-	proxy := httputil.NewSingleHostReverseProxy(backendURL)
+type upstreamKey struct{}
+
+// upstreamTransport hands the request to the upstream picked for it, which
+// chooses the target and applies its timeouts and retries.
+type upstreamTransport struct{}
+
+func (upstreamTransport) RoundTrip(req *http.Request) (*http.Response, error) {
+	return req.Context().Value(upstreamKey{}).(*gatekeeping.Upstream).RoundTrip(req)
+}
+
+// RegisterProxyRoutes forwards every request that doesn't hit a /gatekeeper
+// route to the upstream of its endpoint. It hangs off NoRoute because a
+// catch-all route would clash with the routes registered under /gatekeeper.
+func RegisterProxyRoutes(router *gin.Engine, h *gateKeeperHandler.GateKeeperHandler, upstreams *gatekeeping.UpstreamRouter) {
 
-	// Optionally preserve the original host header
-	proxy.Director = func(req *http.Request) {
-		req.URL.Scheme = backendURL.Scheme
-		req.URL.Host = backendURL.Host
-		req.Host = backendURL.Host
+	proxy := &httputil.ReverseProxy{
+		// The upstream sets the target host on each attempt
+		Director:     func(req *http.Request) {},
+		Transport:    upstreamTransport{},
+		ErrorHandler: h.ProxyErrorHandler,
 	}
 
-	rg.Use(func(c *gin.Context) {
-		if _, err := h.ValidateRequestCore(c); err != nil {
-			c.AbortWithStatusJSON(http.StatusForbidden, gin.H{"error": err.Error()})
+	router.NoRoute(func(c *gin.Context) {
+		input, output, err := h.ValidateIncomingRequestCore(c)
+		if err != nil {
+			h.AbortRequest(c, err)
 			return
 		}
 
+		upstream, ok := upstreams.Route(output.Endpoint.EndpointName, output.Endpoint.ResourceTypeID)
+		if !ok {
+			h.ReleaseIncomingHold(c, output)
+			c.AbortWithStatusJSON(http.StatusBadGateway, gin.H{"error": "no upstream serves this endpoint"})
+			return
+		}
+
+		h.CaptureUsageUnits(c, output)
+		// NoRoute starts out as a 404, which a WebSocket upgrade never
+		// overwrites since the connection is hijacked
+		c.Status(http.StatusOK)
+
 		// Replace Gin context writer with http.ResponseWriter proxy needs
-		proxy.ServeHTTP(c.Writer, c.Request)
+		// A deep copy, the transforms must not touch the request gin holds
+		req := c.Request.Clone(context.WithValue(c.Request.Context(), upstreamKey{}, upstream))
+		upstreams.Transform(req, upstream, input.Path, output)
+		proxy.ServeHTTP(c.Writer, req)
 
-		// Gin will not proceed to c.Next() after ServeHTTP, so post-processing must be here
+		// Gin will not proceed to c.Next() after ServeHTTP, so post-processing must be here.
+		// Upstream failures, an open circuit included, are >= 500 and never billed
 		if c.Writer.Status() < 400 {
-			_, _, _ = h.UsageRecorderCore(c)
+			_, _ = h.RecordIncomingUsageCore(c, input, output)
+		} else {
+			h.ReleaseIncomingHold(c, output)
 		}
 	})
 }
@@ -89,11 +137,17 @@ func RegisterGateKeeperHandlers(
 	db *sqlcgen.Queries,
 	conn *pgxpool.Pool,
 	validator *validator.Validate,
-	matcher *gatekeeping.Matcher,
+	matcher *route.Matcher,
 	cacheContoller *caching.CacheController,
 	counter *counter.CounterWorker,
+	rateLimiter *gatekeeping.RateLimiter,
+	reservations *gatekeeping.ReservationStore,
+	idempotency *gatekeeping.IdempotencyStore,
+	jwtValidator *gatekeeping.JWTValidator,
+	identity *gatekeeping.OrgIdentity,
+	upstreams *gatekeeping.UpstreamRouter,
 	mode string,
-	target string,
+	enforcement string,
 	flushInterval int64,
 ) {
 
@@ -101,7 +155,7 @@ func RegisterGateKeeperHandlers(
 	regRouterLogger.Info("Starting")
 
 	h := gateKeeperHandler.NewGateKeeperHandler(
-		logger, rw, db, conn, validator, cacheContoller, matcher, counter, flushInterval,
+		logger, rw, db, conn, validator, cacheContoller, matcher, counter, rateLimiter, reservations, idempotency, jwtValidator, identity, enforcement, flushInterval,
 	)
 
 	rg := router.Group("/gatekeeper")
@@ -109,12 +163,15 @@ func RegisterGateKeeperHandlers(
 	rg.GET("/status", func(c *gin.Context) {
 		c.JSON(http.StatusOK, gin.H{"status": "OK"})
 	})
+	rg.GET("/metrics", gin.WrapH(promhttp.Handler()))
 
 	switch mode {
 	case "auth-middleware":
 		RegisterAuthMiddlewareRoutes(rg, h)
+	case "forward-auth":
+		RegisterForwardAuthRoutes(rg, h)
 	case "proxy":
-		RegisterProxyRoutes(rg, h, target)
+		RegisterProxyRoutes(router, h, upstreams)
 	default:
 		RegisterMiddlewareRoutes(rg, h)
 	}
@@ -0,0 +1,1325 @@
+// Code generated by protoc-gen-go. DO NOT EDIT.
+// versions:
+// 	protoc-gen-go v1.36.6
+// 	protoc        v5.29.3
+// source: proto/gatekeeper.proto
+
+package proto
+
+import (
+	protoreflect "google.golang.org/protobuf/reflect/protoreflect"
+	protoimpl "google.golang.org/protobuf/runtime/protoimpl"
+	durationpb "google.golang.org/protobuf/types/known/durationpb"
+	structpb "google.golang.org/protobuf/types/known/structpb"
+	timestamppb "google.golang.org/protobuf/types/known/timestamppb"
+	reflect "reflect"
+	sync "sync"
+	unsafe "unsafe"
+)
+
+const (
+	// Verify that this generated code is sufficiently up-to-date.
+	_ = protoimpl.EnforceVersion(20 - protoimpl.MinVersion)
+	// Verify that runtime/protoimpl is sufficiently up-to-date.
+	_ = protoimpl.EnforceVersion(protoimpl.MaxVersion - 20)
+)
+
+type DenialReason int32
+
+const (
+	DenialReason_DENIAL_REASON_UNSPECIFIED          DenialReason = 0
+	DenialReason_DENIAL_REASON_UNKNOWN_ENDPOINT     DenialReason = 1
+	DenialReason_DENIAL_REASON_ORG_NOT_FOUND        DenialReason = 2
+	DenialReason_DENIAL_REASON_PERMISSION_DENIED    DenialReason = 3
+	DenialReason_DENIAL_REASON_NO_SUBSCRIPTION      DenialReason = 4
+	DenialReason_DENIAL_REASON_SUBSCRIPTION_EXPIRED DenialReason = 5
+	DenialReason_DENIAL_REASON_QUOTA_EXCEEDED       DenialReason = 6
+	DenialReason_DENIAL_REASON_RATE_LIMITED         DenialReason = 7
+	DenialReason_DENIAL_REASON_INVALID_CREDENTIALS  DenialReason = 8
+	DenialReason_DENIAL_REASON_INSUFFICIENT_SCOPE   DenialReason = 9
+)
+
+// Enum value maps for DenialReason.
+var (
+	DenialReason_name = map[int32]string{
+		0: "DENIAL_REASON_UNSPECIFIED",
+		1: "DENIAL_REASON_UNKNOWN_ENDPOINT",
+		2: "DENIAL_REASON_ORG_NOT_FOUND",
+		3: "DENIAL_REASON_PERMISSION_DENIED",
+		4: "DENIAL_REASON_NO_SUBSCRIPTION",
+		5: "DENIAL_REASON_SUBSCRIPTION_EXPIRED",
+		6: "DENIAL_REASON_QUOTA_EXCEEDED",
+		7: "DENIAL_REASON_RATE_LIMITED",
+		8: "DENIAL_REASON_INVALID_CREDENTIALS",
+		9: "DENIAL_REASON_INSUFFICIENT_SCOPE",
+	}
+	DenialReason_value = map[string]int32{
+		"DENIAL_REASON_UNSPECIFIED":          0,
+		"DENIAL_REASON_UNKNOWN_ENDPOINT":     1,
+		"DENIAL_REASON_ORG_NOT_FOUND":        2,
+		"DENIAL_REASON_PERMISSION_DENIED":    3,
+		"DENIAL_REASON_NO_SUBSCRIPTION":      4,
+		"DENIAL_REASON_SUBSCRIPTION_EXPIRED": 5,
+		"DENIAL_REASON_QUOTA_EXCEEDED":       6,
+		"DENIAL_REASON_RATE_LIMITED":         7,
+		"DENIAL_REASON_INVALID_CREDENTIALS":  8,
+		"DENIAL_REASON_INSUFFICIENT_SCOPE":   9,
+	}
+)
+
+func (x DenialReason) Enum() *DenialReason {
+	p := new(DenialReason)
+	*p = x
+	return p
+}
+
+func (x DenialReason) String() string {
+	return protoimpl.X.EnumStringOf(x.Descriptor(), protoreflect.EnumNumber(x))
+}
+
+func (DenialReason) Descriptor() protoreflect.EnumDescriptor {
+	return file_proto_gatekeeper_proto_enumTypes[0].Descriptor()
+}
+
+func (DenialReason) Type() protoreflect.EnumType {
+	return &file_proto_gatekeeper_proto_enumTypes[0]
+}
+
+func (x DenialReason) Number() protoreflect.EnumNumber {
+	return protoreflect.EnumNumber(x)
+}
+
+// Deprecated: Use DenialReason.Descriptor instead.
+func (DenialReason) EnumDescriptor() ([]byte, []int) {
+	return file_proto_gatekeeper_proto_rawDescGZIP(), []int{0}
+}
+
+type RecordUsageRequest struct {
+	state            protoimpl.MessageState `protogen:"open.v1"`
+	Method           string                 `protobuf:"bytes,1,opt,name=method,proto3" json:"method,omitempty"`
+	Path             string                 `protobuf:"bytes,2,opt,name=path,proto3" json:"path,omitempty"`
+	OrganizationName string                 `protobuf:"bytes,3,opt,name=organization_name,json=organizationName,proto3" json:"organization_name,omitempty"`
+	// Host of the gated request, read by the subdomain organization extractor
+	// and matched against the host pattern of the endpoints
+	Host string `protobuf:"bytes,4,opt,name=host,proto3" json:"host,omitempty"`
+	// hold_id returned by validate, settles the credit hold of the request
+	HoldId string `protobuf:"bytes,5,opt,name=hold_id,json=holdId,proto3" json:"hold_id,omitempty"`
+	// Retries with the same key are billed once, the cost of the first call is
+	// returned. At most 255 characters
+	IdempotencyKey string `protobuf:"bytes,6,opt,name=idempotency_key,json=idempotencyKey,proto3" json:"idempotency_key,omitempty"`
+	// Multiplies the cost of dynamic pricing, e.g. the tokens of an LLM call.
+	// The call counts as one unit when unset
+	UsageUnits    *float64 `protobuf:"fixed64,7,opt,name=usage_units,json=usageUnits,proto3,oneof" json:"usage_units,omitempty"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *RecordUsageRequest) Reset() {
+	*x = RecordUsageRequest{}
+	mi := &file_proto_gatekeeper_proto_msgTypes[0]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *RecordUsageRequest) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*RecordUsageRequest) ProtoMessage() {}
+
+func (x *RecordUsageRequest) ProtoReflect() protoreflect.Message {
+	mi := &file_proto_gatekeeper_proto_msgTypes[0]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use RecordUsageRequest.ProtoReflect.Descriptor instead.
+func (*RecordUsageRequest) Descriptor() ([]byte, []int) {
+	return file_proto_gatekeeper_proto_rawDescGZIP(), []int{0}
+}
+
+func (x *RecordUsageRequest) GetMethod() string {
+	if x != nil {
+		return x.Method
+	}
+	return ""
+}
+
+func (x *RecordUsageRequest) GetPath() string {
+	if x != nil {
+		return x.Path
+	}
+	return ""
+}
+
+func (x *RecordUsageRequest) GetOrganizationName() string {
+	if x != nil {
+		return x.OrganizationName
+	}
+	return ""
+}
+
+func (x *RecordUsageRequest) GetHost() string {
+	if x != nil {
+		return x.Host
+	}
+	return ""
+}
+
+func (x *RecordUsageRequest) GetHoldId() string {
+	if x != nil {
+		return x.HoldId
+	}
+	return ""
+}
+
+func (x *RecordUsageRequest) GetIdempotencyKey() string {
+	if x != nil {
+		return x.IdempotencyKey
+	}
+	return ""
+}
+
+func (x *RecordUsageRequest) GetUsageUnits() float64 {
+	if x != nil && x.UsageUnits != nil {
+		return *x.UsageUnits
+	}
+	return 0
+}
+
+type RecordUsageResponse struct {
+	state         protoimpl.MessageState `protogen:"open.v1"`
+	Cost          float64                `protobuf:"fixed64,1,opt,name=cost,proto3" json:"cost,omitempty"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *RecordUsageResponse) Reset() {
+	*x = RecordUsageResponse{}
+	mi := &file_proto_gatekeeper_proto_msgTypes[1]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *RecordUsageResponse) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*RecordUsageResponse) ProtoMessage() {}
+
+func (x *RecordUsageResponse) ProtoReflect() protoreflect.Message {
+	mi := &file_proto_gatekeeper_proto_msgTypes[1]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use RecordUsageResponse.ProtoReflect.Descriptor instead.
+func (*RecordUsageResponse) Descriptor() ([]byte, []int) {
+	return file_proto_gatekeeper_proto_rawDescGZIP(), []int{1}
+}
+
+func (x *RecordUsageResponse) GetCost() float64 {
+	if x != nil {
+		return x.Cost
+	}
+	return 0
+}
+
+// Kept for existing clients, new ones should use AuthorizeRequest.
+//
+// Deprecated: Marked as deprecated in proto/gatekeeper.proto.
+type ValidateRequestRequest struct {
+	state            protoimpl.MessageState `protogen:"open.v1"`
+	OrganizationName string                 `protobuf:"bytes,1,opt,name=organization_name,json=organizationName,proto3" json:"organization_name,omitempty"`
+	Method           string                 `protobuf:"bytes,3,opt,name=method,proto3" json:"method,omitempty"`
+	Path             string                 `protobuf:"bytes,4,opt,name=path,proto3" json:"path,omitempty"`
+	unknownFields    protoimpl.UnknownFields
+	sizeCache        protoimpl.SizeCache
+}
+
+func (x *ValidateRequestRequest) Reset() {
+	*x = ValidateRequestRequest{}
+	mi := &file_proto_gatekeeper_proto_msgTypes[2]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *ValidateRequestRequest) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*ValidateRequestRequest) ProtoMessage() {}
+
+func (x *ValidateRequestRequest) ProtoReflect() protoreflect.Message {
+	mi := &file_proto_gatekeeper_proto_msgTypes[2]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use ValidateRequestRequest.ProtoReflect.Descriptor instead.
+func (*ValidateRequestRequest) Descriptor() ([]byte, []int) {
+	return file_proto_gatekeeper_proto_rawDescGZIP(), []int{2}
+}
+
+func (x *ValidateRequestRequest) GetOrganizationName() string {
+	if x != nil {
+		return x.OrganizationName
+	}
+	return ""
+}
+
+func (x *ValidateRequestRequest) GetMethod() string {
+	if x != nil {
+		return x.Method
+	}
+	return ""
+}
+
+func (x *ValidateRequestRequest) GetPath() string {
+	if x != nil {
+		return x.Path
+	}
+	return ""
+}
+
+// Kept for existing clients, new ones should use Decision.
+//
+// Deprecated: Marked as deprecated in proto/gatekeeper.proto.
+type ValidateRequestResponse struct {
+	state         protoimpl.MessageState `protogen:"open.v1"`
+	Organization  *structpb.Struct       `protobuf:"bytes,1,opt,name=organization,proto3" json:"organization,omitempty"`
+	Endpoint      *structpb.Struct       `protobuf:"bytes,2,opt,name=endpoint,proto3" json:"endpoint,omitempty"`
+	Subscription  *structpb.Struct       `protobuf:"bytes,3,opt,name=subscription,proto3" json:"subscription,omitempty"`
+	Remaining     int64                  `protobuf:"varint,4,opt,name=remaining,proto3" json:"remaining,omitempty"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *ValidateRequestResponse) Reset() {
+	*x = ValidateRequestResponse{}
+	mi := &file_proto_gatekeeper_proto_msgTypes[3]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *ValidateRequestResponse) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*ValidateRequestResponse) ProtoMessage() {}
+
+func (x *ValidateRequestResponse) ProtoReflect() protoreflect.Message {
+	mi := &file_proto_gatekeeper_proto_msgTypes[3]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use ValidateRequestResponse.ProtoReflect.Descriptor instead.
+func (*ValidateRequestResponse) Descriptor() ([]byte, []int) {
+	return file_proto_gatekeeper_proto_rawDescGZIP(), []int{3}
+}
+
+func (x *ValidateRequestResponse) GetOrganization() *structpb.Struct {
+	if x != nil {
+		return x.Organization
+	}
+	return nil
+}
+
+func (x *ValidateRequestResponse) GetEndpoint() *structpb.Struct {
+	if x != nil {
+		return x.Endpoint
+	}
+	return nil
+}
+
+func (x *ValidateRequestResponse) GetSubscription() *structpb.Struct {
+	if x != nil {
+		return x.Subscription
+	}
+	return nil
+}
+
+func (x *ValidateRequestResponse) GetRemaining() int64 {
+	if x != nil {
+		return x.Remaining
+	}
+	return 0
+}
+
+type Organization struct {
+	state         protoimpl.MessageState `protogen:"open.v1"`
+	Id            int32                  `protobuf:"varint,1,opt,name=id,proto3" json:"id,omitempty"`
+	Name          string                 `protobuf:"bytes,2,opt,name=name,proto3" json:"name,omitempty"`
+	Realm         string                 `protobuf:"bytes,3,opt,name=realm,proto3" json:"realm,omitempty"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *Organization) Reset() {
+	*x = Organization{}
+	mi := &file_proto_gatekeeper_proto_msgTypes[4]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *Organization) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*Organization) ProtoMessage() {}
+
+func (x *Organization) ProtoReflect() protoreflect.Message {
+	mi := &file_proto_gatekeeper_proto_msgTypes[4]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use Organization.ProtoReflect.Descriptor instead.
+func (*Organization) Descriptor() ([]byte, []int) {
+	return file_proto_gatekeeper_proto_rawDescGZIP(), []int{4}
+}
+
+func (x *Organization) GetId() int32 {
+	if x != nil {
+		return x.Id
+	}
+	return 0
+}
+
+func (x *Organization) GetName() string {
+	if x != nil {
+		return x.Name
+	}
+	return ""
+}
+
+func (x *Organization) GetRealm() string {
+	if x != nil {
+		return x.Realm
+	}
+	return ""
+}
+
+type Endpoint struct {
+	state            protoimpl.MessageState `protogen:"open.v1"`
+	Id               int32                  `protobuf:"varint,1,opt,name=id,proto3" json:"id,omitempty"`
+	Code             string                 `protobuf:"bytes,2,opt,name=code,proto3" json:"code,omitempty"`
+	Method           string                 `protobuf:"bytes,3,opt,name=method,proto3" json:"method,omitempty"`
+	PathTemplate     string                 `protobuf:"bytes,4,opt,name=path_template,json=pathTemplate,proto3" json:"path_template,omitempty"`
+	ResourceTypeId   int32                  `protobuf:"varint,5,opt,name=resource_type_id,json=resourceTypeId,proto3" json:"resource_type_id,omitempty"`
+	ResourceTypeName string                 `protobuf:"bytes,6,opt,name=resource_type_name,json=resourceTypeName,proto3" json:"resource_type_name,omitempty"`
+	PermissionCode   string                 `protobuf:"bytes,7,opt,name=permission_code,json=permissionCode,proto3" json:"permission_code,omitempty"`
+	AccessType       string                 `protobuf:"bytes,8,opt,name=access_type,json=accessType,proto3" json:"access_type,omitempty"`
+	unknownFields    protoimpl.UnknownFields
+	sizeCache        protoimpl.SizeCache
+}
+
+func (x *Endpoint) Reset() {
+	*x = Endpoint{}
+	mi := &file_proto_gatekeeper_proto_msgTypes[5]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *Endpoint) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*Endpoint) ProtoMessage() {}
+
+func (x *Endpoint) ProtoReflect() protoreflect.Message {
+	mi := &file_proto_gatekeeper_proto_msgTypes[5]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use Endpoint.ProtoReflect.Descriptor instead.
+func (*Endpoint) Descriptor() ([]byte, []int) {
+	return file_proto_gatekeeper_proto_rawDescGZIP(), []int{5}
+}
+
+func (x *Endpoint) GetId() int32 {
+	if x != nil {
+		return x.Id
+	}
+	return 0
+}
+
+func (x *Endpoint) GetCode() string {
+	if x != nil {
+		return x.Code
+	}
+	return ""
+}
+
+func (x *Endpoint) GetMethod() string {
+	if x != nil {
+		return x.Method
+	}
+	return ""
+}
+
+func (x *Endpoint) GetPathTemplate() string {
+	if x != nil {
+		return x.PathTemplate
+	}
+	return ""
+}
+
+func (x *Endpoint) GetResourceTypeId() int32 {
+	if x != nil {
+		return x.ResourceTypeId
+	}
+	return 0
+}
+
+func (x *Endpoint) GetResourceTypeName() string {
+	if x != nil {
+		return x.ResourceTypeName
+	}
+	return ""
+}
+
+func (x *Endpoint) GetPermissionCode() string {
+	if x != nil {
+		return x.PermissionCode
+	}
+	return ""
+}
+
+func (x *Endpoint) GetAccessType() string {
+	if x != nil {
+		return x.AccessType
+	}
+	return ""
+}
+
+type Subscription struct {
+	state          protoimpl.MessageState `protogen:"open.v1"`
+	Id             int32                  `protobuf:"varint,1,opt,name=id,proto3" json:"id,omitempty"`
+	OrganizationId int32                  `protobuf:"varint,2,opt,name=organization_id,json=organizationId,proto3" json:"organization_id,omitempty"`
+	// 0 means unlimited
+	ApiLimit int32                  `protobuf:"varint,3,opt,name=api_limit,json=apiLimit,proto3" json:"api_limit,omitempty"`
+	StartsAt *timestamppb.Timestamp `protobuf:"bytes,4,opt,name=starts_at,json=startsAt,proto3" json:"starts_at,omitempty"`
+	// Unset when the subscription never expires
+	ExpiresAt          *timestamppb.Timestamp `protobuf:"bytes,5,opt,name=expires_at,json=expiresAt,proto3" json:"expires_at,omitempty"`
+	QuotaResetInterval string                 `protobuf:"bytes,6,opt,name=quota_reset_interval,json=quotaResetInterval,proto3" json:"quota_reset_interval,omitempty"`
+	Active             bool                   `protobuf:"varint,7,opt,name=active,proto3" json:"active,omitempty"`
+	unknownFields      protoimpl.UnknownFields
+	sizeCache          protoimpl.SizeCache
+}
+
+func (x *Subscription) Reset() {
+	*x = Subscription{}
+	mi := &file_proto_gatekeeper_proto_msgTypes[6]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *Subscription) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*Subscription) ProtoMessage() {}
+
+func (x *Subscription) ProtoReflect() protoreflect.Message {
+	mi := &file_proto_gatekeeper_proto_msgTypes[6]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use Subscription.ProtoReflect.Descriptor instead.
+func (*Subscription) Descriptor() ([]byte, []int) {
+	return file_proto_gatekeeper_proto_rawDescGZIP(), []int{6}
+}
+
+func (x *Subscription) GetId() int32 {
+	if x != nil {
+		return x.Id
+	}
+	return 0
+}
+
+func (x *Subscription) GetOrganizationId() int32 {
+	if x != nil {
+		return x.OrganizationId
+	}
+	return 0
+}
+
+func (x *Subscription) GetApiLimit() int32 {
+	if x != nil {
+		return x.ApiLimit
+	}
+	return 0
+}
+
+func (x *Subscription) GetStartsAt() *timestamppb.Timestamp {
+	if x != nil {
+		return x.StartsAt
+	}
+	return nil
+}
+
+func (x *Subscription) GetExpiresAt() *timestamppb.Timestamp {
+	if x != nil {
+		return x.ExpiresAt
+	}
+	return nil
+}
+
+func (x *Subscription) GetQuotaResetInterval() string {
+	if x != nil {
+		return x.QuotaResetInterval
+	}
+	return ""
+}
+
+func (x *Subscription) GetActive() bool {
+	if x != nil {
+		return x.Active
+	}
+	return false
+}
+
+type Quota struct {
+	state protoimpl.MessageState `protogen:"open.v1"`
+	// False when the subscription has no api limit, the other fields are then unset
+	Limited   bool  `protobuf:"varint,1,opt,name=limited,proto3" json:"limited,omitempty"`
+	Limit     int64 `protobuf:"varint,2,opt,name=limit,proto3" json:"limit,omitempty"`
+	Remaining int64 `protobuf:"varint,3,opt,name=remaining,proto3" json:"remaining,omitempty"`
+	// Unset when the quota never resets
+	ResetsAt      *timestamppb.Timestamp `protobuf:"bytes,4,opt,name=resets_at,json=resetsAt,proto3" json:"resets_at,omitempty"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *Quota) Reset() {
+	*x = Quota{}
+	mi := &file_proto_gatekeeper_proto_msgTypes[7]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *Quota) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*Quota) ProtoMessage() {}
+
+func (x *Quota) ProtoReflect() protoreflect.Message {
+	mi := &file_proto_gatekeeper_proto_msgTypes[7]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use Quota.ProtoReflect.Descriptor instead.
+func (*Quota) Descriptor() ([]byte, []int) {
+	return file_proto_gatekeeper_proto_rawDescGZIP(), []int{7}
+}
+
+func (x *Quota) GetLimited() bool {
+	if x != nil {
+		return x.Limited
+	}
+	return false
+}
+
+func (x *Quota) GetLimit() int64 {
+	if x != nil {
+		return x.Limit
+	}
+	return 0
+}
+
+func (x *Quota) GetRemaining() int64 {
+	if x != nil {
+		return x.Remaining
+	}
+	return 0
+}
+
+func (x *Quota) GetResetsAt() *timestamppb.Timestamp {
+	if x != nil {
+		return x.ResetsAt
+	}
+	return nil
+}
+
+type RateLimit struct {
+	state protoimpl.MessageState `protogen:"open.v1"`
+	// "organization" or "endpoint", whichever has the fewest requests left
+	Scope         string               `protobuf:"bytes,1,opt,name=scope,proto3" json:"scope,omitempty"`
+	Limit         int32                `protobuf:"varint,2,opt,name=limit,proto3" json:"limit,omitempty"`
+	Remaining     int32                `protobuf:"varint,3,opt,name=remaining,proto3" json:"remaining,omitempty"`
+	ResetIn       *durationpb.Duration `protobuf:"bytes,4,opt,name=reset_in,json=resetIn,proto3" json:"reset_in,omitempty"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *RateLimit) Reset() {
+	*x = RateLimit{}
+	mi := &file_proto_gatekeeper_proto_msgTypes[8]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *RateLimit) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*RateLimit) ProtoMessage() {}
+
+func (x *RateLimit) ProtoReflect() protoreflect.Message {
+	mi := &file_proto_gatekeeper_proto_msgTypes[8]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use RateLimit.ProtoReflect.Descriptor instead.
+func (*RateLimit) Descriptor() ([]byte, []int) {
+	return file_proto_gatekeeper_proto_rawDescGZIP(), []int{8}
+}
+
+func (x *RateLimit) GetScope() string {
+	if x != nil {
+		return x.Scope
+	}
+	return ""
+}
+
+func (x *RateLimit) GetLimit() int32 {
+	if x != nil {
+		return x.Limit
+	}
+	return 0
+}
+
+func (x *RateLimit) GetRemaining() int32 {
+	if x != nil {
+		return x.Remaining
+	}
+	return 0
+}
+
+func (x *RateLimit) GetResetIn() *durationpb.Duration {
+	if x != nil {
+		return x.ResetIn
+	}
+	return nil
+}
+
+type AuthorizeRequest struct {
+	state  protoimpl.MessageState `protogen:"open.v1"`
+	Method string                 `protobuf:"bytes,1,opt,name=method,proto3" json:"method,omitempty"`
+	Path   string                 `protobuf:"bytes,2,opt,name=path,proto3" json:"path,omitempty"`
+	// Ignored when credentials are sent in the x-api-key or authorization metadata,
+	// or when an organization extractor finds the organization
+	OrganizationName string `protobuf:"bytes,3,opt,name=organization_name,json=organizationName,proto3" json:"organization_name,omitempty"`
+	// Host of the gated request, read by the subdomain organization extractor
+	// and matched against the host pattern of the endpoints
+	Host          string `protobuf:"bytes,4,opt,name=host,proto3" json:"host,omitempty"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *AuthorizeRequest) Reset() {
+	*x = AuthorizeRequest{}
+	mi := &file_proto_gatekeeper_proto_msgTypes[9]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *AuthorizeRequest) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*AuthorizeRequest) ProtoMessage() {}
+
+func (x *AuthorizeRequest) ProtoReflect() protoreflect.Message {
+	mi := &file_proto_gatekeeper_proto_msgTypes[9]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use AuthorizeRequest.ProtoReflect.Descriptor instead.
+func (*AuthorizeRequest) Descriptor() ([]byte, []int) {
+	return file_proto_gatekeeper_proto_rawDescGZIP(), []int{9}
+}
+
+func (x *AuthorizeRequest) GetMethod() string {
+	if x != nil {
+		return x.Method
+	}
+	return ""
+}
+
+func (x *AuthorizeRequest) GetPath() string {
+	if x != nil {
+		return x.Path
+	}
+	return ""
+}
+
+func (x *AuthorizeRequest) GetOrganizationName() string {
+	if x != nil {
+		return x.OrganizationName
+	}
+	return ""
+}
+
+func (x *AuthorizeRequest) GetHost() string {
+	if x != nil {
+		return x.Host
+	}
+	return ""
+}
+
+// Decision is returned with an OK status for both outcomes, denials carry the
+// reason instead of failing the call. Only unexpected failures (bad input,
+// internal errors) are reported as gRPC errors.
+type Decision struct {
+	state        protoimpl.MessageState `protogen:"open.v1"`
+	Allowed      bool                   `protobuf:"varint,1,opt,name=allowed,proto3" json:"allowed,omitempty"`
+	Reason       DenialReason           `protobuf:"varint,2,opt,name=reason,proto3,enum=gatekeeper.DenialReason" json:"reason,omitempty"`
+	Message      string                 `protobuf:"bytes,3,opt,name=message,proto3" json:"message,omitempty"`
+	Organization *Organization          `protobuf:"bytes,4,opt,name=organization,proto3" json:"organization,omitempty"`
+	Endpoint     *Endpoint              `protobuf:"bytes,5,opt,name=endpoint,proto3" json:"endpoint,omitempty"`
+	Subscription *Subscription          `protobuf:"bytes,6,opt,name=subscription,proto3" json:"subscription,omitempty"`
+	Quota        *Quota                 `protobuf:"bytes,7,opt,name=quota,proto3" json:"quota,omitempty"`
+	RateLimit    *RateLimit             `protobuf:"bytes,8,opt,name=rate_limit,json=rateLimit,proto3" json:"rate_limit,omitempty"`
+	// Set when the request was rate limited or ran out of quota
+	RetryAfter *durationpb.Duration `protobuf:"bytes,9,opt,name=retry_after,json=retryAfter,proto3" json:"retry_after,omitempty"`
+	// Credits held against the quota until RecordUsage settles them with this
+	// hold_id, or the reservation is committed or cancelled
+	HoldId        string `protobuf:"bytes,10,opt,name=hold_id,json=holdId,proto3" json:"hold_id,omitempty"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *Decision) Reset() {
+	*x = Decision{}
+	mi := &file_proto_gatekeeper_proto_msgTypes[10]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *Decision) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*Decision) ProtoMessage() {}
+
+func (x *Decision) ProtoReflect() protoreflect.Message {
+	mi := &file_proto_gatekeeper_proto_msgTypes[10]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use Decision.ProtoReflect.Descriptor instead.
+func (*Decision) Descriptor() ([]byte, []int) {
+	return file_proto_gatekeeper_proto_rawDescGZIP(), []int{10}
+}
+
+func (x *Decision) GetAllowed() bool {
+	if x != nil {
+		return x.Allowed
+	}
+	return false
+}
+
+func (x *Decision) GetReason() DenialReason {
+	if x != nil {
+		return x.Reason
+	}
+	return DenialReason_DENIAL_REASON_UNSPECIFIED
+}
+
+func (x *Decision) GetMessage() string {
+	if x != nil {
+		return x.Message
+	}
+	return ""
+}
+
+func (x *Decision) GetOrganization() *Organization {
+	if x != nil {
+		return x.Organization
+	}
+	return nil
+}
+
+func (x *Decision) GetEndpoint() *Endpoint {
+	if x != nil {
+		return x.Endpoint
+	}
+	return nil
+}
+
+func (x *Decision) GetSubscription() *Subscription {
+	if x != nil {
+		return x.Subscription
+	}
+	return nil
+}
+
+func (x *Decision) GetQuota() *Quota {
+	if x != nil {
+		return x.Quota
+	}
+	return nil
+}
+
+func (x *Decision) GetRateLimit() *RateLimit {
+	if x != nil {
+		return x.RateLimit
+	}
+	return nil
+}
+
+func (x *Decision) GetRetryAfter() *durationpb.Duration {
+	if x != nil {
+		return x.RetryAfter
+	}
+	return nil
+}
+
+func (x *Decision) GetHoldId() string {
+	if x != nil {
+		return x.HoldId
+	}
+	return ""
+}
+
+type ValidateAndRecordResponse struct {
+	state    protoimpl.MessageState `protogen:"open.v1"`
+	Decision *Decision              `protobuf:"bytes,1,opt,name=decision,proto3" json:"decision,omitempty"`
+	// Empty when the request was denied
+	ReservationToken string  `protobuf:"bytes,2,opt,name=reservation_token,json=reservationToken,proto3" json:"reservation_token,omitempty"`
+	Cost             float64 `protobuf:"fixed64,3,opt,name=cost,proto3" json:"cost,omitempty"`
+	// The reservation is dropped, unbilled, when it isn't settled by then
+	ExpiresAt     *timestamppb.Timestamp `protobuf:"bytes,4,opt,name=expires_at,json=expiresAt,proto3" json:"expires_at,omitempty"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *ValidateAndRecordResponse) Reset() {
+	*x = ValidateAndRecordResponse{}
+	mi := &file_proto_gatekeeper_proto_msgTypes[11]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *ValidateAndRecordResponse) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*ValidateAndRecordResponse) ProtoMessage() {}
+
+func (x *ValidateAndRecordResponse) ProtoReflect() protoreflect.Message {
+	mi := &file_proto_gatekeeper_proto_msgTypes[11]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use ValidateAndRecordResponse.ProtoReflect.Descriptor instead.
+func (*ValidateAndRecordResponse) Descriptor() ([]byte, []int) {
+	return file_proto_gatekeeper_proto_rawDescGZIP(), []int{11}
+}
+
+func (x *ValidateAndRecordResponse) GetDecision() *Decision {
+	if x != nil {
+		return x.Decision
+	}
+	return nil
+}
+
+func (x *ValidateAndRecordResponse) GetReservationToken() string {
+	if x != nil {
+		return x.ReservationToken
+	}
+	return ""
+}
+
+func (x *ValidateAndRecordResponse) GetCost() float64 {
+	if x != nil {
+		return x.Cost
+	}
+	return 0
+}
+
+func (x *ValidateAndRecordResponse) GetExpiresAt() *timestamppb.Timestamp {
+	if x != nil {
+		return x.ExpiresAt
+	}
+	return nil
+}
+
+type ReservationRequest struct {
+	state            protoimpl.MessageState `protogen:"open.v1"`
+	ReservationToken string                 `protobuf:"bytes,1,opt,name=reservation_token,json=reservationToken,proto3" json:"reservation_token,omitempty"`
+	// Usage units of the call, only read by CommitReservation
+	UsageUnits    *float64 `protobuf:"fixed64,2,opt,name=usage_units,json=usageUnits,proto3,oneof" json:"usage_units,omitempty"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *ReservationRequest) Reset() {
+	*x = ReservationRequest{}
+	mi := &file_proto_gatekeeper_proto_msgTypes[12]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *ReservationRequest) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*ReservationRequest) ProtoMessage() {}
+
+func (x *ReservationRequest) ProtoReflect() protoreflect.Message {
+	mi := &file_proto_gatekeeper_proto_msgTypes[12]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use ReservationRequest.ProtoReflect.Descriptor instead.
+func (*ReservationRequest) Descriptor() ([]byte, []int) {
+	return file_proto_gatekeeper_proto_rawDescGZIP(), []int{12}
+}
+
+func (x *ReservationRequest) GetReservationToken() string {
+	if x != nil {
+		return x.ReservationToken
+	}
+	return ""
+}
+
+func (x *ReservationRequest) GetUsageUnits() float64 {
+	if x != nil && x.UsageUnits != nil {
+		return *x.UsageUnits
+	}
+	return 0
+}
+
+type CancelReservationResponse struct {
+	state         protoimpl.MessageState `protogen:"open.v1"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *CancelReservationResponse) Reset() {
+	*x = CancelReservationResponse{}
+	mi := &file_proto_gatekeeper_proto_msgTypes[13]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *CancelReservationResponse) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*CancelReservationResponse) ProtoMessage() {}
+
+func (x *CancelReservationResponse) ProtoReflect() protoreflect.Message {
+	mi := &file_proto_gatekeeper_proto_msgTypes[13]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use CancelReservationResponse.ProtoReflect.Descriptor instead.
+func (*CancelReservationResponse) Descriptor() ([]byte, []int) {
+	return file_proto_gatekeeper_proto_rawDescGZIP(), []int{13}
+}
+
+var File_proto_gatekeeper_proto protoreflect.FileDescriptor
+
+const file_proto_gatekeeper_proto_rawDesc = "" +
+	"\n" +
+	"\x16proto/gatekeeper.proto\x12\n" +
+	"gatekeeper\x1a\x1egoogle/protobuf/duration.proto\x1a\x1cgoogle/protobuf/struct.proto\x1a\x1fgoogle/protobuf/timestamp.proto\"\xf9\x01\n" +
+	"\x12RecordUsageRequest\x12\x16\n" +
+	"\x06method\x18\x01 \x01(\tR\x06method\x12\x12\n" +
+	"\x04path\x18\x02 \x01(\tR\x04path\x12+\n" +
+	"\x11organization_name\x18\x03 \x01(\tR\x10organizationName\x12\x12\n" +
+	"\x04host\x18\x04 \x01(\tR\x04host\x12\x17\n" +
+	"\ahold_id\x18\x05 \x01(\tR\x06holdId\x12'\n" +
+	"\x0fidempotency_key\x18\x06 \x01(\tR\x0eidempotencyKey\x12$\n" +
+	"\vusage_units\x18\a \x01(\x01H\x00R\n" +
+	"usageUnits\x88\x01\x01B\x0e\n" +
+	"\f_usage_units\")\n" +
+	"\x13RecordUsageResponse\x12\x12\n" +
+	"\x04cost\x18\x01 \x01(\x01R\x04cost\"u\n" +
+	"\x16ValidateRequestRequest\x12+\n" +
+	"\x11organization_name\x18\x01 \x01(\tR\x10organizationName\x12\x16\n" +
+	"\x06method\x18\x03 \x01(\tR\x06method\x12\x12\n" +
+	"\x04path\x18\x04 \x01(\tR\x04path:\x02\x18\x01\"\xea\x01\n" +
+	"\x17ValidateRequestResponse\x12;\n" +
+	"\forganization\x18\x01 \x01(\v2\x17.google.protobuf.StructR\forganization\x123\n" +
+	"\bendpoint\x18\x02 \x01(\v2\x17.google.protobuf.StructR\bendpoint\x12;\n" +
+	"\fsubscription\x18\x03 \x01(\v2\x17.google.protobuf.StructR\fsubscription\x12\x1c\n" +
+	"\tremaining\x18\x04 \x01(\x03R\tremaining:\x02\x18\x01\"H\n" +
+	"\fOrganization\x12\x0e\n" +
+	"\x02id\x18\x01 \x01(\x05R\x02id\x12\x12\n" +
+	"\x04name\x18\x02 \x01(\tR\x04name\x12\x14\n" +
+	"\x05realm\x18\x03 \x01(\tR\x05realm\"\x8d\x02\n" +
+	"\bEndpoint\x12\x0e\n" +
+	"\x02id\x18\x01 \x01(\x05R\x02id\x12\x12\n" +
+	"\x04code\x18\x02 \x01(\tR\x04code\x12\x16\n" +
+	"\x06method\x18\x03 \x01(\tR\x06method\x12#\n" +
+	"\rpath_template\x18\x04 \x01(\tR\fpathTemplate\x12(\n" +
+	"\x10resource_type_id\x18\x05 \x01(\x05R\x0eresourceTypeId\x12,\n" +
+	"\x12resource_type_name\x18\x06 \x01(\tR\x10resourceTypeName\x12'\n" +
+	"\x0fpermission_code\x18\a \x01(\tR\x0epermissionCode\x12\x1f\n" +
+	"\vaccess_type\x18\b \x01(\tR\n" +
+	"accessType\"\xa2\x02\n" +
+	"\fSubscription\x12\x0e\n" +
+	"\x02id\x18\x01 \x01(\x05R\x02id\x12'\n" +
+	"\x0forganization_id\x18\x02 \x01(\x05R\x0eorganizationId\x12\x1b\n" +
+	"\tapi_limit\x18\x03 \x01(\x05R\bapiLimit\x127\n" +
+	"\tstarts_at\x18\x04 \x01(\v2\x1a.google.protobuf.TimestampR\bstartsAt\x129\n" +
+	"\n" +
+	"expires_at\x18\x05 \x01(\v2\x1a.google.protobuf.TimestampR\texpiresAt\x120\n" +
+	"\x14quota_reset_interval\x18\x06 \x01(\tR\x12quotaResetInterval\x12\x16\n" +
+	"\x06active\x18\a \x01(\bR\x06active\"\x8e\x01\n" +
+	"\x05Quota\x12\x18\n" +
+	"\alimited\x18\x01 \x01(\bR\alimited\x12\x14\n" +
+	"\x05limit\x18\x02 \x01(\x03R\x05limit\x12\x1c\n" +
+	"\tremaining\x18\x03 \x01(\x03R\tremaining\x127\n" +
+	"\tresets_at\x18\x04 \x01(\v2\x1a.google.protobuf.TimestampR\bresetsAt\"\x8b\x01\n" +
+	"\tRateLimit\x12\x14\n" +
+	"\x05scope\x18\x01 \x01(\tR\x05scope\x12\x14\n" +
+	"\x05limit\x18\x02 \x01(\x05R\x05limit\x12\x1c\n" +
+	"\tremaining\x18\x03 \x01(\x05R\tremaining\x124\n" +
+	"\breset_in\x18\x04 \x01(\v2\x19.google.protobuf.DurationR\aresetIn\"\x7f\n" +
+	"\x10AuthorizeRequest\x12\x16\n" +
+	"\x06method\x18\x01 \x01(\tR\x06method\x12\x12\n" +
+	"\x04path\x18\x02 \x01(\tR\x04path\x12+\n" +
+	"\x11organization_name\x18\x03 \x01(\tR\x10organizationName\x12\x12\n" +
+	"\x04host\x18\x04 \x01(\tR\x04host\"\xd2\x03\n" +
+	"\bDecision\x12\x18\n" +
+	"\aallowed\x18\x01 \x01(\bR\aallowed\x120\n" +
+	"\x06reason\x18\x02 \x01(\x0e2\x18.gatekeeper.DenialReasonR\x06reason\x12\x18\n" +
+	"\amessage\x18\x03 \x01(\tR\amessage\x12<\n" +
+	"\forganization\x18\x04 \x01(\v2\x18.gatekeeper.OrganizationR\forganization\x120\n" +
+	"\bendpoint\x18\x05 \x01(\v2\x14.gatekeeper.EndpointR\bendpoint\x12<\n" +
+	"\fsubscription\x18\x06 \x01(\v2\x18.gatekeeper.SubscriptionR\fsubscription\x12'\n" +
+	"\x05quota\x18\a \x01(\v2\x11.gatekeeper.QuotaR\x05quota\x124\n" +
+	"\n" +
+	"rate_limit\x18\b \x01(\v2\x15.gatekeeper.RateLimitR\trateLimit\x12:\n" +
+	"\vretry_after\x18\t \x01(\v2\x19.google.protobuf.DurationR\n" +
+	"retryAfter\x12\x17\n" +
+	"\ahold_id\x18\n" +
+	" \x01(\tR\x06holdId\"\xc9\x01\n" +
+	"\x19ValidateAndRecordResponse\x120\n" +
+	"\bdecision\x18\x01 \x01(\v2\x14.gatekeeper.DecisionR\bdecision\x12+\n" +
+	"\x11reservation_token\x18\x02 \x01(\tR\x10reservationToken\x12\x12\n" +
+	"\x04cost\x18\x03 \x01(\x01R\x04cost\x129\n" +
+	"\n" +
+	"expires_at\x18\x04 \x01(\v2\x1a.google.protobuf.TimestampR\texpiresAt\"w\n" +
+	"\x12ReservationRequest\x12+\n" +
+	"\x11reservation_token\x18\x01 \x01(\tR\x10reservationToken\x12$\n" +
+	"\vusage_units\x18\x02 \x01(\x01H\x00R\n" +
+	"usageUnits\x88\x01\x01B\x0e\n" +
+	"\f_usage_units\"\x1b\n" +
+	"\x19CancelReservationResponse*\xf1\x02\n" +
+	"\fDenialReason\x12\x1d\n" +
+	"\x19DENIAL_REASON_UNSPECIFIED\x10\x00\x12\"\n" +
+	"\x1eDENIAL_REASON_UNKNOWN_ENDPOINT\x10\x01\x12\x1f\n" +
+	"\x1bDENIAL_REASON_ORG_NOT_FOUND\x10\x02\x12#\n" +
+	"\x1fDENIAL_REASON_PERMISSION_DENIED\x10\x03\x12!\n" +
+	"\x1dDENIAL_REASON_NO_SUBSCRIPTION\x10\x04\x12&\n" +
+	"\"DENIAL_REASON_SUBSCRIPTION_EXPIRED\x10\x05\x12 \n" +
+	"\x1cDENIAL_REASON_QUOTA_EXCEEDED\x10\x06\x12\x1e\n" +
+	"\x1aDENIAL_REASON_RATE_LIMITED\x10\a\x12%\n" +
+	"!DENIAL_REASON_INVALID_CREDENTIALS\x10\b\x12$\n" +
+	" DENIAL_REASON_INSUFFICIENT_SCOPE\x10\t2\x91\x04\n" +
+	"\x11GatekeeperService\x12N\n" +
+	"\vRecordUsage\x12\x1e.gatekeeper.RecordUsageRequest\x1a\x1f.gatekeeper.RecordUsageResponse\x12_\n" +
+	"\x0fValidateRequest\x12\".gatekeeper.ValidateRequestRequest\x1a#.gatekeeper.ValidateRequestResponse\"\x03\x88\x02\x01\x12?\n" +
+	"\tAuthorize\x12\x1c.gatekeeper.AuthorizeRequest\x1a\x14.gatekeeper.Decision\x12X\n" +
+	"\x11ValidateAndRecord\x12\x1c.gatekeeper.AuthorizeRequest\x1a%.gatekeeper.ValidateAndRecordResponse\x12T\n" +
+	"\x11CommitReservation\x12\x1e.gatekeeper.ReservationRequest\x1a\x1f.gatekeeper.RecordUsageResponse\x12Z\n" +
+	"\x11CancelReservation\x12\x1e.gatekeeper.ReservationRequest\x1a%.gatekeeper.CancelReservationResponseB2Z0github.com/bignyap/go-admin/pkg/gatekeeper/protob\x06proto3"
+
+var (
+	file_proto_gatekeeper_proto_rawDescOnce sync.Once
+	file_proto_gatekeeper_proto_rawDescData []byte
+)
+
+func file_proto_gatekeeper_proto_rawDescGZIP() []byte {
+	file_proto_gatekeeper_proto_rawDescOnce.Do(func() {
+		file_proto_gatekeeper_proto_rawDescData = protoimpl.X.CompressGZIP(unsafe.Slice(unsafe.StringData(file_proto_gatekeeper_proto_rawDesc), len(file_proto_gatekeeper_proto_rawDesc)))
+	})
+	return file_proto_gatekeeper_proto_rawDescData
+}
+
+var file_proto_gatekeeper_proto_enumTypes = make([]protoimpl.EnumInfo, 1)
+var file_proto_gatekeeper_proto_msgTypes = make([]protoimpl.MessageInfo, 14)
+var file_proto_gatekeeper_proto_goTypes = []any{
+	(DenialReason)(0),                 // 0: gatekeeper.DenialReason
+	(*RecordUsageRequest)(nil),        // 1: gatekeeper.RecordUsageRequest
+	(*RecordUsageResponse)(nil),       // 2: gatekeeper.RecordUsageResponse
+	(*ValidateRequestRequest)(nil),    // 3: gatekeeper.ValidateRequestRequest
+	(*ValidateRequestResponse)(nil),   // 4: gatekeeper.ValidateRequestResponse
+	(*Organization)(nil),              // 5: gatekeeper.Organization
+	(*Endpoint)(nil),                  // 6: gatekeeper.Endpoint
+	(*Subscription)(nil),              // 7: gatekeeper.Subscription
+	(*Quota)(nil),                     // 8: gatekeeper.Quota
+	(*RateLimit)(nil),                 // 9: gatekeeper.RateLimit
+	(*AuthorizeRequest)(nil),          // 10: gatekeeper.AuthorizeRequest
+	(*Decision)(nil),                  // 11: gatekeeper.Decision
+	(*ValidateAndRecordResponse)(nil), // 12: gatekeeper.ValidateAndRecordResponse
+	(*ReservationRequest)(nil),        // 13: gatekeeper.ReservationRequest
+	(*CancelReservationResponse)(nil), // 14: gatekeeper.CancelReservationResponse
+	(*structpb.Struct)(nil),           // 15: google.protobuf.Struct
+	(*timestamppb.Timestamp)(nil),     // 16: google.protobuf.Timestamp
+	(*durationpb.Duration)(nil),       // 17: google.protobuf.Duration
+}
+var file_proto_gatekeeper_proto_depIdxs = []int32{
+	15, // 0: gatekeeper.ValidateRequestResponse.organization:type_name -> google.protobuf.Struct
+	15, // 1: gatekeeper.ValidateRequestResponse.endpoint:type_name -> google.protobuf.Struct
+	15, // 2: gatekeeper.ValidateRequestResponse.subscription:type_name -> google.protobuf.Struct
+	16, // 3: gatekeeper.Subscription.starts_at:type_name -> google.protobuf.Timestamp
+	16, // 4: gatekeeper.Subscription.expires_at:type_name -> google.protobuf.Timestamp
+	16, // 5: gatekeeper.Quota.resets_at:type_name -> google.protobuf.Timestamp
+	17, // 6: gatekeeper.RateLimit.reset_in:type_name -> google.protobuf.Duration
+	0,  // 7: gatekeeper.Decision.reason:type_name -> gatekeeper.DenialReason
+	5,  // 8: gatekeeper.Decision.organization:type_name -> gatekeeper.Organization
+	6,  // 9: gatekeeper.Decision.endpoint:type_name -> gatekeeper.Endpoint
+	7,  // 10: gatekeeper.Decision.subscription:type_name -> gatekeeper.Subscription
+	8,  // 11: gatekeeper.Decision.quota:type_name -> gatekeeper.Quota
+	9,  // 12: gatekeeper.Decision.rate_limit:type_name -> gatekeeper.RateLimit
+	17, // 13: gatekeeper.Decision.retry_after:type_name -> google.protobuf.Duration
+	11, // 14: gatekeeper.ValidateAndRecordResponse.decision:type_name -> gatekeeper.Decision
+	16, // 15: gatekeeper.ValidateAndRecordResponse.expires_at:type_name -> google.protobuf.Timestamp
+	1,  // 16: gatekeeper.GatekeeperService.RecordUsage:input_type -> gatekeeper.RecordUsageRequest
+	3,  // 17: gatekeeper.GatekeeperService.ValidateRequest:input_type -> gatekeeper.ValidateRequestRequest
+	10, // 18: gatekeeper.GatekeeperService.Authorize:input_type -> gatekeeper.AuthorizeRequest
+	10, // 19: gatekeeper.GatekeeperService.ValidateAndRecord:input_type -> gatekeeper.AuthorizeRequest
+	13, // 20: gatekeeper.GatekeeperService.CommitReservation:input_type -> gatekeeper.ReservationRequest
+	13, // 21: gatekeeper.GatekeeperService.CancelReservation:input_type -> gatekeeper.ReservationRequest
+	2,  // 22: gatekeeper.GatekeeperService.RecordUsage:output_type -> gatekeeper.RecordUsageResponse
+	4,  // 23: gatekeeper.GatekeeperService.ValidateRequest:output_type -> gatekeeper.ValidateRequestResponse
+	11, // 24: gatekeeper.GatekeeperService.Authorize:output_type -> gatekeeper.Decision
+	12, // 25: gatekeeper.GatekeeperService.ValidateAndRecord:output_type -> gatekeeper.ValidateAndRecordResponse
+	2,  // 26: gatekeeper.GatekeeperService.CommitReservation:output_type -> gatekeeper.RecordUsageResponse
+	14, // 27: gatekeeper.GatekeeperService.CancelReservation:output_type -> gatekeeper.CancelReservationResponse
+	22, // [22:28] is the sub-list for method output_type
+	16, // [16:22] is the sub-list for method input_type
+	16, // [16:16] is the sub-list for extension type_name
+	16, // [16:16] is the sub-list for extension extendee
+	0,  // [0:16] is the sub-list for field type_name
+}
+
+func init() { file_proto_gatekeeper_proto_init() }
+func file_proto_gatekeeper_proto_init() {
+	if File_proto_gatekeeper_proto != nil {
+		return
+	}
+	file_proto_gatekeeper_proto_msgTypes[0].OneofWrappers = []any{}
+	file_proto_gatekeeper_proto_msgTypes[12].OneofWrappers = []any{}
+	type x struct{}
+	out := protoimpl.TypeBuilder{
+		File: protoimpl.DescBuilder{
+			GoPackagePath: reflect.TypeOf(x{}).PkgPath(),
+			RawDescriptor: unsafe.Slice(unsafe.StringData(file_proto_gatekeeper_proto_rawDesc), len(file_proto_gatekeeper_proto_rawDesc)),
+			NumEnums:      1,
+			NumMessages:   14,
+			NumExtensions: 0,
+			NumServices:   1,
+		},
+		GoTypes:           file_proto_gatekeeper_proto_goTypes,
+		DependencyIndexes: file_proto_gatekeeper_proto_depIdxs,
+		EnumInfos:         file_proto_gatekeeper_proto_enumTypes,
+		MessageInfos:      file_proto_gatekeeper_proto_msgTypes,
+	}.Build()
+	File_proto_gatekeeper_proto = out.File
+	file_proto_gatekeeper_proto_goTypes = nil
+	file_proto_gatekeeper_proto_depIdxs = nil
+}
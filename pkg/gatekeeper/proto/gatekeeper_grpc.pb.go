@@ -0,0 +1,320 @@
+// Code generated by protoc-gen-go-grpc. DO NOT EDIT.
+// versions:
+// - protoc-gen-go-grpc v1.5.1
+// - protoc             v5.29.3
+// source: proto/gatekeeper.proto
+
+package proto
+
+import (
+	context "context"
+	grpc "google.golang.org/grpc"
+	codes "google.golang.org/grpc/codes"
+	status "google.golang.org/grpc/status"
+)
+
+// This is a compile-time assertion to ensure that this generated file
+// is compatible with the grpc package it is being compiled against.
+// Requires gRPC-Go v1.64.0 or later.
+const _ = grpc.SupportPackageIsVersion9
+
+const (
+	GatekeeperService_RecordUsage_FullMethodName       = "/gatekeeper.GatekeeperService/RecordUsage"
+	GatekeeperService_ValidateRequest_FullMethodName   = "/gatekeeper.GatekeeperService/ValidateRequest"
+	GatekeeperService_Authorize_FullMethodName         = "/gatekeeper.GatekeeperService/Authorize"
+	GatekeeperService_ValidateAndRecord_FullMethodName = "/gatekeeper.GatekeeperService/ValidateAndRecord"
+	GatekeeperService_CommitReservation_FullMethodName = "/gatekeeper.GatekeeperService/CommitReservation"
+	GatekeeperService_CancelReservation_FullMethodName = "/gatekeeper.GatekeeperService/CancelReservation"
+)
+
+// GatekeeperServiceClient is the client API for GatekeeperService service.
+//
+// For semantics around ctx use and closing/ending streaming RPCs, please refer to https://pkg.go.dev/google.golang.org/grpc/?tab=doc#ClientConn.NewStream.
+type GatekeeperServiceClient interface {
+	RecordUsage(ctx context.Context, in *RecordUsageRequest, opts ...grpc.CallOption) (*RecordUsageResponse, error)
+	// Deprecated: Do not use.
+	// Kept for existing clients, Authorize returns typed messages instead.
+	ValidateRequest(ctx context.Context, in *ValidateRequestRequest, opts ...grpc.CallOption) (*ValidateRequestResponse, error)
+	Authorize(ctx context.Context, in *AuthorizeRequest, opts ...grpc.CallOption) (*Decision, error)
+	// Authorize and reserve the cost in one call, the token is then settled
+	// with CommitReservation or CancelReservation.
+	ValidateAndRecord(ctx context.Context, in *AuthorizeRequest, opts ...grpc.CallOption) (*ValidateAndRecordResponse, error)
+	CommitReservation(ctx context.Context, in *ReservationRequest, opts ...grpc.CallOption) (*RecordUsageResponse, error)
+	CancelReservation(ctx context.Context, in *ReservationRequest, opts ...grpc.CallOption) (*CancelReservationResponse, error)
+}
+
+type gatekeeperServiceClient struct {
+	cc grpc.ClientConnInterface
+}
+
+func NewGatekeeperServiceClient(cc grpc.ClientConnInterface) GatekeeperServiceClient {
+	return &gatekeeperServiceClient{cc}
+}
+
+func (c *gatekeeperServiceClient) RecordUsage(ctx context.Context, in *RecordUsageRequest, opts ...grpc.CallOption) (*RecordUsageResponse, error) {
+	cOpts := append([]grpc.CallOption{grpc.StaticMethod()}, opts...)
+	out := new(RecordUsageResponse)
+	err := c.cc.Invoke(ctx, GatekeeperService_RecordUsage_FullMethodName, in, out, cOpts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+// Deprecated: Do not use.
+func (c *gatekeeperServiceClient) ValidateRequest(ctx context.Context, in *ValidateRequestRequest, opts ...grpc.CallOption) (*ValidateRequestResponse, error) {
+	cOpts := append([]grpc.CallOption{grpc.StaticMethod()}, opts...)
+	out := new(ValidateRequestResponse)
+	err := c.cc.Invoke(ctx, GatekeeperService_ValidateRequest_FullMethodName, in, out, cOpts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *gatekeeperServiceClient) Authorize(ctx context.Context, in *AuthorizeRequest, opts ...grpc.CallOption) (*Decision, error) {
+	cOpts := append([]grpc.CallOption{grpc.StaticMethod()}, opts...)
+	out := new(Decision)
+	err := c.cc.Invoke(ctx, GatekeeperService_Authorize_FullMethodName, in, out, cOpts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *gatekeeperServiceClient) ValidateAndRecord(ctx context.Context, in *AuthorizeRequest, opts ...grpc.CallOption) (*ValidateAndRecordResponse, error) {
+	cOpts := append([]grpc.CallOption{grpc.StaticMethod()}, opts...)
+	out := new(ValidateAndRecordResponse)
+	err := c.cc.Invoke(ctx, GatekeeperService_ValidateAndRecord_FullMethodName, in, out, cOpts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *gatekeeperServiceClient) CommitReservation(ctx context.Context, in *ReservationRequest, opts ...grpc.CallOption) (*RecordUsageResponse, error) {
+	cOpts := append([]grpc.CallOption{grpc.StaticMethod()}, opts...)
+	out := new(RecordUsageResponse)
+	err := c.cc.Invoke(ctx, GatekeeperService_CommitReservation_FullMethodName, in, out, cOpts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *gatekeeperServiceClient) CancelReservation(ctx context.Context, in *ReservationRequest, opts ...grpc.CallOption) (*CancelReservationResponse, error) {
+	cOpts := append([]grpc.CallOption{grpc.StaticMethod()}, opts...)
+	out := new(CancelReservationResponse)
+	err := c.cc.Invoke(ctx, GatekeeperService_CancelReservation_FullMethodName, in, out, cOpts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+// GatekeeperServiceServer is the server API for GatekeeperService service.
+// All implementations must embed UnimplementedGatekeeperServiceServer
+// for forward compatibility.
+type GatekeeperServiceServer interface {
+	RecordUsage(context.Context, *RecordUsageRequest) (*RecordUsageResponse, error)
+	// Deprecated: Do not use.
+	// Kept for existing clients, Authorize returns typed messages instead.
+	ValidateRequest(context.Context, *ValidateRequestRequest) (*ValidateRequestResponse, error)
+	Authorize(context.Context, *AuthorizeRequest) (*Decision, error)
+	// Authorize and reserve the cost in one call, the token is then settled
+	// with CommitReservation or CancelReservation.
+	ValidateAndRecord(context.Context, *AuthorizeRequest) (*ValidateAndRecordResponse, error)
+	CommitReservation(context.Context, *ReservationRequest) (*RecordUsageResponse, error)
+	CancelReservation(context.Context, *ReservationRequest) (*CancelReservationResponse, error)
+	mustEmbedUnimplementedGatekeeperServiceServer()
+}
+
+// UnimplementedGatekeeperServiceServer must be embedded to have
+// forward compatible implementations.
+//
+// NOTE: this should be embedded by value instead of pointer to avoid a nil
+// pointer dereference when methods are called.
+type UnimplementedGatekeeperServiceServer struct{}
+
+func (UnimplementedGatekeeperServiceServer) RecordUsage(context.Context, *RecordUsageRequest) (*RecordUsageResponse, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method RecordUsage not implemented")
+}
+func (UnimplementedGatekeeperServiceServer) ValidateRequest(context.Context, *ValidateRequestRequest) (*ValidateRequestResponse, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method ValidateRequest not implemented")
+}
+func (UnimplementedGatekeeperServiceServer) Authorize(context.Context, *AuthorizeRequest) (*Decision, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method Authorize not implemented")
+}
+func (UnimplementedGatekeeperServiceServer) ValidateAndRecord(context.Context, *AuthorizeRequest) (*ValidateAndRecordResponse, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method ValidateAndRecord not implemented")
+}
+func (UnimplementedGatekeeperServiceServer) CommitReservation(context.Context, *ReservationRequest) (*RecordUsageResponse, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method CommitReservation not implemented")
+}
+func (UnimplementedGatekeeperServiceServer) CancelReservation(context.Context, *ReservationRequest) (*CancelReservationResponse, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method CancelReservation not implemented")
+}
+func (UnimplementedGatekeeperServiceServer) mustEmbedUnimplementedGatekeeperServiceServer() {}
+func (UnimplementedGatekeeperServiceServer) testEmbeddedByValue()                           {}
+
+// UnsafeGatekeeperServiceServer may be embedded to opt out of forward compatibility for this service.
+// Use of this interface is not recommended, as added methods to GatekeeperServiceServer will
+// result in compilation errors.
+type UnsafeGatekeeperServiceServer interface {
+	mustEmbedUnimplementedGatekeeperServiceServer()
+}
+
+func RegisterGatekeeperServiceServer(s grpc.ServiceRegistrar, srv GatekeeperServiceServer) {
+	// If the following call pancis, it indicates UnimplementedGatekeeperServiceServer was
+	// embedded by pointer and is nil.  This will cause panics if an
+	// unimplemented method is ever invoked, so we test this at initialization
+	// time to prevent it from happening at runtime later due to I/O.
+	if t, ok := srv.(interface{ testEmbeddedByValue() }); ok {
+		t.testEmbeddedByValue()
+	}
+	s.RegisterService(&GatekeeperService_ServiceDesc, srv)
+}
+
+func _GatekeeperService_RecordUsage_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(RecordUsageRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(GatekeeperServiceServer).RecordUsage(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: GatekeeperService_RecordUsage_FullMethodName,
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(GatekeeperServiceServer).RecordUsage(ctx, req.(*RecordUsageRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _GatekeeperService_ValidateRequest_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(ValidateRequestRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(GatekeeperServiceServer).ValidateRequest(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: GatekeeperService_ValidateRequest_FullMethodName,
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(GatekeeperServiceServer).ValidateRequest(ctx, req.(*ValidateRequestRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _GatekeeperService_Authorize_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(AuthorizeRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(GatekeeperServiceServer).Authorize(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: GatekeeperService_Authorize_FullMethodName,
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(GatekeeperServiceServer).Authorize(ctx, req.(*AuthorizeRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _GatekeeperService_ValidateAndRecord_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(AuthorizeRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(GatekeeperServiceServer).ValidateAndRecord(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: GatekeeperService_ValidateAndRecord_FullMethodName,
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(GatekeeperServiceServer).ValidateAndRecord(ctx, req.(*AuthorizeRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _GatekeeperService_CommitReservation_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(ReservationRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(GatekeeperServiceServer).CommitReservation(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: GatekeeperService_CommitReservation_FullMethodName,
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(GatekeeperServiceServer).CommitReservation(ctx, req.(*ReservationRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _GatekeeperService_CancelReservation_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(ReservationRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(GatekeeperServiceServer).CancelReservation(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: GatekeeperService_CancelReservation_FullMethodName,
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(GatekeeperServiceServer).CancelReservation(ctx, req.(*ReservationRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+// GatekeeperService_ServiceDesc is the grpc.ServiceDesc for GatekeeperService service.
+// It's only intended for direct use with grpc.RegisterService,
+// and not to be introspected or modified (even as a copy)
+var GatekeeperService_ServiceDesc = grpc.ServiceDesc{
+	ServiceName: "gatekeeper.GatekeeperService",
+	HandlerType: (*GatekeeperServiceServer)(nil),
+	Methods: []grpc.MethodDesc{
+		{
+			MethodName: "RecordUsage",
+			Handler:    _GatekeeperService_RecordUsage_Handler,
+		},
+		{
+			MethodName: "ValidateRequest",
+			Handler:    _GatekeeperService_ValidateRequest_Handler,
+		},
+		{
+			MethodName: "Authorize",
+			Handler:    _GatekeeperService_Authorize_Handler,
+		},
+		{
+			MethodName: "ValidateAndRecord",
+			Handler:    _GatekeeperService_ValidateAndRecord_Handler,
+		},
+		{
+			MethodName: "CommitReservation",
+			Handler:    _GatekeeperService_CommitReservation_Handler,
+		},
+		{
+			MethodName: "CancelReservation",
+			Handler:    _GatekeeperService_CancelReservation_Handler,
+		},
+	},
+	Streams:  []grpc.StreamDesc{},
+	Metadata: "proto/gatekeeper.proto",
+}